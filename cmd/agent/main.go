@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/kuda-io/kuda/pkg/agent"
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	utilruntime.Must(datav1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	var (
+		nodeName    string
+		metricsAddr string
+		probeAddr   string
+		configFile  string
+		p2pAddr     string
+		p2pAdvAddr  string
+	)
+	flag.StringVar(&nodeName, "node-name", os.Getenv("NODE_NAME"), "The node the agent runs on, defaults to the NODE_NAME environment variable.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&configFile, "config", "", "Config file path for the garbage collection of the host cache, the default config is used if not set.")
+	flag.StringVar(&p2pAddr, "p2p-bind-address", "", "The address the host cache is served to the peers on, the host cache is not shared if not set.")
+	flag.StringVar(&p2pAdvAddr, "p2p-advertise-address", "", "The address of the node advertised to the peers, defaults to the NODE_IP environment variable and the port of the p2p bind address.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if nodeName == "" {
+		setupLog.Error(nil, "node name must be set")
+		os.Exit(1)
+	}
+
+	config := agent.DefaultConfig()
+	if configFile != "" {
+		var err error
+		if config, err = agent.LoadConfig(configFile); err != nil {
+			setupLog.Error(err, "unable to load config")
+			os.Exit(1)
+		}
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: probeAddr,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	gc := agent.NewGarbageCollector(config, nodeName, mgr.GetClient(), mgr.GetAPIReader(), mgr.GetEventRecorderFor("kuda-agent"))
+	if err := mgr.Add(gc); err != nil {
+		setupLog.Error(err, "unable to set up garbage collector")
+		os.Exit(1)
+	}
+
+	if p2pAddr != "" {
+		if p2pAdvAddr == "" {
+			_, port, err := net.SplitHostPort(p2pAddr)
+			if err != nil {
+				setupLog.Error(err, "invalid p2p bind address")
+				os.Exit(1)
+			}
+			if os.Getenv("NODE_IP") == "" {
+				setupLog.Error(nil, "p2p advertise address must be set")
+				os.Exit(1)
+			}
+			p2pAdvAddr = net.JoinHostPort(os.Getenv("NODE_IP"), port)
+		}
+		sharer := agent.NewCacheSharer(config, nodeName, p2pAddr, p2pAdvAddr, mgr.GetClient(), mgr.GetAPIReader())
+		if err := mgr.Add(sharer); err != nil {
+			setupLog.Error(err, "unable to set up cache sharer")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting agent", "node", nodeName, "hostPath", config.HostPath)
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
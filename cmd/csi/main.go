@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/csi"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	utilruntime.Must(datav1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	config := csi.DefaultConfig()
+	flag.StringVar(&config.Endpoint, "endpoint", config.Endpoint, "The unix socket the CSI driver listens on.")
+	flag.StringVar(&config.NodeID, "node-id", os.Getenv("NODE_NAME"), "The node the CSI driver runs on, defaults to the NODE_NAME environment variable.")
+	flag.StringVar(&config.Name, "driver-name", config.Name, "The name of the CSI driver.")
+	flag.StringVar(&config.HostPath, "host-path", config.HostPath, "The host cache directory, which should be the same as the webhook config.")
+	flag.StringVar(&config.HostCacheIsolation, "host-cache-isolation", utils.HostCacheIsolationShared,
+		"The isolation of the host cache, which should be the same as the webhook config. One of Shared, Namespace and DataSet.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if config.NodeID == "" {
+		setupLog.Error(nil, "node id must be set")
+		os.Exit(1)
+	}
+	if !utils.IsValidHostCacheIsolation(config.HostCacheIsolation) {
+		setupLog.Error(nil, "invalid host cache isolation", "isolation", config.HostCacheIsolation)
+		os.Exit(1)
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+
+	driver := csi.NewDriver(config, c, csi.NewMounter())
+	setupLog.Info("starting csi driver", "node", config.NodeID, "hostPath", config.HostPath)
+	if err := driver.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running csi driver")
+		os.Exit(1)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -33,6 +34,8 @@ import (
 
 	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
 	"github.com/kuda-io/kuda/pkg/controllers"
+	"github.com/kuda-io/kuda/pkg/metrics"
+	"github.com/kuda-io/kuda/pkg/utils"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -52,11 +55,37 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var metricsPodLabels bool
+	var prefetchConfig controllers.PrefetchConfig
+	var downloadLeaseConfig controllers.DownloadLeaseConfig
+	var sourceBandwidth, nodeBandwidth string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&metricsPodLabels, "metrics-pod-labels", false,
+		"Enable the metrics with per pod labels, whose cardinality grows with the replicas of datasets.")
+	flag.StringVar(&prefetchConfig.RuntimeImage, "runtime-image", "kuda4bigo/kuda-runtime:latest",
+		"The kuda runtime image of the pods prefetching data onto nodes, which should be the same as the webhook config.")
+	flag.StringVar(&prefetchConfig.HostPath, "host-path", "/var/lib/kuda",
+		"The host path the data is prefetched into, which should be the same as the webhook config.")
+	flag.UintVar(&prefetchConfig.RuntimeServerPort, "runtime-server-port", 8888,
+		"The server port of the kuda runtime prefetching data onto nodes.")
+	flag.StringVar(&prefetchConfig.HostCacheIsolation, "host-cache-isolation", utils.HostCacheIsolationShared,
+		"The isolation of the host cache, one of Shared, Namespace and DataSet, which should be the same as the webhook config.")
+	flag.BoolVar(&prefetchConfig.HostCacheDedup, "host-cache-dedup", false,
+		"Deduplicate the data items prefetched in the host cache, which should be the same as the webhook config.")
+	flag.IntVar(&downloadLeaseConfig.MaxPerDataSet, "max-downloads-per-dataset", 0,
+		"The max concurrent downloads of a dataset, which can be overridden by the dataset, 0 means unlimited.")
+	flag.IntVar(&downloadLeaseConfig.MaxPerSource, "max-downloads-per-source", 0,
+		"The max concurrent downloads from a data source, e.g. an HDFS cluster, 0 means unlimited.")
+	flag.IntVar(&downloadLeaseConfig.MaxPerNode, "max-downloads-per-node", 0,
+		"The max concurrent downloads onto a node, 0 means unlimited.")
+	flag.StringVar(&sourceBandwidth, "download-bandwidth-per-source", "",
+		"The bandwidth of a data source shared by the downloads, e.g. 1Gi, which is handed out to the kuda runtimes with the download leases.")
+	flag.StringVar(&nodeBandwidth, "download-bandwidth-per-node", "",
+		"The bandwidth of a node shared by the downloads, e.g. 100Mi, which is handed out to the kuda runtimes with the download leases.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,6 +94,20 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if !utils.IsValidHostCacheIsolation(prefetchConfig.HostCacheIsolation) {
+		setupLog.Error(nil, "unsupported host cache isolation", "isolation", prefetchConfig.HostCacheIsolation)
+		os.Exit(1)
+	}
+	var err error
+	if downloadLeaseConfig.BandwidthPerSource, err = parseBandwidth(sourceBandwidth); err != nil {
+		setupLog.Error(err, "invalid download bandwidth per source", "bandwidth", sourceBandwidth)
+		os.Exit(1)
+	}
+	if downloadLeaseConfig.BandwidthPerNode, err = parseBandwidth(nodeBandwidth); err != nil {
+		setupLog.Error(err, "invalid download bandwidth per node", "bandwidth", nodeBandwidth)
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -79,21 +122,59 @@ func main() {
 	}
 
 	if err = (&controllers.DataSetReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Recorder:      mgr.GetEventRecorderFor("dataset-controller"),
+		Prefetch:      prefetchConfig,
+		DownloadLease: downloadLeaseConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DataSet")
 		os.Exit(1)
 	}
 	if err = (&controllers.DataReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("data-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Data")
+		os.Exit(1)
+	}
+	if err = (&controllers.NodeDataReconciler{
+		Client:             mgr.GetClient(),
+		Scheme:             mgr.GetScheme(),
+		HostCacheIsolation: prefetchConfig.HostCacheIsolation,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NodeData")
+		os.Exit(1)
+	}
+	if err = (&controllers.DataQuotaReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Data")
+		setupLog.Error(err, "unable to create controller", "controller", "DataQuota")
+		os.Exit(1)
+	}
+	if err = (&controllers.DownloadLeaseReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("downloadlease-controller"),
+		Config:   downloadLeaseConfig,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DownloadLease")
+		os.Exit(1)
+	}
+	if err = (&controllers.VersionDiscoveryReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("versiondiscovery-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "VersionDiscovery")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	metrics.Register(metrics.NewDataSetCollector(mgr.GetClient(), metricsPodLabels))
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -109,3 +190,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// parseBandwidth returns the bytes per second of the bandwidth quantity, 0 if empty.
+func parseBandwidth(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, err
+	}
+	return quantity.Value(), nil
+}
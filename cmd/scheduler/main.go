@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	cliflag "k8s.io/component-base/cli/flag"
+	"k8s.io/component-base/logs"
+	_ "k8s.io/component-base/metrics/prometheus/clientgo"
+	_ "k8s.io/component-base/metrics/prometheus/version"
+	"k8s.io/klog/v2/klogr"
+	"k8s.io/kubernetes/cmd/kube-scheduler/app"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kuda-io/kuda/pkg/scheduler"
+)
+
+// The kuda scheduler is the kube-scheduler built with the data locality plugin, which is
+// enabled by the profiles of the KubeSchedulerConfiguration.
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	pflag.CommandLine.SetNormalizeFunc(cliflag.WordSepNormalizeFunc)
+
+	command := app.NewSchedulerCommand(app.WithPlugin(scheduler.Name, scheduler.New))
+
+	logs.InitLogs()
+	defer logs.FlushLogs()
+	ctrl.SetLogger(klogr.New())
+
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -40,6 +41,7 @@ var (
 )
 
 func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
 	_ = datav1alpha1.AddToScheme(scheme)
 }
 
@@ -82,6 +84,7 @@ func main() {
 	log.Info("setting up webhook server")
 	ws := mgr.GetWebhookServer()
 	ws.Register("/inject", &webhook.Admission{Handler: webhook2.NewPodInjector(config, mgr.GetClient())})
+	ws.Register("/validate", &webhook.Admission{Handler: webhook2.NewDataSetValidator(config, mgr.GetClient())})
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		log.Error(err, "unable to set up health check")
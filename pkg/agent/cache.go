@@ -0,0 +1,288 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kuda-io/kuda/pkg/dedup"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+// CachedVersion describes a version of data item in the host cache, which is downloaded
+// by the kuda runtime into <hostPath>/<tenant>/<namespace>/<name>/<version>.
+type CachedVersion struct {
+	// Tenant is the sub directory of the host cache by the isolation, it's empty if the
+	// host cache is shared.
+	Tenant    string
+	Namespace string
+	Name      string
+	Version   string
+	// Path of the version directory.
+	Path string
+	// Size is the bytes of the regular files only linked within the version directory,
+	// which are freed once the version is removed.
+	Size int64
+	// LastAccess is the last time the version is observed used.
+	LastAccess time.Time
+
+	// linked are the regular files of the version also linked elsewhere, i.e. into the
+	// other versions or the content store, which are not counted by Size.
+	linked []linkedFile
+}
+
+// fileID identifies a regular file by its device and inode, so that the hard links of the
+// file are counted once.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// linkedFile is a regular file of a version, which is also linked elsewhere.
+type linkedFile struct {
+	id   fileID
+	size int64
+	// links is the number of the links within the version directory, and nlink is the
+	// number of all the links.
+	links uint64
+	nlink uint64
+	// stored is whether the file is linked into the content store, which is freed by the
+	// collection of the content store rather than the removal of versions.
+	stored bool
+}
+
+// Key returns the identity of the version in the host cache.
+func (v *CachedVersion) Key() string {
+	return tenantKey(v.Tenant, v.DataItemKey())
+}
+
+// DataItemKey returns the identity of the version of the data item regardless of the tenant.
+func (v *CachedVersion) DataItemKey() string {
+	return versionKey(v.Namespace, v.Name, v.Version)
+}
+
+// ScanCache returns the versions in the host cache isolated by the isolation. The last
+// access time of a version is the modification time of its directory. The hard links of a
+// file are counted once, the files linked by several versions or the content store are
+// recorded with the versions, by which the bytes freed are accounted.
+func ScanCache(root, isolation string) ([]CachedVersion, error) {
+	versions := make([]CachedVersion, 0)
+
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return versions, nil
+	}
+	tenants, err := scanTenants(root, isolation)
+	if err != nil {
+		return nil, err
+	}
+	for _, tenant := range tenants {
+		tenantPath := filepath.Join(root, tenant)
+		namespaces, err := readDirs(tenantPath)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := scanFiles(filepath.Join(tenantPath, dedup.DirName))
+		if err != nil {
+			return nil, err
+		}
+		for _, namespace := range namespaces {
+			// The content store holds the content of the versions deduplicated, which are
+			// linked into the version directories.
+			if namespace.Name() == dedup.DirName {
+				continue
+			}
+			names, err := readDirs(filepath.Join(tenantPath, namespace.Name()))
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				dirs, err := readDirs(filepath.Join(tenantPath, namespace.Name(), name.Name()))
+				if err != nil {
+					return nil, err
+				}
+				for _, dir := range dirs {
+					path := filepath.Join(tenantPath, namespace.Name(), name.Name(), dir.Name())
+					size, linked, err := scanVersion(path, stored)
+					if err != nil {
+						return nil, err
+					}
+					versions = append(versions, CachedVersion{
+						Tenant:     tenant,
+						Namespace:  namespace.Name(),
+						Name:       name.Name(),
+						Version:    dir.Name(),
+						Path:       path,
+						Size:       size,
+						LastAccess: dir.ModTime(),
+						linked:     linked,
+					})
+				}
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+// scanTenants returns the sub directories of the tenants in the host cache, the depth of
+// which is the number of path segments of utils.HostCacheSubPath.
+func scanTenants(root, isolation string) ([]string, error) {
+	depth := 0
+	switch isolation {
+	case utils.HostCacheIsolationNamespace:
+		depth = 1
+	case utils.HostCacheIsolationDataSet:
+		depth = 2
+	}
+
+	tenants := []string{""}
+	for i := 0; i < depth; i++ {
+		next := make([]string, 0, len(tenants))
+		for _, tenant := range tenants {
+			dirs, err := readDirs(filepath.Join(root, tenant))
+			if err != nil {
+				return nil, err
+			}
+			for _, dir := range dirs {
+				next = append(next, filepath.Join(tenant, dir.Name()))
+			}
+		}
+		tenants = next
+	}
+
+	return tenants, nil
+}
+
+// RemoveVersion removes the version directory from the host cache, and the parent
+// directories of the data item left empty. The directories of the tenants are kept
+// with their owner and mode.
+func RemoveVersion(root string, version *CachedVersion) error {
+	path := filepath.Join(root, version.Tenant, version.Namespace, version.Name, version.Version)
+	if path != filepath.Clean(version.Path) {
+		return fmt.Errorf("version path %s is not in the host cache %s", version.Path, root)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+
+	for _, dir := range []string{filepath.Dir(path), filepath.Dir(filepath.Dir(path))} {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			break
+		}
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// readDirs returns the sub directories of the directory.
+func readDirs(dir string) ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry)
+		}
+	}
+	return dirs, nil
+}
+
+// scanVersion returns the bytes of the regular files only linked within the version
+// directory, and the files also linked elsewhere.
+func scanVersion(dir string, stored map[fileID]bool) (int64, []linkedFile, error) {
+	var size int64
+	var linked []linkedFile
+	index := make(map[fileID]int)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		dev, ino, nlink, ok := dedup.FileLinks(info)
+		if !ok || nlink <= 1 {
+			size += info.Size()
+			return nil
+		}
+		id := fileID{dev: dev, ino: ino}
+		if i, ok := index[id]; ok {
+			linked[i].links++
+			return nil
+		}
+		index[id] = len(linked)
+		linked = append(linked, linkedFile{id: id, size: info.Size(), links: 1, nlink: nlink, stored: stored[id]})
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// The files of which all the links are within the version are freed with it.
+	shared := linked[:0]
+	for _, file := range linked {
+		if file.links >= file.nlink {
+			size += file.size
+			continue
+		}
+		shared = append(shared, file)
+	}
+	if len(shared) == 0 {
+		shared = nil
+	}
+	return size, shared, nil
+}
+
+// scanFiles returns the regular files in the directory, which may not exist.
+func scanFiles(dir string) (map[fileID]bool, error) {
+	files := make(map[fileID]bool)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if dev, ino, _, ok := dedup.FileLinks(info); ok && info.Mode().IsRegular() {
+			files[fileID{dev: dev, ino: ino}] = true
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	return files, err
+}
+
+func versionKey(namespace, name, version string) string {
+	return fmt.Sprintf("%s/%s@%s", namespace, name, version)
+}
+
+func tenantKey(tenant, key string) string {
+	if tenant == "" {
+		return key
+	}
+	return tenant + ":" + key
+}
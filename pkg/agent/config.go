@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+// Config defines fields for the garbage collection of the host cache.
+type Config struct {
+	// HostPath is the host cache directory, which should be the same as the webhook config.
+	HostPath string `yaml:"hostPath"`
+	// HostCacheIsolation is the isolation of the host cache, which should be the same as the
+	// webhook config.
+	HostCacheIsolation string `yaml:"hostCacheIsolation"`
+	// Interval between two garbage collections.
+	Interval metav1.Duration `yaml:"interval"`
+	// KeepVersions is the number of the most recently used versions kept for each data
+	// item, zero keeps all the versions.
+	KeepVersions int `yaml:"keepVersions"`
+	// MaxSize is the max size of the host cache, the least recently used versions are
+	// removed until the cache fits in it. No limit if not set.
+	MaxSize *resource.Quantity `yaml:"maxSize"`
+	// MinAge protects the versions used within the duration from removal, e.g. the versions
+	// being downloaded.
+	MinAge metav1.Duration `yaml:"minAge"`
+}
+
+// DefaultConfig returns the config keeping the last 2 versions of each data item.
+func DefaultConfig() *Config {
+	return &Config{
+		HostPath:           "/var/lib/kuda",
+		HostCacheIsolation: utils.HostCacheIsolationShared,
+		Interval:           metav1.Duration{Duration: 10 * time.Minute},
+		KeepVersions:       2,
+		MinAge:             metav1.Duration{Duration: time.Hour},
+	}
+}
+
+// LoadConfig returns config from the file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := DefaultConfig()
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate validates the config.
+func (c *Config) Validate() error {
+	if c.HostPath == "" {
+		return fmt.Errorf("hostPath must be set")
+	}
+	if !utils.IsValidHostCacheIsolation(c.HostCacheIsolation) {
+		return fmt.Errorf("unsupported hostCacheIsolation %s", c.HostCacheIsolation)
+	}
+	if c.Interval.Duration <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if c.KeepVersions < 0 {
+		return fmt.Errorf("keepVersions must be greater than or equal to 0")
+	}
+	if c.MaxSize != nil && c.MaxSize.Sign() < 0 {
+		return fmt.Errorf("maxSize must be greater than or equal to 0")
+	}
+	if c.MinAge.Duration < 0 {
+		return fmt.Errorf("minAge must be greater than or equal to 0")
+	}
+	return nil
+}
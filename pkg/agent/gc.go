@@ -0,0 +1,288 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/dedup"
+	"github.com/kuda-io/kuda/pkg/metrics"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	reasonCacheRemoved       = "CacheVersionRemoved"
+	reasonFailedRemoveCache  = "FailedRemoveCache"
+	reasonFailedCollectCache = "FailedCollectCache"
+)
+
+var (
+	log = ctrl.Log.WithName("agent")
+)
+
+// GarbageCollector removes the stale versions from the host cache of a node.
+type GarbageCollector struct {
+	config   *Config
+	nodeName string
+	client   client.Client
+	reader   client.Reader
+	recorder record.EventRecorder
+	now      func() time.Time
+}
+
+// NewGarbageCollector returns GarbageCollector object of the node. The reader should read
+// from the API server directly, since the pods are listed by the node name.
+func NewGarbageCollector(config *Config, nodeName string, c client.Client, reader client.Reader, recorder record.EventRecorder) *GarbageCollector {
+	return &GarbageCollector{
+		config:   config,
+		nodeName: nodeName,
+		client:   c,
+		reader:   reader,
+		recorder: recorder,
+		now:      time.Now,
+	}
+}
+
+// Start runs the garbage collection periodically until the context is done, it implements
+// manager.Runnable.
+func (gc *GarbageCollector) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := gc.Collect(ctx); err != nil {
+			log.Error(err, "failed to collect host cache")
+			metrics.CacheGCErrorsTotal.Inc()
+			gc.recorder.Eventf(gc.node(), v1.EventTypeWarning, reasonFailedCollectCache, "Failed to collect host cache: %v", err)
+		}
+	}, gc.config.Interval.Duration)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the collector runs on
+// every node.
+func (gc *GarbageCollector) NeedLeaderElection() bool {
+	return false
+}
+
+// Collect removes the versions selected by the config from the host cache, and the
+// NodeData of the node is updated accordingly.
+func (gc *GarbageCollector) Collect(ctx context.Context) error {
+	versions, err := ScanCache(gc.config.HostPath, gc.config.HostCacheIsolation)
+	if err != nil {
+		return err
+	}
+
+	inUse, err := gc.getVersionsInUse(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodeData := &datav1alpha1.NodeData{}
+	if err := gc.reader.Get(ctx, types.NamespacedName{Name: gc.nodeName}, nodeData); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	// The versions used by pods are accessed through the mounts rather than the cache
+	// directory, so the last used time recorded in the NodeData is taken into account.
+	lastUsed := make(map[string]time.Time, len(nodeData.Status.Items))
+	for _, item := range nodeData.Status.Items {
+		lastUsed[cachedItemKey(&item)] = item.LastUsed.Time
+	}
+	for i := range versions {
+		if t, ok := lastUsed[versions[i].Key()]; ok && t.After(versions[i].LastAccess) {
+			versions[i].LastAccess = t
+		}
+	}
+
+	removed := make(map[string]bool)
+	for _, eviction := range SelectEvictions(versions, inUse, gc.config, gc.now()) {
+		version := eviction.Version
+		if err := RemoveVersion(gc.config.HostPath, &version); err != nil {
+			log.Error(err, "failed to remove version", "version", version.Key())
+			gc.recorder.Eventf(gc.node(), v1.EventTypeWarning, reasonFailedRemoveCache, "Failed to remove %s from host cache: %v", version.Key(), err)
+			continue
+		}
+		removed[version.Key()] = true
+
+		log.Info("removed version from host cache", "version", version.Key(), "size", eviction.Size, "reason", eviction.Reason)
+		metrics.CacheRemovedVersionsTotal.WithLabelValues(eviction.Reason).Inc()
+		metrics.CacheRemovedBytesTotal.WithLabelValues(eviction.Reason).Add(float64(eviction.Size))
+		gc.recorder.Eventf(gc.node(), v1.EventTypeNormal, reasonCacheRemoved, "Removed %s (%s) from host cache by %s policy",
+			version.Key(), resource.NewQuantity(eviction.Size, resource.BinarySI), eviction.Reason)
+	}
+
+	if err := gc.collectContent(); err != nil {
+		return err
+	}
+
+	// The files shared by versions are counted once, and the content left unreferenced by
+	// the removed versions is collected above.
+	u := newUsage(versions)
+	for i := range versions {
+		if removed[versions[i].Key()] {
+			u.remove(&versions[i])
+		}
+	}
+	metrics.CacheSizeBytes.Set(float64(u.total))
+	metrics.CacheVersions.Set(float64(len(versions) - len(removed)))
+
+	if len(removed) == 0 || nodeData.Name == "" {
+		return nil
+	}
+	return gc.updateNodeData(ctx, nodeData, removed)
+}
+
+// collectContent removes the content no longer linked into any version from the content
+// stores of the tenants, the content stored within the min age is kept since it may not be
+// linked yet.
+func (gc *GarbageCollector) collectContent() error {
+	tenants, err := scanTenants(gc.config.HostPath, gc.config.HostCacheIsolation)
+	if err != nil {
+		return err
+	}
+
+	before := gc.now().Add(-gc.config.MinAge.Duration)
+	for _, tenant := range tenants {
+		store := &dedup.Store{Root: filepath.Join(gc.config.HostPath, tenant, dedup.DirName)}
+		if err := store.RemoveStale(before); err != nil {
+			return err
+		}
+		entries, err := store.Unreferenced(before)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			key := tenantKey(tenant, entry.Key)
+			if err := store.Remove(entry.Key); err != nil {
+				log.Error(err, "failed to remove content", "content", key)
+				gc.recorder.Eventf(gc.node(), v1.EventTypeWarning, reasonFailedRemoveCache, "Failed to remove content %s from host cache: %v", key, err)
+				continue
+			}
+
+			log.Info("removed content from host cache", "content", key, "size", entry.Size)
+			metrics.CacheRemovedVersionsTotal.WithLabelValues(metrics.GCReasonUnreferenced).Inc()
+			metrics.CacheRemovedBytesTotal.WithLabelValues(metrics.GCReasonUnreferenced).Add(float64(entry.Size))
+		}
+	}
+	return nil
+}
+
+// getVersionsInUse returns the versions referenced by the data resources of the pods on
+// the node, and the data resources prefetching onto the node. The data resources are
+// listed by their labels, rather than all of the cluster.
+func (gc *GarbageCollector) getVersionsInUse(ctx context.Context) (map[string]bool, error) {
+	podList := &v1.PodList{}
+	if err := gc.reader.List(ctx, podList, client.MatchingFields{"spec.nodeName": gc.nodeName}); err != nil {
+		return nil, err
+	}
+	pods := make(map[string]bool, len(podList.Items))
+	names := sets.NewString()
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != gc.nodeName {
+			continue
+		}
+		pods[pod.Namespace+"/"+pod.Name] = true
+		// The pods named beyond the label values never have data resources.
+		if len(validation.IsValidLabelValue(pod.Name)) == 0 {
+			names.Insert(pod.Name)
+		}
+	}
+
+	dataList := &datav1alpha1.DataList{}
+	if names.Len() > 0 {
+		requirement, err := labels.NewRequirement(datav1alpha1.KudaKeyPod, selection.In, names.List())
+		if err != nil {
+			return nil, err
+		}
+		if err := gc.reader.List(ctx, dataList, client.MatchingLabelsSelector{Selector: labels.NewSelector().Add(*requirement)}); err != nil {
+			return nil, err
+		}
+	}
+	prefetchList := &datav1alpha1.DataList{}
+	if err := gc.reader.List(ctx, prefetchList, client.MatchingLabels{datav1alpha1.KudaKeyNode: gc.nodeName}); err != nil {
+		return nil, err
+	}
+
+	inUse := make(map[string]bool)
+	for _, data := range append(dataList.Items, prefetchList.Items...) {
+		// The pods of the same name in the other namespaces are not on the node.
+		if !pods[data.Namespace+"/"+data.Labels[datav1alpha1.KudaKeyPod]] && data.Labels[datav1alpha1.KudaKeyNode] != gc.nodeName {
+			continue
+		}
+		dataset := data.Labels[datav1alpha1.KudaKeyDataSet]
+		if owner := metav1.GetControllerOf(&data); owner != nil {
+			dataset = owner.Name
+		}
+		tenant := utils.HostCacheSubPath(gc.config.HostCacheIsolation, data.Namespace, dataset)
+		for _, item := range data.Spec.DataItems {
+			inUse[tenantKey(tenant, versionKey(item.Namespace, item.Name, item.Version))] = true
+		}
+		for _, item := range data.Status.DataItemsStatus {
+			inUse[tenantKey(tenant, versionKey(item.Namespace, item.Name, item.Version))] = true
+		}
+	}
+
+	return inUse, nil
+}
+
+// updateNodeData removes the versions removed from the host cache from the NodeData.
+func (gc *GarbageCollector) updateNodeData(ctx context.Context, nodeData *datav1alpha1.NodeData, removed map[string]bool) error {
+	items := make([]datav1alpha1.CachedDataItem, 0, len(nodeData.Status.Items))
+	var size int64
+	for _, item := range nodeData.Status.Items {
+		if removed[cachedItemKey(&item)] {
+			continue
+		}
+		items = append(items, item)
+		size += item.Size
+	}
+	if len(items) == len(nodeData.Status.Items) {
+		return nil
+	}
+
+	nodeData.Status.Items = items
+	if len(items) == 0 {
+		nodeData.Status.Items = nil
+	}
+	nodeData.Status.ItemsNum = len(items)
+	nodeData.Status.Size = size
+	return gc.client.Status().Update(ctx, nodeData)
+}
+
+// cachedItemKey returns the key of the cached data item of the NodeData, which is the same
+// as the key of the version in the host cache of the tenant.
+func cachedItemKey(item *datav1alpha1.CachedDataItem) string {
+	return tenantKey(item.Tenant, versionKey(item.Namespace, item.Name, item.Version))
+}
+
+// node returns the reference of the node for events, whose uid is the node name as the kubelet does.
+func (gc *GarbageCollector) node() *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: gc.nodeName, UID: types.UID(gc.nodeName)}}
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/dedup"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+func getTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = datav1alpha1.AddToScheme(scheme)
+	return scheme
+}
+
+// writeTestVersion writes a version of size bytes into the host cache, which is accessed at the time.
+func writeTestVersion(t *testing.T, root, name, version string, size int, accessed time.Time) {
+	dir := filepath.Join(root, "ns", name, version)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "file"), make([]byte, size), 0644))
+	assert.NoError(t, os.Chtimes(dir, accessed, accessed))
+}
+
+func TestScanCache(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuda-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	now := time.Now().Truncate(time.Second)
+	writeTestVersion(t, root, "model", "v1", 100, now)
+	writeTestVersion(t, root, "model", "v2", 200, now)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "ns", "README"), []byte("kuda"), 0644))
+
+	versions, err := ScanCache(root, utils.HostCacheIsolationShared)
+	assert.NoError(t, err)
+	assert.Equal(t, []CachedVersion{
+		{Namespace: "ns", Name: "model", Version: "v1", Path: filepath.Join(root, "ns/model/v1"), Size: 100, LastAccess: now},
+		{Namespace: "ns", Name: "model", Version: "v2", Path: filepath.Join(root, "ns/model/v2"), Size: 200, LastAccess: now},
+	}, stripMonotonic(versions))
+
+	// The empty parent directories are removed with the last version.
+	assert.NoError(t, RemoveVersion(root, &versions[0]))
+	assert.DirExists(t, filepath.Join(root, "ns/model"))
+	assert.NoError(t, RemoveVersion(root, &versions[1]))
+	assert.NoDirExists(t, filepath.Join(root, "ns/model"))
+	assert.DirExists(t, filepath.Join(root, "ns"))
+
+	// The version out of the host cache is never removed.
+	escaped := CachedVersion{Namespace: "ns", Name: "..", Version: "..", Path: root}
+	assert.Error(t, RemoveVersion(root, &escaped))
+	assert.DirExists(t, root)
+
+	versions, err = ScanCache(filepath.Join(root, "not-exist"), utils.HostCacheIsolationShared)
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestScanCacheLinked(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuda-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	store := &dedup.Store{Root: filepath.Join(root, dedup.DirName)}
+	_, err = store.Populate(context.Background(), "content", func(dir string) error {
+		return ioutil.WriteFile(filepath.Join(dir, "file"), make([]byte, 100), 0644)
+	})
+	assert.NoError(t, err)
+	path := func(version, file string) string {
+		return filepath.Join(root, "ns", "model", version, file)
+	}
+	// v1 and v2 link the content, v2 links a file twice and v3 and v4 share a file.
+	assert.NoError(t, store.Link("content", filepath.Dir(path("v1", "file"))))
+	assert.NoError(t, store.Link("content", filepath.Dir(path("v2", "file"))))
+	assert.NoError(t, ioutil.WriteFile(path("v2", "a"), make([]byte, 10), 0644))
+	assert.NoError(t, os.Link(path("v2", "a"), path("v2", "b")))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path("v3", "x")), 0755))
+	assert.NoError(t, ioutil.WriteFile(path("v3", "x"), make([]byte, 20), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path("v4", "x")), 0755))
+	assert.NoError(t, os.Link(path("v3", "x"), path("v4", "x")))
+
+	versions, err := ScanCache(root, utils.HostCacheIsolationShared)
+	assert.NoError(t, err)
+	sizes := make(map[string]int64, len(versions))
+	for _, version := range versions {
+		sizes[version.Version] = version.Size
+	}
+	assert.Equal(t, map[string]int64{"v1": 0, "v2": 10, "v3": 0, "v4": 0}, sizes)
+
+	// The bytes are freed once the last version linking them is removed.
+	u := newUsage(versions)
+	assert.Equal(t, int64(130), u.total)
+	freed := make([][2]int64, 0, len(versions))
+	for i := range versions {
+		size, unreferenced := u.remove(&versions[i])
+		freed = append(freed, [2]int64{size, unreferenced})
+	}
+	assert.Equal(t, [][2]int64{{0, 0}, {10, 100}, {0, 0}, {20, 0}}, freed)
+	assert.Equal(t, int64(0), u.total)
+}
+
+func stripMonotonic(versions []CachedVersion) []CachedVersion {
+	for i := range versions {
+		versions[i].LastAccess = versions[i].LastAccess.Round(0)
+	}
+	return versions
+}
+
+func TestGarbageCollectorCollect(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuda-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	writeTestVersion(t, root, "model", "v1", 100, now.Add(-72*time.Hour))
+	writeTestVersion(t, root, "model", "v2", 100, now.Add(-48*time.Hour))
+	writeTestVersion(t, root, "model", "v3", 100, now.Add(-24*time.Hour))
+	writeTestVersion(t, root, "model", "v4", 100, now.Add(-12*time.Hour))
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod-a", Namespace: "default"},
+		Spec:       v1.PodSpec{NodeName: "node-a"},
+	}
+	// model@v1 is used by the pod on the node.
+	data := &datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-data",
+			Namespace: "default",
+			Labels:    map[string]string{datav1alpha1.KudaKeyPod: "test-pod-a"},
+		},
+		Spec: datav1alpha1.DataSpec{DataItems: []datav1alpha1.DataItem{{Name: "model", Namespace: "ns", Version: "v1"}}},
+	}
+	// model@v2 is used by the pod on the other node.
+	otherData := &datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-data-other",
+			Namespace: "default",
+			Labels:    map[string]string{datav1alpha1.KudaKeyPod: "test-pod-b"},
+		},
+		Spec: datav1alpha1.DataSpec{DataItems: []datav1alpha1.DataItem{{Name: "model", Namespace: "ns", Version: "v2"}}},
+	}
+	// model@v2 is used by the pod of the same name in the other namespace.
+	otherNamespaceData := otherData.DeepCopy()
+	otherNamespaceData.Namespace = "other"
+	otherNamespaceData.Labels[datav1alpha1.KudaKeyPod] = "test-pod-a"
+	// model@v3 is used recently, as recorded in the node data.
+	nodeData := &datav1alpha1.NodeData{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status: datav1alpha1.NodeDataStatus{
+			Items: []datav1alpha1.CachedDataItem{
+				{Name: "model", Namespace: "ns", Version: "v1", Size: 100, LastUsed: metav1.NewTime(now.Add(-72 * time.Hour))},
+				{Name: "model", Namespace: "ns", Version: "v2", Size: 100, LastUsed: metav1.NewTime(now.Add(-48 * time.Hour))},
+				{Name: "model", Namespace: "ns", Version: "v3", Size: 100, LastUsed: metav1.NewTime(now.Add(-time.Minute))},
+			},
+			ItemsNum: 3,
+			Size:     300,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(getTestScheme()).WithObjects(pod, data, otherData, otherNamespaceData, nodeData).Build()
+
+	config := &Config{HostPath: root, KeepVersions: 2, MinAge: metav1.Duration{Duration: time.Hour}}
+	recorder := record.NewFakeRecorder(10)
+	gc := NewGarbageCollector(config, "node-a", c, c, recorder)
+	gc.now = func() time.Time { return now }
+
+	assert.NoError(t, gc.Collect(context.Background()))
+	versions, err := ScanCache(root, utils.HostCacheIsolationShared)
+	assert.NoError(t, err)
+	remained := make([]string, 0, len(versions))
+	for _, version := range versions {
+		remained = append(remained, version.Version)
+	}
+	assert.Equal(t, []string{"v1", "v3", "v4"}, remained)
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, reasonCacheRemoved)
+
+	newNodeData := &datav1alpha1.NodeData{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "node-a"}, newNodeData))
+	assert.Equal(t, 2, newNodeData.Status.ItemsNum)
+	assert.Equal(t, int64(200), newNodeData.Status.Size)
+	assert.Equal(t, "v1", newNodeData.Status.Items[0].Version)
+	assert.Equal(t, "v3", newNodeData.Status.Items[1].Version)
+}
+
+func TestGarbageCollectorCollectIsolated(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuda-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	for _, tenant := range []string{"default/ds-a", "default/ds-b"} {
+		writeTestVersion(t, filepath.Join(root, tenant), "model", "v1", 100, now.Add(-48*time.Hour))
+		writeTestVersion(t, filepath.Join(root, tenant), "model", "v2", 100, now.Add(-24*time.Hour))
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod-a", Namespace: "default"},
+		Spec:       v1.PodSpec{NodeName: "node-a"},
+	}
+	controller := true
+	// model@v1 is used by the pod of ds-a on the node.
+	data := &datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "ds-a-pod-a",
+			Namespace:       "default",
+			Labels:          map[string]string{datav1alpha1.KudaKeyPod: "test-pod-a"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DataSet", Name: "ds-a", Controller: &controller}},
+		},
+		Spec: datav1alpha1.DataSpec{DataItems: []datav1alpha1.DataItem{{Name: "model", Namespace: "ns", Version: "v1"}}},
+	}
+	nodeData := &datav1alpha1.NodeData{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status: datav1alpha1.NodeDataStatus{
+			Items: []datav1alpha1.CachedDataItem{
+				{Tenant: "default/ds-a", Name: "model", Namespace: "ns", Version: "v1", Size: 100, Pods: 1, LastUsed: metav1.NewTime(now.Add(-48 * time.Hour))},
+				{Tenant: "default/ds-b", Name: "model", Namespace: "ns", Version: "v1", Size: 100, LastUsed: metav1.NewTime(now.Add(-48 * time.Hour))},
+			},
+			ItemsNum: 2,
+			Size:     200,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(getTestScheme()).WithObjects(pod, data, nodeData).Build()
+
+	config := &Config{HostPath: root, HostCacheIsolation: utils.HostCacheIsolationDataSet, KeepVersions: 1}
+	gc := NewGarbageCollector(config, "node-a", c, c, record.NewFakeRecorder(10))
+	gc.now = func() time.Time { return now }
+
+	assert.NoError(t, gc.Collect(context.Background()))
+	versions, err := ScanCache(root, utils.HostCacheIsolationDataSet)
+	assert.NoError(t, err)
+	remained := make([]string, 0, len(versions))
+	for _, version := range versions {
+		remained = append(remained, version.Key())
+	}
+	assert.Equal(t, []string{"default/ds-a:ns/model@v1", "default/ds-a:ns/model@v2", "default/ds-b:ns/model@v2"}, remained)
+
+	// model@v1 is removed for ds-b only.
+	newNodeData := &datav1alpha1.NodeData{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "node-a"}, newNodeData))
+	assert.Equal(t, 1, newNodeData.Status.ItemsNum)
+	assert.Equal(t, "default/ds-a", newNodeData.Status.Items[0].Tenant)
+}
+
+func TestGarbageCollectorCollectContent(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuda-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	store := &dedup.Store{Root: filepath.Join(root, dedup.DirName)}
+	for _, version := range []string{"v1", "v2"} {
+		_, err := store.Populate(context.Background(), version, func(dir string) error {
+			return ioutil.WriteFile(filepath.Join(dir, "file"), make([]byte, 100), 0644)
+		})
+		assert.NoError(t, err)
+		dir := filepath.Join(root, "ns", "model", version)
+		assert.NoError(t, store.Link(version, dir))
+		stored := now.Add(-48 * time.Hour)
+		if version == "v2" {
+			stored = now.Add(-24 * time.Hour)
+		}
+		assert.NoError(t, os.Chtimes(dir, stored, stored))
+		assert.NoError(t, os.Chtimes(store.Path(version), stored, stored))
+	}
+	// The content stored just now is not linked yet.
+	_, err = store.Populate(context.Background(), "v3", func(dir string) error { return nil })
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chtimes(store.Path("v3"), now, now))
+
+	c := fake.NewClientBuilder().WithScheme(getTestScheme()).Build()
+	config := &Config{HostPath: root, HostCacheIsolation: utils.HostCacheIsolationShared, KeepVersions: 1, MinAge: metav1.Duration{Duration: time.Hour}}
+	gc := NewGarbageCollector(config, "node-a", c, c, record.NewFakeRecorder(10))
+	gc.now = func() time.Time { return now }
+
+	assert.NoError(t, gc.Collect(context.Background()))
+	versions, err := ScanCache(root, utils.HostCacheIsolationShared)
+	assert.NoError(t, err)
+	if assert.Len(t, versions, 1) {
+		assert.Equal(t, "v2", versions[0].Version)
+	}
+	for key, exists := range map[string]bool{"v1": false, "v2": true, "v3": true} {
+		_, err := os.Stat(store.Path(key))
+		assert.Equal(t, exists, err == nil, key)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kuda-io/kuda/pkg/metrics"
+)
+
+// Eviction describes a version selected to be removed from the host cache.
+type Eviction struct {
+	Version CachedVersion
+	// Reason is one of the gc reasons of the metrics.
+	Reason string
+	// Size is the bytes freed by removing the version. The content left unreferenced is
+	// freed by the collection of the content store, which is not counted.
+	Size int64
+}
+
+// usage accounts the bytes of the host cache, each file is counted once however many
+// versions link it. The file shared by versions is freed once all of them are removed,
+// and the file in the content store once it's no longer linked into any version.
+type usage struct {
+	total int64
+	// remaining are the numbers of the links of the shared files out of the content store.
+	remaining map[fileID]uint64
+}
+
+func newUsage(versions []CachedVersion) *usage {
+	u := &usage{remaining: make(map[fileID]uint64)}
+	for _, v := range versions {
+		u.total += v.Size
+		for _, file := range v.linked {
+			if _, ok := u.remaining[file.id]; ok {
+				continue
+			}
+			u.remaining[file.id] = file.nlink
+			if file.stored {
+				u.remaining[file.id]--
+			}
+			u.total += file.size
+		}
+	}
+	return u
+}
+
+// remove removes the version from the usage, and returns the bytes freed by removing the
+// version directory and the bytes of the content left unreferenced.
+func (u *usage) remove(v *CachedVersion) (int64, int64) {
+	freed, unreferenced := v.Size, int64(0)
+	for _, file := range v.linked {
+		if u.remaining[file.id] <= file.links {
+			u.remaining[file.id] = 0
+			if file.stored {
+				unreferenced += file.size
+			} else {
+				freed += file.size
+			}
+			continue
+		}
+		u.remaining[file.id] -= file.links
+	}
+	u.total -= freed + unreferenced
+	return freed, unreferenced
+}
+
+// SelectEvictions selects the versions to be removed by the config. The versions in use
+// and the versions accessed within MinAge are never selected.
+//
+// The versions of each data item beyond the KeepVersions most recently accessed ones are
+// selected first, then the least recently accessed versions are selected until the host
+// cache fits in MaxSize. The size of the host cache counts the files shared by versions
+// once, and the content left unreferenced by the evictions is taken as freed, since it's
+// collected from the content store afterwards.
+func SelectEvictions(versions []CachedVersion, inUse map[string]bool, config *Config, now time.Time) []Eviction {
+	evictions := make([]Eviction, 0)
+	evicted := make(map[string]bool)
+	u := newUsage(versions)
+	protected := func(v *CachedVersion) bool {
+		return inUse[v.Key()] || now.Sub(v.LastAccess) < config.MinAge.Duration
+	}
+
+	sorted := make([]CachedVersion, len(versions))
+	copy(sorted, versions)
+	// The most recently accessed first, ties are broken by the version in descending order.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].LastAccess.Equal(sorted[j].LastAccess) {
+			return sorted[i].LastAccess.After(sorted[j].LastAccess)
+		}
+		return sorted[i].Version > sorted[j].Version
+	})
+
+	if config.KeepVersions > 0 {
+		kept := make(map[string]int)
+		for _, v := range sorted {
+			item := tenantKey(v.Tenant, v.Namespace+"/"+v.Name)
+			if kept[item] < config.KeepVersions {
+				kept[item]++
+				continue
+			}
+			if protected(&v) {
+				continue
+			}
+			freed, _ := u.remove(&v)
+			evicted[v.Key()] = true
+			evictions = append(evictions, Eviction{Version: v, Reason: metrics.GCReasonVersions, Size: freed})
+		}
+	}
+
+	if config.MaxSize == nil {
+		return evictions
+	}
+	for i := len(sorted) - 1; i >= 0 && u.total > config.MaxSize.Value(); i-- {
+		v := sorted[i]
+		if evicted[v.Key()] || protected(&v) {
+			continue
+		}
+		freed, _ := u.remove(&v)
+		evicted[v.Key()] = true
+		evictions = append(evictions, Eviction{Version: v, Reason: metrics.GCReasonSize, Size: freed})
+	}
+
+	return evictions
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kuda-io/kuda/pkg/metrics"
+)
+
+func getEvictedKeys(evictions []Eviction) map[string]string {
+	keys := make(map[string]string, len(evictions))
+	for _, eviction := range evictions {
+		keys[eviction.Version.Key()] = eviction.Reason
+	}
+	return keys
+}
+
+func TestSelectEvictions(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	newVersion := func(name, version string, size int64, age time.Duration) CachedVersion {
+		return CachedVersion{Namespace: "ns", Name: name, Version: version, Size: size, LastAccess: now.Add(-age)}
+	}
+	versions := []CachedVersion{
+		newVersion("model", "v1", 400, 72*time.Hour),
+		newVersion("model", "v2", 400, 48*time.Hour),
+		newVersion("model", "v3", 400, 24*time.Hour),
+		newVersion("model", "v4", 400, 10*time.Minute),
+		newVersion("dict", "v1", 100, 96*time.Hour),
+	}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		inUse    map[string]bool
+		expected map[string]string
+	}{
+		{
+			name:   "keep versions",
+			config: &Config{KeepVersions: 2},
+			expected: map[string]string{
+				"ns/model@v1": metrics.GCReasonVersions,
+				"ns/model@v2": metrics.GCReasonVersions,
+			},
+		},
+		{
+			name:   "versions in use are kept",
+			config: &Config{KeepVersions: 2},
+			inUse:  map[string]bool{"ns/model@v1": true},
+			expected: map[string]string{
+				"ns/model@v2": metrics.GCReasonVersions,
+			},
+		},
+		{
+			name:   "versions accessed recently are kept",
+			config: &Config{KeepVersions: 1, MinAge: metav1.Duration{Duration: 36 * time.Hour}},
+			expected: map[string]string{
+				"ns/model@v1": metrics.GCReasonVersions,
+				"ns/model@v2": metrics.GCReasonVersions,
+			},
+		},
+		{
+			name:   "max size",
+			config: &Config{MaxSize: resource.NewQuantity(1000, resource.BinarySI)},
+			expected: map[string]string{
+				"ns/dict@v1":  metrics.GCReasonSize,
+				"ns/model@v1": metrics.GCReasonSize,
+				"ns/model@v2": metrics.GCReasonSize,
+			},
+		},
+		{
+			name:   "keep versions and max size",
+			config: &Config{KeepVersions: 3, MaxSize: resource.NewQuantity(800, resource.BinarySI), MinAge: metav1.Duration{Duration: time.Hour}},
+			inUse:  map[string]bool{"ns/model@v3": true},
+			expected: map[string]string{
+				"ns/model@v1": metrics.GCReasonVersions,
+				"ns/dict@v1":  metrics.GCReasonSize,
+				"ns/model@v2": metrics.GCReasonSize,
+			},
+		},
+		{
+			name:     "nothing to remove",
+			config:   &Config{},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evictions := SelectEvictions(versions, test.inUse, test.config, now)
+			assert.Equal(t, test.expected, getEvictedKeys(evictions))
+		})
+	}
+
+	t.Run("shared files are counted once", func(t *testing.T) {
+		shared := linkedFile{id: fileID{ino: 1}, size: 400, links: 1, nlink: 2}
+		linked := []CachedVersion{
+			newVersion("model", "v1", 100, 72*time.Hour),
+			newVersion("model", "v2", 100, 48*time.Hour),
+			newVersion("model", "v3", 100, 24*time.Hour),
+		}
+		linked[0].linked = []linkedFile{shared}
+		linked[1].linked = []linkedFile{shared}
+
+		// The shared file is freed with the second version removed.
+		evictions := SelectEvictions(linked, nil, &Config{MaxSize: resource.NewQuantity(300, resource.BinarySI)}, now)
+		if assert.Len(t, evictions, 2) {
+			assert.Equal(t, int64(100), evictions[0].Size)
+			assert.Equal(t, int64(500), evictions[1].Size)
+		}
+	})
+}
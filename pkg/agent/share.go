@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/p2p"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+// CacheSharer serves the versions in the host cache of a node to the kuda runtimes
+// downloading the data items with the p2p policy. Only the shared host cache is served,
+// since the versions isolated by the tenants are not meant for the others.
+type CacheSharer struct {
+	config   *Config
+	nodeName string
+	// bindAddress is the address the server listens on, and address is the one advertised
+	// to the peers by the kuda.io/peer-address annotation of the NodeData.
+	bindAddress string
+	address     string
+	client      client.Client
+	reader      client.Reader
+	server      *p2p.Server
+	interval    time.Duration
+	// manifests are the manifests of the shared versions by the data item key, which are
+	// built once since a version is not changed once downloaded.
+	manifests map[string]*p2p.Manifest
+}
+
+// NewCacheSharer returns CacheSharer object of the node.
+func NewCacheSharer(config *Config, nodeName, bindAddress, address string, c client.Client, reader client.Reader) *CacheSharer {
+	return &CacheSharer{
+		config:      config,
+		nodeName:    nodeName,
+		bindAddress: bindAddress,
+		address:     address,
+		client:      c,
+		reader:      reader,
+		server:      p2p.NewServer(),
+		interval:    time.Minute,
+		manifests:   make(map[string]*p2p.Manifest),
+	}
+}
+
+// Start serves the host cache and refreshes the versions shared periodically until the
+// context is done, it implements manager.Runnable.
+func (s *CacheSharer) Start(ctx context.Context) error {
+	server := &http.Server{Addr: s.bindAddress, Handler: s.server}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.Refresh(ctx); err != nil {
+			log.Error(err, "failed to share host cache")
+		}
+	}, s.interval)
+
+	log.Info("sharing host cache", "bindAddress", s.bindAddress, "address", s.address)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the sharer runs on every
+// node.
+func (s *CacheSharer) NeedLeaderElection() bool {
+	return false
+}
+
+// Refresh shares the versions in the host cache which are recorded in the NodeData, i.e.
+// downloaded completely, and stops sharing the removed ones. The address of the node is
+// advertised in the NodeData.
+func (s *CacheSharer) Refresh(ctx context.Context) error {
+	if s.config.HostCacheIsolation != utils.HostCacheIsolationShared {
+		return nil
+	}
+
+	nodeData := &datav1alpha1.NodeData{}
+	if err := s.reader.Get(ctx, types.NamespacedName{Name: s.nodeName}, nodeData); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	cached := make(map[string]bool, len(nodeData.Status.Items))
+	for _, item := range nodeData.Status.Items {
+		cached[cachedItemKey(&item)] = true
+	}
+
+	versions, err := ScanCache(s.config.HostPath, s.config.HostCacheIsolation)
+	if err != nil {
+		return err
+	}
+	shared := make(map[p2p.Item]bool, len(versions))
+	for _, version := range versions {
+		key := version.DataItemKey()
+		if !cached[version.Key()] {
+			continue
+		}
+		item := p2p.Item{Namespace: version.Namespace, Name: version.Name, Version: version.Version}
+		if _, ok := s.manifests[key]; !ok {
+			manifest, err := p2p.BuildManifest(version.Path, p2p.DefaultPieceSize)
+			if err != nil {
+				log.Error(err, "failed to build manifest", "version", key)
+				continue
+			}
+			s.manifests[key] = manifest
+			s.server.Share(item, version.Path, manifest, true)
+		}
+		shared[item] = true
+	}
+	for _, item := range s.server.Items() {
+		if !shared[item] {
+			s.server.Unshare(item)
+			delete(s.manifests, versionKey(item.Namespace, item.Name, item.Version))
+		}
+	}
+
+	if nodeData.Annotations[datav1alpha1.KudaKeyPeerAddress] == s.address {
+		return nil
+	}
+	patch := client.MergeFrom(nodeData.DeepCopy())
+	if nodeData.Annotations == nil {
+		nodeData.Annotations = make(map[string]string)
+	}
+	nodeData.Annotations[datav1alpha1.KudaKeyPeerAddress] = s.address
+	return s.client.Patch(ctx, nodeData, patch)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/p2p"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+func TestCacheSharerRefresh(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuda-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	now := time.Now()
+	writeTestVersion(t, root, "model", "v1", 100, now)
+	// model@v2 is being downloaded, which is not recorded in the node data yet.
+	writeTestVersion(t, root, "model", "v2", 100, now)
+
+	nodeData := &datav1alpha1.NodeData{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status: datav1alpha1.NodeDataStatus{
+			Items: []datav1alpha1.CachedDataItem{{Name: "model", Namespace: "ns", Version: "v1", Size: 100}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(getTestScheme()).WithObjects(nodeData).Build()
+
+	config := &Config{HostPath: root, HostCacheIsolation: utils.HostCacheIsolationShared}
+	sharer := NewCacheSharer(config, "node-a", ":0", "10.0.0.1:8082", c, c)
+	assert.NoError(t, sharer.Refresh(context.Background()))
+	item := p2p.Item{Namespace: "ns", Name: "model", Version: "v1"}
+	assert.Equal(t, []p2p.Item{item}, sharer.server.Items())
+
+	newNodeData := &datav1alpha1.NodeData{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "node-a"}, newNodeData))
+	assert.Equal(t, "10.0.0.1:8082", newNodeData.Annotations[datav1alpha1.KudaKeyPeerAddress])
+
+	// A kuda runtime downloads the version from the node agent.
+	server := httptest.NewServer(sharer.server)
+	defer server.Close()
+	dir := t.TempDir()
+	d := &p2p.Downloader{
+		Item: item,
+		Dir:  dir,
+		Peers: p2p.PeersFunc(func(context.Context, p2p.Item) ([]string, error) {
+			return []string{strings.TrimPrefix(server.URL, "http://")}, nil
+		}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status, err := d.Download(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) {
+		assert.Equal(t, int64(100), status.BytesFromPeers)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "file"))
+	assert.NoError(t, err)
+	assert.Len(t, data, 100)
+
+	// The version removed from the node data is not shared anymore.
+	newNodeData.Status.Items = nil
+	assert.NoError(t, c.Status().Update(context.Background(), newNodeData))
+	assert.NoError(t, sharer.Refresh(context.Background()))
+	assert.Empty(t, sharer.server.Items())
+}
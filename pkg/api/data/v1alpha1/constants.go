@@ -20,9 +20,39 @@ const (
 	KudaKeyPod     = "kuda.io/pod"
 	KudaKeyDataSet = "kuda.io/dataset"
 	KudaKeyDigest  = "kuda.io/data-digest"
+	// KudaKeyRevision is the label of the data resource, indicating the revision of the template it comes from.
+	KudaKeyRevision = "kuda.io/revision"
+
+	// KudaKeyRestartedAt is set on the pod template of a workload when it is restarted for the missed injection.
+	KudaKeyRestartedAt = "kuda.io/restartedAt"
+
+	// KudaKeyPrefetch is the label of the prefetch pods and their data resources, indicating the DataSet they prefetch.
+	KudaKeyPrefetch = "kuda.io/prefetch"
+	// KudaKeyNode is the label of the prefetch data resources, indicating the node they prefetch onto.
+	KudaKeyNode = "kuda.io/node"
+
+	// KudaKeyHostCache is the label of the namespace, the pods in the namespace don't use the
+	// host cache if it's set to HostCacheDisabled.
+	KudaKeyHostCache  = "kuda.io/host-cache"
+	HostCacheDisabled = "disabled"
+
+	// KudaKeyPeerAddress is the annotation of the NodeData, indicating the address the node agent
+	// serves the pieces of the data items in the host cache to the peers.
+	KudaKeyPeerAddress = "kuda.io/peer-address"
+
+	KudaRuntimeContainerName = "kuda-runtime"
+
+	// KudaCSIDriverName is the name of the CSI driver serving the data of datasets as volumes.
+	KudaCSIDriverName = "csi.kuda.io"
+	// KudaCSIVolumeDataSet is the volume attribute referencing the dataset of the kuda CSI volume.
+	KudaCSIVolumeDataSet = "dataset"
+	// KudaCSIVolumeNamespace is the volume attribute of the namespace of the dataset, it must
+	// be the namespace of the pod if set.
+	KudaCSIVolumeNamespace = "namespace"
 
 	KudaRuntimeEnvDataSetName       = "KUDA_DATASET_NAME"
 	KudaRuntimeEnvDataSetNamespace  = "KUDA_DATASET_NAMESPACE"
 	KudaRuntimeEnvPodName           = "MY_POD_NAME"
 	KudaRuntimeEnvMainContainerName = "MAIN_CONTAINER_NAME"
+	KudaRuntimeEnvPodIP             = "MY_POD_IP"
 )
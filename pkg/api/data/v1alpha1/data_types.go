@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,6 +32,47 @@ type DataSpec struct {
 	DataItems   []DataItem   `json:"dataItems"`
 	Lifecycle   *Lifecycle   `json:"lifecycle,omitempty"`
 	DataSources *DataSources `json:"dataSources"`
+
+	// Queued holds the download of the data items until the controller hands out a download
+	// lease by clearing it, the kuda runtime must not start downloading while it's set.
+	//+optional
+	Queued bool `json:"queued,omitempty"`
+
+	// BandwidthLimit is the bytes per second the kuda runtime is expected to download the
+	// data items at most, it's set by the controller with the download lease by the share
+	// of the bandwidth of the data sources and the node. It's a hint to the kuda runtime,
+	// and no limit if unset.
+	//+optional
+	BandwidthLimit *resource.Quantity `json:"bandwidthLimit,omitempty"`
+
+	// Refreshes request the kuda runtime to re-sync the data items with a schedule, the ones
+	// not synced since their refresh times are synced again even if the versions are
+	// unchanged.
+	//+optional
+	Refreshes []DataItemRefresh `json:"refreshes,omitempty"`
+
+	// Activation holds the data items staged until they are activated, it's set if the
+	// DataSet activates the updates on all the pods at the same time.
+	//+optional
+	Activation *DataActivation `json:"activation,omitempty"`
+}
+
+// DataActivation describes the generation of the data items and the generation activated.
+type DataActivation struct {
+	// Generation is the activation generation of the data items in the spec.
+	Generation int64 `json:"generation"`
+	// ActiveGeneration is the generation activated across the DataSet. The kuda runtime
+	// downloads the data items of a newer generation into the staged phase, without running
+	// postDownload or switching the local paths, until their generation is activated.
+	ActiveGeneration int64 `json:"activeGeneration"`
+}
+
+// DataItemRefresh describes the latest scheduled re-sync of a data item.
+type DataItemRefresh struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Time is the latest time scheduled by the schedule of the data item.
+	Time metav1.Time `json:"time"`
 }
 
 // DataStatus defines the observed state of Data
@@ -40,9 +82,11 @@ type DataStatus struct {
 
 	DataItemsStatus DataItemsStatus `json:"dataItemsStatus"`
 	DataItems       int             `json:"dataItems"`
+	Queued          int             `json:"queued,omitempty"`
 	Success         int             `json:"success"`
 	Waiting         int             `json:"waiting"`
 	Downloading     int             `json:"downloading"`
+	Staged          int             `json:"staged,omitempty"`
 	Failed          int             `json:"failed"`
 	Ready           string          `json:"ready"`
 }
@@ -57,6 +101,74 @@ type DataItemStatus struct {
 	Phase     DataPhase   `json:"phase"`
 	StartTime metav1.Time `json:"startTime"`
 	Message   string      `json:"message,omitempty"`
+	// CompletionTime is the time when the data item is first observed successful.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Size is the bytes of the data item reported by the kuda runtime after downloaded.
+	Size int64 `json:"size,omitempty"`
+	// ExtractedSize is the bytes of the files extracted from the archive of the data item,
+	// which is what's kept in the cache instead of the archive.
+	ExtractedSize int64 `json:"extractedSize,omitempty"`
+	// Files are the changes of the files in the local path made by the last sync, which are
+	// reported by the kuda runtime.
+	Files *FileChanges `json:"files,omitempty"`
+	// Delta is the delta fetched by the last update of the data item with the delta policy,
+	// which is reported by the kuda runtime.
+	Delta *DeltaStatus `json:"delta,omitempty"`
+	// LocalVersions are the versions of the data item with the versioning policy kept
+	// locally, from the current one to the least recently used, which are reported by the
+	// kuda runtime.
+	LocalVersions []string `json:"localVersions,omitempty"`
+	// P2P is the peer-to-peer distribution of the data item with the p2p policy.
+	P2P *P2PStatus `json:"p2p,omitempty"`
+	// Content is the content of the data item shared in the host cache when deduplicated.
+	Content *ContentStatus `json:"content,omitempty"`
+}
+
+// ContentStatus describes the content of a data item in the content store of the host cache.
+type ContentStatus struct {
+	// Key of the content in the content store, see the dedup package.
+	Key string `json:"key"`
+	// Reused is true if the content was downloaded onto the node before, e.g. by another
+	// DataSet, and it's only linked into the data item.
+	Reused bool `json:"reused,omitempty"`
+}
+
+// P2PStatus describes the peer-to-peer distribution of a data item on a pod.
+type P2PStatus struct {
+	// Address is the address the kuda runtime serves the pieces of the data item to the
+	// peers, it's set once the data item is held or being fetched.
+	Address string `json:"address,omitempty"`
+	// Seeder is true if the data item was downloaded from the data source.
+	Seeder bool `json:"seeder,omitempty"`
+	// ManifestDigest is the SHA-256 of the manifest of the pieces. The digests recorded by
+	// the seeders are trusted by the peers, which reject the manifests of other digests.
+	ManifestDigest string `json:"manifestDigest,omitempty"`
+	// BytesFromPeers is the bytes fetched from the peers.
+	BytesFromPeers int64 `json:"bytesFromPeers,omitempty"`
+	// BytesFromOrigin is the bytes downloaded from the data source.
+	BytesFromOrigin int64 `json:"bytesFromOrigin,omitempty"`
+}
+
+// DeltaStatus describes the delta fetched between two versions of a data item.
+type DeltaStatus struct {
+	// Reused is the number of the files reused from the previous version.
+	Reused int `json:"reused"`
+	// Fetched is the number of the files fetched from the remote store.
+	Fetched int `json:"fetched"`
+	// BytesSaved is the bytes of the files reused instead of fetched.
+	BytesSaved int64 `json:"bytesSaved"`
+	// BytesFetched is the bytes of the files fetched.
+	BytesFetched int64 `json:"bytesFetched"`
+}
+
+// FileChanges counts the files changed by a sync of a data item.
+type FileChanges struct {
+	// Added is the number of the files not in the local path before.
+	Added int `json:"added"`
+	// Changed is the number of the files updated in the local path.
+	Changed int `json:"changed"`
+	// Removed is the number of the files removed from the local path.
+	Removed int `json:"removed"`
 }
 
 //+genclient
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DataQuotaSpec defines the limits of the data pulled through kuda in the namespace, the
+// limits not set are unlimited.
+type DataQuotaSpec struct {
+	// MaxSize is the max total declared size of the data items of the DataSets in the namespace.
+	// The data items without a declared size are not counted.
+	// +optional
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+	// MaxItems is the max total number of the data items of the DataSets in the namespace.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxItems *int32 `json:"maxItems,omitempty"`
+	// MaxConcurrentDownloads is the max number of the Data downloading at the same time in
+	// the namespace, including the prefetch onto nodes. The others are queued until the
+	// downloads complete.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentDownloads *int32 `json:"maxConcurrentDownloads,omitempty"`
+}
+
+// DataQuotaUsage describes the data pulled through kuda in the namespace.
+type DataQuotaUsage struct {
+	// Number of the DataSets in the namespace.
+	DataSets int `json:"dataSets"`
+	// Total declared size of the data items of the DataSets.
+	Size resource.Quantity `json:"size"`
+	// Total number of the data items of the DataSets.
+	Items int `json:"items"`
+	// Number of the Data downloading in the namespace, or queued for the download leases.
+	Downloads int `json:"downloads"`
+}
+
+// DataQuotaStatus defines the observed usage of the DataQuota.
+type DataQuotaStatus struct {
+	// Used is the current usage of the namespace.
+	Used DataQuotaUsage `json:"used"`
+	// DataSets exceeding the limits of the quota, their data is not delivered until they fit
+	// in the quota. The DataSets created earlier take the quota first.
+	ExceededDataSets []string `json:"exceededDataSets,omitempty"`
+	// The generation observed by the DataQuota controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+genclient
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=dataquotas
+//+kubebuilder:printcolumn:name="Size",type=string,JSONPath=`.status.used.size`
+//+kubebuilder:printcolumn:name="MaxSize",type=string,JSONPath=`.spec.maxSize`
+//+kubebuilder:printcolumn:name="Items",type=integer,JSONPath=`.status.used.items`
+//+kubebuilder:printcolumn:name="Downloads",type=integer,JSONPath=`.status.used.downloads`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// DataQuota is the Schema for the dataquotas API, it limits the data pulled through kuda in
+// its namespace. All the DataQuotas of a namespace are enforced.
+type DataQuota struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Specification of the limits of the namespace.
+	Spec DataQuotaSpec `json:"spec,omitempty"`
+
+	// Most recently observed usage of the namespace.
+	Status DataQuotaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DataQuotaList contains a list of DataQuota
+type DataQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DataQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DataQuota{}, &DataQuotaList{})
+}
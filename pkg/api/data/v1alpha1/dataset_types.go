@@ -18,7 +18,9 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -27,10 +29,122 @@ import (
 type DataPhase string
 
 const (
+	// DataQueued means the data item waits for a download lease from the controller.
+	DataQueued      DataPhase = "queued"
 	DataWaiting     DataPhase = "waiting"
 	DataDownloading DataPhase = "downloading"
 	DataSuccess     DataPhase = "success"
 	DataFailed      DataPhase = "failed"
+	// DataStaged means the data item is downloaded but not activated yet, it waits for the
+	// DataSet to activate its generation on all the pods.
+	DataStaged DataPhase = "staged"
+)
+
+// MissingInjectionPolicy describes how to deal with the pods which match the workload
+// selector but run without the kuda runtime container.
+type MissingInjectionPolicy string
+
+const (
+	// MissingInjectionIgnore only reports the pods missed injection.
+	MissingInjectionIgnore MissingInjectionPolicy = "Ignore"
+	// MissingInjectionRestart triggers a rolling restart of the workload owning the pods missed injection.
+	MissingInjectionRestart MissingInjectionPolicy = "Restart"
+)
+
+// DataFormat is the archive format of a data item, the data is downloaded as is if empty.
+type DataFormat string
+
+const (
+	// DataFormatTar is an uncompressed tar archive.
+	DataFormatTar DataFormat = "tar"
+	// DataFormatTarGz is a tar archive compressed with gzip.
+	DataFormatTarGz DataFormat = "tar.gz"
+	// DataFormatTarZst is a tar archive compressed with zstd.
+	DataFormatTarZst DataFormat = "tar.zst"
+	// DataFormatZip is a zip archive.
+	DataFormatZip DataFormat = "zip"
+)
+
+// SyncMode describes how the files removed from the remote directory are handled.
+type SyncMode string
+
+const (
+	// SyncModeCopy copies the files into the local path, and keeps the files removed remotely.
+	SyncModeCopy SyncMode = "Copy"
+	// SyncModeMirror makes the local path the same as the remote directory, the files not
+	// synced are removed, including the excluded ones.
+	SyncModeMirror SyncMode = "Mirror"
+)
+
+// DeltaCompare describes how the files of two versions are compared.
+type DeltaCompare string
+
+const (
+	// DeltaCompareSizeModTime compares the sizes and the modification times of the files.
+	DeltaCompareSizeModTime DeltaCompare = "SizeModTime"
+	// DeltaCompareChecksum compares the checksums of the files listed in the manifests of
+	// the versions.
+	DeltaCompareChecksum DeltaCompare = "Checksum"
+)
+
+// VersionOrder describes how the discovered versions are ordered.
+type VersionOrder string
+
+const (
+	// VersionOrderLexical orders the versions as strings, e.g. timestamps of a fixed width.
+	VersionOrderLexical VersionOrder = "Lexical"
+	// VersionOrderNumeric orders the versions as numbers.
+	VersionOrderNumeric VersionOrder = "Numeric"
+	// VersionOrderSemVer orders the versions as semantic versions, e.g. v1.2.0.
+	VersionOrderSemVer VersionOrder = "SemVer"
+)
+
+// AffinityMode describes how the pods are scheduled relative to the affinity target.
+type AffinityMode string
+
+const (
+	// AffinityModeAffinity schedules the pods close to the target.
+	AffinityModeAffinity AffinityMode = "Affinity"
+	// AffinityModeAntiAffinity spreads the pods away from each other, only valid for the Pods target.
+	AffinityModeAntiAffinity AffinityMode = "AntiAffinity"
+	// AffinityModeNone disables the affinity for the dataset.
+	AffinityModeNone AffinityMode = "None"
+)
+
+// AffinityTarget describes what the pods are scheduled relative to.
+type AffinityTarget string
+
+const (
+	// AffinityTargetPods targets the peer pods matching the workload selector.
+	AffinityTargetPods AffinityTarget = "Pods"
+	// AffinityTargetNodes targets the nodes caching all the data items, which are read from
+	// the NodeData resources.
+	AffinityTargetNodes AffinityTarget = "Nodes"
+)
+
+// PrefetchPhase describes the progress of prefetching the data onto a node.
+type PrefetchPhase string
+
+const (
+	// PrefetchPending means the data resource of the node is not at the current revision yet.
+	PrefetchPending PrefetchPhase = "Pending"
+	// PrefetchDownloading means the data items are being downloaded onto the node.
+	PrefetchDownloading PrefetchPhase = "Downloading"
+	// PrefetchSucceeded means all the data items are downloaded onto the node.
+	PrefetchSucceeded PrefetchPhase = "Succeeded"
+	// PrefetchFailed means some data items failed to download onto the node.
+	PrefetchFailed PrefetchPhase = "Failed"
+)
+
+const (
+	// DataSetInjected indicates whether all the pods matching the workload selector are injected.
+	DataSetInjected = "Injected"
+	// DataSetWithinQuota indicates whether the data of the DataSet is delivered within the
+	// DataQuotas of the namespace, it's only set if there is any DataQuota.
+	DataSetWithinQuota = "WithinQuota"
+	// DataSetProgressing indicates whether the updates of the DataSet are rolled out or held
+	// until the update window opens, it's only set if there is any update window.
+	DataSetProgressing = "Progressing"
 )
 
 // DataTemplateSpec describes the fields a data resource should have when created from a template.
@@ -59,6 +173,123 @@ type DataItem struct {
 	DataSourceType string `json:"dataSourceType"`
 	// Actions should be taken for the data.
 	Lifecycle *Lifecycle `json:"lifecycle,omitempty"`
+	// Size is the expected size of the data, e.g. 10Gi. The ephemeral storage of the pods
+	// is requested by the total size of the data items.
+	//+optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// Format of the archive at the remote path, one of tar, tar.gz, tar.zst and zip. The kuda
+	// runtime extracts the archive into the local path before the data item succeeds, and
+	// the archive itself is not kept. Defaults to the data as is.
+	//+kubebuilder:validation:Enum=tar;tar.gz;tar.zst;zip
+	//+optional
+	Format DataFormat `json:"format,omitempty"`
+	// Sync describes how the kuda runtime syncs the directory at the remote path into the
+	// local path.
+	//+optional
+	Sync *SyncPolicy `json:"sync,omitempty"`
+	// Delta asks the kuda runtime to fetch only the files changed since the previous local
+	// version into the directory of the new version, and to reuse the unchanged files from
+	// the previous one. It's not supported for the archives.
+	//+optional
+	Delta *DeltaPolicy `json:"delta,omitempty"`
+	// Versioning asks the kuda runtime to download each version of the data item into its
+	// own directory under the data path prefix, and to switch the local path to it atomically
+	// once the data item succeeded, the local path is a symlink then.
+	//+optional
+	Versioning *VersioningPolicy `json:"versioning,omitempty"`
+	// VersionPolicy discovers the latest version of the data item from the remote store, the
+	// version and the remote path are then updated by the controller.
+	//+optional
+	VersionPolicy *VersionPolicy `json:"versionPolicy,omitempty"`
+	// Schedule re-syncs the data item from the remote path periodically in the cron format,
+	// e.g. "0 2 * * *", even if its version is unchanged. The re-syncs are rolled out as the
+	// template changes, within the update windows of the DataSet.
+	//+optional
+	Schedule string `json:"schedule,omitempty"`
+	// P2P distributes the data item between the pods, so that only a few seeders download
+	// it from the data source and the others fetch the pieces from the peers holding it.
+	//+optional
+	P2P *P2PPolicy `json:"p2p,omitempty"`
+	// Checksum identifies the content of the version, e.g. sha256:<hex> of the archive or of
+	// the checksum manifest. The data items of the same checksum share the content in the
+	// host cache when deduplicated, whatever their data sources and remote paths are.
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]+:[0-9a-f]+$`
+	//+optional
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// VersionPolicy describes how the versions of a data item are discovered. The versions are
+// the sub directories of the parent path, and the remote path of the version is
+// <parentPath>/<version>.
+type VersionPolicy struct {
+	// ParentPath is the remote directory of the versions, e.g. /models/ranker.
+	ParentPath string `json:"parentPath"`
+	// Order of the versions, one of Lexical, Numeric and SemVer, the greatest one is the
+	// latest. The names not numbers or semantic versions are skipped in the Numeric and
+	// SemVer orders. Defaults to Lexical.
+	//+kubebuilder:validation:Enum=Lexical;Numeric;SemVer
+	//+optional
+	Order VersionOrder `json:"order,omitempty"`
+	// MarkerFile is the file which must exist in the version directory before the version
+	// is chosen, e.g. _SUCCESS.
+	//+optional
+	MarkerFile string `json:"markerFile,omitempty"`
+	// Interval of polling the remote store. Defaults to 5m.
+	//+optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// DeltaPolicy describes how the delta between the versions of a directory is computed.
+type DeltaPolicy struct {
+	// Compare describes how the files are compared, one of SizeModTime and Checksum.
+	// Defaults to SizeModTime.
+	//+kubebuilder:validation:Enum=SizeModTime;Checksum
+	//+optional
+	Compare DeltaCompare `json:"compare,omitempty"`
+	// Manifest is the path of the checksum manifest relative to the remote path, in the
+	// format of the output of sha256sum, e.g. SHA256SUMS. It's required by the Checksum
+	// comparison, the files not listed are always fetched.
+	//+optional
+	Manifest string `json:"manifest,omitempty"`
+}
+
+// VersioningPolicy describes how the versions of a data item are kept locally.
+type VersioningPolicy struct {
+	// KeepPrevious is the number of the previous versions kept locally besides the current
+	// one, rolling back to them switches the local path without downloading. Defaults to 1.
+	//+kubebuilder:validation:Minimum=0
+	//+optional
+	KeepPrevious *int32 `json:"keepPrevious,omitempty"`
+}
+
+// P2PPolicy describes how a data item is distributed between the peers.
+type P2PPolicy struct {
+	// Seeders is the max number of the pods downloading the data item from the data source
+	// while no peer holds it. Defaults to 3.
+	//+kubebuilder:validation:Minimum=1
+	//+optional
+	Seeders *int32 `json:"seeders,omitempty"`
+	// PieceSize is the size of the pieces fetched from the peers, each of which is verified
+	// by its checksum. Defaults to 4Mi.
+	//+optional
+	PieceSize *resource.Quantity `json:"pieceSize,omitempty"`
+}
+
+// SyncPolicy describes how the files of a directory are synced into the local path.
+type SyncPolicy struct {
+	// Mode of the sync, one of Copy and Mirror. Defaults to Copy.
+	//+kubebuilder:validation:Enum=Copy;Mirror
+	//+optional
+	Mode SyncMode `json:"mode,omitempty"`
+	// Include are the glob patterns of the files synced, all the files are synced if empty.
+	// A pattern without a slash matches the file names, e.g. "*.parquet", otherwise it
+	// matches the paths relative to the remote path, e.g. "part-*/*.parquet".
+	//+optional
+	Include []string `json:"include,omitempty"`
+	// Exclude are the glob patterns of the files not synced, e.g. "_SUCCESS" and "*.crc",
+	// which take precedence over the include patterns.
+	//+optional
+	Exclude []string `json:"exclude,omitempty"`
 }
 
 // Lifecycle describes actions that the kuda runtime should take in response to data lifecycle events.
@@ -91,6 +322,10 @@ type DataSources struct {
 type HdfsDataSource struct {
 	Addresses []string `json:"addresses"`
 	UserName  string   `json:"userName"`
+	// HTTPAddresses are the WebHDFS addresses of the name nodes, e.g. namenode:9870, which
+	// are required to discover the versions of the data items.
+	//+optional
+	HTTPAddresses []string `json:"httpAddresses,omitempty"`
 }
 
 // AlluxioDataSource defines the information of the alluxio data source.
@@ -98,6 +333,58 @@ type AlluxioDataSource struct {
 	Host    string `json:"host"`
 	Port    int    `json:"port"`
 	Timeout int    `json:"timeout,omitempty"`
+	// ProxyPort is the port of the REST API of the Alluxio proxy on the host, which is used to
+	// discover the versions of the data items. Defaults to 39999.
+	//+optional
+	ProxyPort int `json:"proxyPort,omitempty"`
+}
+
+// AffinityPolicy describes the affinity injected into the pods of the DataSet.
+type AffinityPolicy struct {
+	// Mode of the affinity, one of Affinity, AntiAffinity and None. Defaults to Affinity.
+	//+kubebuilder:validation:Enum=Affinity;AntiAffinity;None
+	//+optional
+	Mode AffinityMode `json:"mode,omitempty"`
+	// Target of the affinity, one of Pods and Nodes. Defaults to Pods.
+	//+kubebuilder:validation:Enum=Pods;Nodes
+	//+optional
+	Target AffinityTarget `json:"target,omitempty"`
+	// TopologyKey is the node label key of the topology domain, e.g. topology.kubernetes.io/zone.
+	// Defaults to kubernetes.io/hostname.
+	//+optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// Required indicates the affinity must be met for scheduling, otherwise it's preferred.
+	//+optional
+	Required bool `json:"required,omitempty"`
+	// Weight of the preferred affinity in the range 1-100. Defaults to 1.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=100
+	//+optional
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// PrefetchSpec describes the nodes whose host cache is populated with the data ahead of
+// the pods landing on them.
+type PrefetchSpec struct {
+	// NodeSelector selects the nodes to prefetch the data onto by labels. An empty selector
+	// selects all the nodes.
+	//+optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// DataVolumeSource describes the volume the data is delivered into for the app containers.
+// At most one of the sources can be set, an emptyDir on the disk of the node is used if none.
+type DataVolumeSource struct {
+	// EmptyDir allows to back the data by memory, and to limit its size.
+	//+optional
+	EmptyDir *v1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+	// Ephemeral provisions a volume for each pod by the claim template, e.g. on the local
+	// NVMe storage classes.
+	//+optional
+	Ephemeral *v1.EphemeralVolumeSource `json:"ephemeral,omitempty"`
+	// PersistentVolumeClaim uses an existing claim in the namespace of the DataSet.
+	//+optional
+	PersistentVolumeClaim *v1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
 }
 
 // DataSetSpec defines the desired state of DataSet
@@ -111,6 +398,90 @@ type DataSetSpec struct {
 	// Label selector for workloads. The DataSet will be applied to all workloads
 	// matching the selector.
 	WorkloadSelector map[string]string `json:"workloadSelector"`
+
+	// MissingInjectionPolicy describes how to deal with the pods missed the kuda runtime injection.
+	// Defaults to Ignore.
+	//+kubebuilder:validation:Enum=Ignore;Restart
+	//+optional
+	MissingInjectionPolicy MissingInjectionPolicy `json:"missingInjectionPolicy,omitempty"`
+
+	// Indicates that the rollout of the DataSet is paused. The data resources of the
+	// existing pods are not updated while paused, new pods still use the latest template.
+	//+optional
+	Paused bool `json:"paused,omitempty"`
+
+	// The number of old revisions of the template to retain to allow rollback.
+	// Defaults to 10.
+	//+optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// Affinity overrides the affinity injected into the pods, which is enabled by the
+	// webhook config by default.
+	//+optional
+	Affinity *AffinityPolicy `json:"affinity,omitempty"`
+
+	// Prefetch downloads the data of the template onto the selected nodes ahead of time, so
+	// that the pods scheduled onto them find the data already cached.
+	//+optional
+	Prefetch *PrefetchSpec `json:"prefetch,omitempty"`
+
+	// Volume describes the volume the data is delivered into for the app containers, which
+	// is not used if the data is served by the kuda CSI driver.
+	//+optional
+	Volume *DataVolumeSource `json:"volume,omitempty"`
+
+	// Download describes how the downloads of the DataSet are scheduled among the others.
+	//+optional
+	Download *DownloadPolicy `json:"download,omitempty"`
+
+	// UpdateWindows are the time windows the updates of the data resources of the existing
+	// pods are rolled out in, including the template changes and the scheduled re-syncs.
+	// The updates are held outside the windows, new pods still use the latest template.
+	// The updates are rolled out anytime if there is no window.
+	//+optional
+	UpdateWindows []UpdateWindow `json:"updateWindows,omitempty"`
+
+	// Activation activates the updates on all the pods at the same time. The pods stage the
+	// new data first, which is activated everywhere once enough pods have staged it, so
+	// that the pods never serve mixed versions for long. The updates are activated on each
+	// pod independently if not set.
+	//+optional
+	Activation *ActivationPolicy `json:"activation,omitempty"`
+}
+
+// ActivationPolicy describes when the staged updates are activated.
+type ActivationPolicy struct {
+	// Quorum is the number or the percentage of the replicas which must stage the update
+	// before it's activated, e.g. 90%. Defaults to 100%.
+	//+optional
+	Quorum *intstr.IntOrString `json:"quorum,omitempty"`
+}
+
+// UpdateWindow describes a recurring time window the updates are rolled out in.
+type UpdateWindow struct {
+	// Schedule is when the window opens in the cron format, e.g. "0 1 * * *". The time zone
+	// can be set by the prefix "CRON_TZ=", e.g. "CRON_TZ=Asia/Shanghai 0 1 * * *", defaults
+	// to the time zone of the manager.
+	Schedule string `json:"schedule"`
+	// Duration is how long the window lasts once it opens.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// DownloadPolicy describes how the downloads of the DataSet are scheduled. The data
+// resources wait in the queued phase until the controller hands out download leases to
+// them, within the limits of the DataSet, the data sources and the nodes.
+type DownloadPolicy struct {
+	// MaxConcurrent is the max number of the data resources of the DataSet downloading at
+	// the same time, including the prefetch onto nodes, 0 means unlimited. Defaults to the
+	// limit of the manager.
+	//+kubebuilder:validation:Minimum=0
+	//+optional
+	MaxConcurrent *int32 `json:"maxConcurrent,omitempty"`
+	// PriorityClassName is the name of the PriorityClass of the downloads, the leases are
+	// handed out to the DataSets of higher priority first. Defaults to the global default
+	// PriorityClass, or zero if there is none.
+	//+optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
 }
 
 // DataSetStatus defines the observed state of DataSet
@@ -122,6 +493,89 @@ type DataSetStatus struct {
 	Replicas        int    `json:"replicas"`
 	SuccessReplicas int    `json:"success"`
 	Ready           string `json:"ready"`
+
+	// Number of pods matching the workload selector but missed the kuda runtime injection.
+	UninjectedReplicas int `json:"uninjected,omitempty"`
+	// Number of replicas whose data resource is at the current revision.
+	UpdatedReplicas int `json:"updated,omitempty"`
+	// The revision of the current template, which is the name of its ControllerRevision.
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// The generation observed by the DataSet controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Represents the latest available observations of the DataSet's current state.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Distribution of the replicas across nodes.
+	Nodes []NodeDistribution `json:"nodes,omitempty"`
+	// Progress of prefetching the data onto each selected node.
+	Prefetch []PrefetchNodeStatus `json:"prefetch,omitempty"`
+	// Versions discovered for the data items with the version policy.
+	DiscoveredVersions []DiscoveredVersion `json:"discoveredVersions,omitempty"`
+	// Progress of the staged activation, it's only set if the activation policy is set.
+	Activation *ActivationStatus `json:"activation,omitempty"`
+	// Restarts of the workloads owning the pods missed injection, it's only set if the
+	// MissingInjectionPolicy is Restart.
+	Restarts []WorkloadRestart `json:"restarts,omitempty"`
+}
+
+// WorkloadRestart describes the restarts of a workload for the pods missed injection, which
+// is removed once all its pods are injected.
+type WorkloadRestart struct {
+	// Kind of the workload, one of Deployment, StatefulSet and DaemonSet.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Attempts is the number of the restarts since the pods of the workload missed injection.
+	Attempts int `json:"attempts"`
+	// LastRestartTime is the last time the workload was restarted.
+	LastRestartTime metav1.Time `json:"lastRestartTime"`
+}
+
+// ActivationStatus describes the progress of the staged activation.
+type ActivationStatus struct {
+	// Generation is the latest activation generation, which is bumped for each revision.
+	Generation int64 `json:"generation"`
+	// Revision is the revision of the latest generation.
+	Revision string `json:"revision"`
+	// ActiveGeneration is the generation activated on all the pods.
+	ActiveGeneration int64 `json:"activeGeneration"`
+	// Staged is the number of the replicas which have staged the latest generation.
+	Staged int `json:"staged"`
+	// LastActivationTime is the last time a generation was activated.
+	LastActivationTime *metav1.Time `json:"lastActivationTime,omitempty"`
+}
+
+// DiscoveredVersion describes the latest version discovered for a data item.
+type DiscoveredVersion struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// The latest version chosen, empty if none is found.
+	Version string `json:"version,omitempty"`
+	// The parent path polled, the version is discovered again once it changes.
+	ParentPath string `json:"parentPath"`
+	// The last time the remote store was polled.
+	LastPollTime metav1.Time `json:"lastPollTime"`
+	// The error of the last poll, if any.
+	Message string `json:"message,omitempty"`
+	// Overridden is true if the version in the template is changed from the one discovered,
+	// e.g. by rollout undo or GitOps. The version in the template is kept until it's set to
+	// the discovered version again.
+	Overridden bool `json:"overridden,omitempty"`
+}
+
+// NodeDistribution describes the replicas of the DataSet on a node.
+type NodeDistribution struct {
+	NodeName        string `json:"nodeName"`
+	Replicas        int    `json:"replicas"`
+	SuccessReplicas int    `json:"success"`
+}
+
+// PrefetchNodeStatus describes the progress of prefetching the data onto a node.
+type PrefetchNodeStatus struct {
+	NodeName string        `json:"nodeName"`
+	Phase    PrefetchPhase `json:"phase"`
+	// The revision of the template being prefetched.
+	Revision string `json:"revision,omitempty"`
+	// Number of the data items downloaded onto the node.
+	Success int `json:"success"`
 }
 
 //+genclient
@@ -129,6 +583,7 @@ type DataSetStatus struct {
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="DataItems",type=integer,JSONPath=`.status.dataItems`
 //+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.ready`
+//+kubebuilder:printcolumn:name="Updated",type=integer,JSONPath=`.status.updated`
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // DataSet is the Schema for the datasets API
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeDataStatus defines the data cached in the host path of a node.
+type NodeDataStatus struct {
+	// List of data items cached on the node.
+	Items []CachedDataItem `json:"items,omitempty"`
+	// Number of the cached data items.
+	ItemsNum int `json:"itemsNum"`
+	// Total bytes of the cached data items.
+	Size int64 `json:"size"`
+}
+
+// CachedDataItem describes a version of data item cached on the node.
+type CachedDataItem struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   string `json:"version"`
+	// Tenant is the sub directory of the host cache holding the data item by the isolation,
+	// e.g. <namespace>/<dataset>, it's empty if the host cache is shared. Only the pods of
+	// the same tenant are served by the data item.
+	Tenant string `json:"tenant,omitempty"`
+	// Size is the bytes of the data item reported by the kuda runtime.
+	Size int64 `json:"size,omitempty"`
+	// Number of the pods on the node using the data item.
+	Pods int `json:"pods,omitempty"`
+	// LastUsed is the last time a pod on the node is observed using the data item.
+	LastUsed metav1.Time `json:"lastUsed"`
+}
+
+//+genclient
+//+genclient:nonNamespaced
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=nodedatas,scope=Cluster
+//+kubebuilder:printcolumn:name="Items",type=integer,JSONPath=`.status.itemsNum`
+//+kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.status.size`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NodeData is the Schema for the nodedata API, it's named after the node and records the
+// data cached in the host path of the node.
+type NodeData struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Most recently observed data cached on the node.
+	Status NodeDataStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NodeDataList contains a list of NodeData
+type NodeDataList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeData `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeData{}, &NodeDataList{})
+}
@@ -21,10 +21,66 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActivationPolicy) DeepCopyInto(out *ActivationPolicy) {
+	*out = *in
+	if in.Quorum != nil {
+		in, out := &in.Quorum, &out.Quorum
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActivationPolicy.
+func (in *ActivationPolicy) DeepCopy() *ActivationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ActivationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActivationStatus) DeepCopyInto(out *ActivationStatus) {
+	*out = *in
+	if in.LastActivationTime != nil {
+		in, out := &in.LastActivationTime, &out.LastActivationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActivationStatus.
+func (in *ActivationStatus) DeepCopy() *ActivationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ActivationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AffinityPolicy) DeepCopyInto(out *AffinityPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AffinityPolicy.
+func (in *AffinityPolicy) DeepCopy() *AffinityPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AffinityPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlluxioDataSource) DeepCopyInto(out *AlluxioDataSource) {
 	*out = *in
@@ -40,6 +96,37 @@ func (in *AlluxioDataSource) DeepCopy() *AlluxioDataSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CachedDataItem) DeepCopyInto(out *CachedDataItem) {
+	*out = *in
+	in.LastUsed.DeepCopyInto(&out.LastUsed)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CachedDataItem.
+func (in *CachedDataItem) DeepCopy() *CachedDataItem {
+	if in == nil {
+		return nil
+	}
+	out := new(CachedDataItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContentStatus) DeepCopyInto(out *ContentStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentStatus.
+func (in *ContentStatus) DeepCopy() *ContentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ContentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Data) DeepCopyInto(out *Data) {
 	*out = *in
@@ -67,6 +154,21 @@ func (in *Data) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataActivation) DeepCopyInto(out *DataActivation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataActivation.
+func (in *DataActivation) DeepCopy() *DataActivation {
+	if in == nil {
+		return nil
+	}
+	out := new(DataActivation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataItem) DeepCopyInto(out *DataItem) {
 	*out = *in
@@ -75,6 +177,36 @@ func (in *DataItem) DeepCopyInto(out *DataItem) {
 		*out = new(Lifecycle)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Sync != nil {
+		in, out := &in.Sync, &out.Sync
+		*out = new(SyncPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delta != nil {
+		in, out := &in.Delta, &out.Delta
+		*out = new(DeltaPolicy)
+		**out = **in
+	}
+	if in.Versioning != nil {
+		in, out := &in.Versioning, &out.Versioning
+		*out = new(VersioningPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VersionPolicy != nil {
+		in, out := &in.VersionPolicy, &out.VersionPolicy
+		*out = new(VersionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.P2P != nil {
+		in, out := &in.P2P, &out.P2P
+		*out = new(P2PPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataItem.
@@ -87,10 +219,55 @@ func (in *DataItem) DeepCopy() *DataItem {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataItemRefresh) DeepCopyInto(out *DataItemRefresh) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataItemRefresh.
+func (in *DataItemRefresh) DeepCopy() *DataItemRefresh {
+	if in == nil {
+		return nil
+	}
+	out := new(DataItemRefresh)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataItemStatus) DeepCopyInto(out *DataItemStatus) {
 	*out = *in
 	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = new(FileChanges)
+		**out = **in
+	}
+	if in.Delta != nil {
+		in, out := &in.Delta, &out.Delta
+		*out = new(DeltaStatus)
+		**out = **in
+	}
+	if in.LocalVersions != nil {
+		in, out := &in.LocalVersions, &out.LocalVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.P2P != nil {
+		in, out := &in.P2P, &out.P2P
+		*out = new(P2PStatus)
+		**out = **in
+	}
+	if in.Content != nil {
+		in, out := &in.Content, &out.Content
+		*out = new(ContentStatus)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataItemStatus.
@@ -156,13 +333,139 @@ func (in *DataList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataQuota) DeepCopyInto(out *DataQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataQuota.
+func (in *DataQuota) DeepCopy() *DataQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(DataQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DataQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataQuotaList) DeepCopyInto(out *DataQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DataQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataQuotaList.
+func (in *DataQuotaList) DeepCopy() *DataQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(DataQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DataQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataQuotaSpec) DeepCopyInto(out *DataQuotaSpec) {
+	*out = *in
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxItems != nil {
+		in, out := &in.MaxItems, &out.MaxItems
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxConcurrentDownloads != nil {
+		in, out := &in.MaxConcurrentDownloads, &out.MaxConcurrentDownloads
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataQuotaSpec.
+func (in *DataQuotaSpec) DeepCopy() *DataQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DataQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataQuotaStatus) DeepCopyInto(out *DataQuotaStatus) {
+	*out = *in
+	in.Used.DeepCopyInto(&out.Used)
+	if in.ExceededDataSets != nil {
+		in, out := &in.ExceededDataSets, &out.ExceededDataSets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataQuotaStatus.
+func (in *DataQuotaStatus) DeepCopy() *DataQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DataQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataQuotaUsage) DeepCopyInto(out *DataQuotaUsage) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataQuotaUsage.
+func (in *DataQuotaUsage) DeepCopy() *DataQuotaUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(DataQuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataSet) DeepCopyInto(out *DataSet) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSet.
@@ -226,6 +529,41 @@ func (in *DataSetSpec) DeepCopyInto(out *DataSetSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(AffinityPolicy)
+		**out = **in
+	}
+	if in.Prefetch != nil {
+		in, out := &in.Prefetch, &out.Prefetch
+		*out = new(PrefetchSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volume != nil {
+		in, out := &in.Volume, &out.Volume
+		*out = new(DataVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Download != nil {
+		in, out := &in.Download, &out.Download
+		*out = new(DownloadPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateWindows != nil {
+		in, out := &in.UpdateWindows, &out.UpdateWindows
+		*out = make([]UpdateWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.Activation != nil {
+		in, out := &in.Activation, &out.Activation
+		*out = new(ActivationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSetSpec.
@@ -241,6 +579,42 @@ func (in *DataSetSpec) DeepCopy() *DataSetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataSetStatus) DeepCopyInto(out *DataSetStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeDistribution, len(*in))
+		copy(*out, *in)
+	}
+	if in.Prefetch != nil {
+		in, out := &in.Prefetch, &out.Prefetch
+		*out = make([]PrefetchNodeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.DiscoveredVersions != nil {
+		in, out := &in.DiscoveredVersions, &out.DiscoveredVersions
+		*out = make([]DiscoveredVersion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Activation != nil {
+		in, out := &in.Activation, &out.Activation
+		*out = new(ActivationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Restarts != nil {
+		in, out := &in.Restarts, &out.Restarts
+		*out = make([]WorkloadRestart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSetStatus.
@@ -298,6 +672,23 @@ func (in *DataSpec) DeepCopyInto(out *DataSpec) {
 		*out = new(DataSources)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.BandwidthLimit != nil {
+		in, out := &in.BandwidthLimit, &out.BandwidthLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Refreshes != nil {
+		in, out := &in.Refreshes, &out.Refreshes
+		*out = make([]DataItemRefresh, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Activation != nil {
+		in, out := &in.Activation, &out.Activation
+		*out = new(DataActivation)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSpec.
@@ -364,6 +755,117 @@ func (in *DataTemplateSpec) DeepCopy() *DataTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeSource) DeepCopyInto(out *DataVolumeSource) {
+	*out = *in
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		*out = new(corev1.EmptyDirVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ephemeral != nil {
+		in, out := &in.Ephemeral, &out.Ephemeral
+		*out = new(corev1.EphemeralVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(corev1.PersistentVolumeClaimVolumeSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeSource.
+func (in *DataVolumeSource) DeepCopy() *DataVolumeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeltaPolicy) DeepCopyInto(out *DeltaPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeltaPolicy.
+func (in *DeltaPolicy) DeepCopy() *DeltaPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DeltaPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeltaStatus) DeepCopyInto(out *DeltaStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeltaStatus.
+func (in *DeltaStatus) DeepCopy() *DeltaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeltaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveredVersion) DeepCopyInto(out *DiscoveredVersion) {
+	*out = *in
+	in.LastPollTime.DeepCopyInto(&out.LastPollTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveredVersion.
+func (in *DiscoveredVersion) DeepCopy() *DiscoveredVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveredVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownloadPolicy) DeepCopyInto(out *DownloadPolicy) {
+	*out = *in
+	if in.MaxConcurrent != nil {
+		in, out := &in.MaxConcurrent, &out.MaxConcurrent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownloadPolicy.
+func (in *DownloadPolicy) DeepCopy() *DownloadPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DownloadPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileChanges) DeepCopyInto(out *FileChanges) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileChanges.
+func (in *FileChanges) DeepCopy() *FileChanges {
+	if in == nil {
+		return nil
+	}
+	out := new(FileChanges)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HdfsDataSource) DeepCopyInto(out *HdfsDataSource) {
 	*out = *in
@@ -372,6 +874,11 @@ func (in *HdfsDataSource) DeepCopyInto(out *HdfsDataSource) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.HTTPAddresses != nil {
+		in, out := &in.HTTPAddresses, &out.HTTPAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsDataSource.
@@ -414,12 +921,12 @@ func (in *LifecycleHandler) DeepCopyInto(out *LifecycleHandler) {
 	*out = *in
 	if in.Exec != nil {
 		in, out := &in.Exec, &out.Exec
-		*out = new(v1.ExecAction)
+		*out = new(corev1.ExecAction)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.HTTPGet != nil {
 		in, out := &in.HTTPGet, &out.HTTPGet
-		*out = new(v1.HTTPGetAction)
+		*out = new(corev1.HTTPGetAction)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -434,6 +941,203 @@ func (in *LifecycleHandler) DeepCopy() *LifecycleHandler {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeData) DeepCopyInto(out *NodeData) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeData.
+func (in *NodeData) DeepCopy() *NodeData {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeData)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeData) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDataList) DeepCopyInto(out *NodeDataList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeData, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDataList.
+func (in *NodeDataList) DeepCopy() *NodeDataList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDataList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeDataList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDataStatus) DeepCopyInto(out *NodeDataStatus) {
+	*out = *in
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CachedDataItem, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDataStatus.
+func (in *NodeDataStatus) DeepCopy() *NodeDataStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDataStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDistribution) DeepCopyInto(out *NodeDistribution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDistribution.
+func (in *NodeDistribution) DeepCopy() *NodeDistribution {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDistribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *P2PPolicy) DeepCopyInto(out *P2PPolicy) {
+	*out = *in
+	if in.Seeders != nil {
+		in, out := &in.Seeders, &out.Seeders
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PieceSize != nil {
+		in, out := &in.PieceSize, &out.PieceSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new P2PPolicy.
+func (in *P2PPolicy) DeepCopy() *P2PPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(P2PPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *P2PStatus) DeepCopyInto(out *P2PStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new P2PStatus.
+func (in *P2PStatus) DeepCopy() *P2PStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(P2PStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefetchNodeStatus) DeepCopyInto(out *PrefetchNodeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefetchNodeStatus.
+func (in *PrefetchNodeStatus) DeepCopy() *PrefetchNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefetchNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefetchSpec) DeepCopyInto(out *PrefetchSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefetchSpec.
+func (in *PrefetchSpec) DeepCopy() *PrefetchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefetchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncPolicy) DeepCopyInto(out *SyncPolicy) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncPolicy.
+func (in *SyncPolicy) DeepCopy() *SyncPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
 	*out = *in
@@ -448,3 +1152,75 @@ func (in *UpdateStrategy) DeepCopy() *UpdateStrategy {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateWindow) DeepCopyInto(out *UpdateWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateWindow.
+func (in *UpdateWindow) DeepCopy() *UpdateWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionPolicy) DeepCopyInto(out *VersionPolicy) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionPolicy.
+func (in *VersionPolicy) DeepCopy() *VersionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersioningPolicy) DeepCopyInto(out *VersioningPolicy) {
+	*out = *in
+	if in.KeepPrevious != nil {
+		in, out := &in.KeepPrevious, &out.KeepPrevious
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersioningPolicy.
+func (in *VersioningPolicy) DeepCopy() *VersioningPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(VersioningPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadRestart) DeepCopyInto(out *WorkloadRestart) {
+	*out = *in
+	in.LastRestartTime.DeepCopyInto(&out.LastRestartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadRestart.
+func (in *WorkloadRestart) DeepCopy() *WorkloadRestart {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadRestart)
+	in.DeepCopyInto(out)
+	return out
+}
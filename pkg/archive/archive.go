@@ -0,0 +1,298 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package archive extracts the downloaded archives of the data items into their local paths.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// Extract extracts the archive file src of the format into the directory dst, and returns
+// the total bytes of the regular files extracted. The entries escaping dst, by absolute
+// paths, ".." or links, are rejected, and so is the archive extracted to more than maxSize
+// bytes if maxSize is positive. Devices, fifos and the owners of the entries are ignored.
+func Extract(src, dst string, format datav1alpha1.DataFormat, maxSize int64) (int64, error) {
+	switch format {
+	case datav1alpha1.DataFormatTar, datav1alpha1.DataFormatTarGz, datav1alpha1.DataFormatTarZst, datav1alpha1.DataFormatZip:
+	default:
+		return 0, fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return 0, err
+	}
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return 0, err
+	}
+	e := &extractor{dst: dst, maxSize: maxSize}
+
+	if format == datav1alpha1.DataFormatZip {
+		r, err := zip.OpenReader(src)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		err = e.extractZip(&r.Reader)
+		return e.size, err
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch format {
+	case datav1alpha1.DataFormatTarGz:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		r = gz
+	case datav1alpha1.DataFormatTarZst:
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	err = e.extractTar(tar.NewReader(r))
+	return e.size, err
+}
+
+// extractor writes the entries of an archive into the directory.
+type extractor struct {
+	dst     string
+	maxSize int64
+	// size is the bytes of the regular files extracted so far.
+	size int64
+}
+
+func (e *extractor) extractTar(r *tar.Reader) error {
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.mkdir(hdr.Name, hdr.FileInfo().Mode())
+		case tar.TypeReg, tar.TypeRegA:
+			err = e.writeFile(hdr.Name, hdr.FileInfo().Mode(), r)
+		case tar.TypeSymlink:
+			err = e.symlink(hdr.Name, hdr.Linkname)
+		case tar.TypeLink:
+			err = e.link(hdr.Name, hdr.Linkname)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *extractor) extractZip(r *zip.Reader) error {
+	for _, f := range r.File {
+		if err := e.extractZipFile(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) extractZipFile(f *zip.File) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return e.mkdir(f.Name, mode)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	switch {
+	case mode&os.ModeSymlink != 0:
+		// The target of a symlink is stored as the content of the entry.
+		linkname, err := ioutil.ReadAll(io.LimitReader(rc, 4096))
+		if err != nil {
+			return err
+		}
+		return e.symlink(f.Name, string(linkname))
+	case mode.IsRegular():
+		return e.writeFile(f.Name, mode, rc)
+	}
+	return nil
+}
+
+// path returns the path of the entry in the directory. The entry must not escape the
+// directory, nor be written through the symlinks extracted before.
+func (e *extractor) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("entry %q escapes the extraction directory", name)
+	}
+
+	path := e.dst
+	for _, elem := range strings.Split(clean, string(filepath.Separator)) {
+		if elem == "." {
+			continue
+		}
+		path = filepath.Join(path, elem)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("entry %q is written through the symlink %s", name, path)
+		}
+	}
+	return filepath.Join(e.dst, clean), nil
+}
+
+func (e *extractor) mkdir(name string, mode os.FileMode) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	// The directory must stay writable to extract the entries in it.
+	return os.MkdirAll(path, mode.Perm()|0700)
+}
+
+func (e *extractor) writeFile(name string, mode os.FileMode, r io.Reader) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// The entry overrides the file of the same name extracted before.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if e.maxSize > 0 {
+		r = io.LimitReader(r, e.maxSize-e.size+1)
+	}
+	n, err := io.Copy(f, r)
+	e.size += n
+	if err != nil {
+		return err
+	}
+	if e.maxSize > 0 && e.size > e.maxSize {
+		return fmt.Errorf("the extracted files exceed the max size of %d bytes", e.maxSize)
+	}
+	return f.Close()
+}
+
+func (e *extractor) symlink(name, linkname string) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	// The ".." following a name may climb out of a symlink rather than the directory it's
+	// cleaned to, so it's only allowed at the beginning of the link.
+	target := filepath.Clean(filepath.Join(filepath.Dir(path), filepath.FromSlash(linkname)))
+	if filepath.IsAbs(filepath.FromSlash(linkname)) || !isWithin(e.dst, target) || hasInnerParentRef(linkname) {
+		return fmt.Errorf("symlink %q to %q escapes the extraction directory", name, linkname)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(linkname, path)
+}
+
+func (e *extractor) link(name, linkname string) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	target, err := e.path(linkname)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(target)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("hard link %q to %q is not a regular file", name, linkname)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Link(target, path)
+}
+
+// isWithin returns true if the path is the directory or in it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// hasInnerParentRef returns true if a ".." follows a name in the slash separated path.
+func hasInnerParentRef(path string) bool {
+	named := false
+	for _, elem := range strings.Split(path, "/") {
+		switch elem {
+		case "", ".":
+		case "..":
+			if named {
+				return true
+			}
+		default:
+			named = true
+		}
+	}
+	return false
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// entry is an entry of the test archives, a symlink if link is set, or a directory if the
+// name ends with a slash.
+type entry struct {
+	name     string
+	content  string
+	link     string
+	hardLink bool
+}
+
+func writeTar(t *testing.T, w io.Writer, entries []entry) {
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.content))}
+		switch {
+		case e.hardLink:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, e.link, 0
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		case e.name[len(e.name)-1] == '/':
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		assert.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+}
+
+func writeZip(t *testing.T, w io.Writer, entries []entry) {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		content := e.content
+		switch {
+		case e.link != "":
+			hdr.SetMode(os.ModeSymlink | 0777)
+			content = e.link
+		case e.name[len(e.name)-1] == '/':
+			hdr.SetMode(os.ModeDir | 0755)
+		default:
+			hdr.SetMode(0644)
+		}
+		fw, err := zw.CreateHeader(hdr)
+		assert.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+}
+
+// writeArchive writes the entries into an archive of the format and returns its path.
+func writeArchive(t *testing.T, dir string, format datav1alpha1.DataFormat, entries []entry) string {
+	buf := &bytes.Buffer{}
+	switch format {
+	case datav1alpha1.DataFormatTar:
+		writeTar(t, buf, entries)
+	case datav1alpha1.DataFormatTarGz:
+		gw := gzip.NewWriter(buf)
+		writeTar(t, gw, entries)
+		assert.NoError(t, gw.Close())
+	case datav1alpha1.DataFormatTarZst:
+		zw, err := zstd.NewWriter(buf)
+		assert.NoError(t, err)
+		writeTar(t, zw, entries)
+		assert.NoError(t, zw.Close())
+	case datav1alpha1.DataFormatZip:
+		writeZip(t, buf, entries)
+	}
+
+	path := filepath.Join(dir, "data."+string(format))
+	assert.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+	return path
+}
+
+func TestExtract(t *testing.T) {
+	entries := []entry{
+		{name: "model/"},
+		{name: "model/weights.bin", content: "weights"},
+		{name: "model/config/vocab.txt", content: "vocab"},
+		{name: "model/latest", link: "weights.bin"},
+		{name: "model/config/weights.bin", link: "../weights.bin"},
+	}
+	for _, format := range []datav1alpha1.DataFormat{
+		datav1alpha1.DataFormatTar,
+		datav1alpha1.DataFormatTarGz,
+		datav1alpha1.DataFormatTarZst,
+		datav1alpha1.DataFormatZip,
+	} {
+		t.Run(string(format), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "kuda-archive")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			dst := filepath.Join(dir, "data")
+			size, err := Extract(writeArchive(t, dir, format, entries), dst, format, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(12), size)
+
+			for path, content := range map[string]string{
+				"model/weights.bin":        "weights",
+				"model/config/vocab.txt":   "vocab",
+				"model/latest":             "weights",
+				"model/config/weights.bin": "weights",
+			} {
+				data, err := ioutil.ReadFile(filepath.Join(dst, path))
+				assert.NoError(t, err)
+				assert.Equal(t, content, string(data))
+			}
+		})
+	}
+}
+
+func TestExtractHardLink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuda-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src := writeArchive(t, dir, datav1alpha1.DataFormatTar, []entry{
+		{name: "weights.bin", content: "weights"},
+		{name: "backup/weights.bin", link: "weights.bin", hardLink: true},
+	})
+	size, err := Extract(src, filepath.Join(dir, "data"), datav1alpha1.DataFormatTar, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), size)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "data", "backup", "weights.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, "weights", string(data))
+}
+
+func TestExtractUnsafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  datav1alpha1.DataFormat
+		entries []entry
+	}{
+		{
+			name:    "parent path",
+			format:  datav1alpha1.DataFormatTar,
+			entries: []entry{{name: "../escaped", content: "x"}},
+		},
+		{
+			name:    "parent path in zip",
+			format:  datav1alpha1.DataFormatZip,
+			entries: []entry{{name: "model/../../escaped", content: "x"}},
+		},
+		{
+			name:    "absolute path",
+			format:  datav1alpha1.DataFormatTar,
+			entries: []entry{{name: "/escaped", content: "x"}},
+		},
+		{
+			name:    "symlink out of the directory",
+			format:  datav1alpha1.DataFormatTar,
+			entries: []entry{{name: "model/link", link: "../../escaped"}},
+		},
+		{
+			name:    "absolute symlink",
+			format:  datav1alpha1.DataFormatZip,
+			entries: []entry{{name: "link", link: "/etc"}},
+		},
+		{
+			name:   "symlink climbing out of a symlink",
+			format: datav1alpha1.DataFormatTar,
+			entries: []entry{
+				{name: "model/current", link: "."},
+				{name: "model/link", link: "current/../.."},
+			},
+		},
+		{
+			name:   "written through a symlink",
+			format: datav1alpha1.DataFormatTar,
+			entries: []entry{
+				{name: "model/", link: "."},
+				{name: "model/escaped", content: "x"},
+			},
+		},
+		{
+			name:    "hard link out of the directory",
+			format:  datav1alpha1.DataFormatTar,
+			entries: []entry{{name: "passwd", link: "../../etc/passwd", hardLink: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "kuda-archive")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			_, err = Extract(writeArchive(t, dir, tt.format, tt.entries), filepath.Join(dir, "data", "model"), tt.format, 0)
+			assert.Error(t, err)
+			_, err = os.Lstat(filepath.Join(dir, "data", "escaped"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestExtractMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuda-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src := writeArchive(t, dir, datav1alpha1.DataFormatTarGz, []entry{
+		{name: "a.bin", content: "12345"},
+		{name: "b.bin", content: "67890"},
+	})
+	_, err = Extract(src, filepath.Join(dir, "data"), datav1alpha1.DataFormatTarGz, 8)
+	assert.EqualError(t, err, "the extracted files exceed the max size of 8 bytes")
+
+	size, err := Extract(src, filepath.Join(dir, "data"), datav1alpha1.DataFormatTarGz, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+}
+
+func TestExtractUnsupportedFormat(t *testing.T) {
+	_, err := Extract("data.rar", os.TempDir(), "rar", 0)
+	assert.EqualError(t, err, `unsupported archive format "rar"`)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/pflag"
+)
+
+const usage = `kuda injects and lints manifests offline, without a cluster.
+
+Usage:
+  kuda inject -f <manifest> --dataset <dataset> --config <config>
+                                Inject the kuda runtime into the workloads matching the DataSets
+  kuda lint <file>...           Validate the DataSets in the files
+
+Flags:
+`
+
+// Options holds the flags shared by the commands.
+type Options struct {
+	In  io.Reader
+	Out io.Writer
+
+	// Filename is the manifest file to inject, "-" means the standard input.
+	Filename string
+	// DataSetFile is the file containing the DataSets to inject.
+	DataSetFile string
+	// ConfigFile is the webhook config file, or the manifest of the webhook ConfigMap.
+	ConfigFile string
+}
+
+// Run parses the arguments and runs the command.
+func Run(args []string, in io.Reader, out io.Writer) error {
+	o := &Options{In: in, Out: out}
+
+	fs := pflag.NewFlagSet("kuda", pflag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprint(out, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVarP(&o.Filename, "filename", "f", "-", "Manifest file to inject, \"-\" means the standard input.")
+	fs.StringVar(&o.DataSetFile, "dataset", "", "File containing the DataSets to inject.")
+	fs.StringVar(&o.ConfigFile, "config", "", "Webhook config file, or the manifest of the webhook ConfigMap.")
+	if err := fs.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+
+	cmd := fs.Args()
+	if len(cmd) == 0 {
+		fs.Usage()
+		return nil
+	}
+
+	switch cmd[0] {
+	case "inject":
+		if len(cmd) == 1 {
+			return o.RunInject()
+		}
+	case "lint":
+		if len(cmd) > 1 {
+			return o.RunLint(cmd[1:])
+		}
+	}
+
+	return fmt.Errorf("unknown command %q, run 'kuda --help' for usage", cmd)
+}
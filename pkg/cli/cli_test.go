@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/yaml"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestRunInject(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := Run([]string{"inject", "-f", "testdata/manifest.yaml",
+		"--dataset", "testdata/dataset.yaml", "--config", "testdata/configmap.yaml"}, nil, out)
+	assert.NoError(t, err)
+
+	docs, err := readDocuments(out)
+	assert.NoError(t, err)
+	assert.Len(t, docs, 3)
+	assert.Equal(t, "Service", docs[1].Kind)
+	assert.Equal(t, "Job", docs[2].Kind)
+	assert.NotContains(t, string(docs[2].Raw), datav1alpha1.KudaRuntimeContainerName)
+
+	deploy := &appsv1.Deployment{}
+	assert.NoError(t, yaml.Unmarshal(docs[0].Raw, deploy))
+	assert.Equal(t, "dataset-nginx", deploy.Spec.Template.Annotations[datav1alpha1.KudaKeyDataSet])
+	assert.Len(t, deploy.Spec.Template.Spec.Containers, 2)
+	assert.Equal(t, "kuda4bigo/kuda-runtime:latest", deploy.Spec.Template.Spec.Containers[1].Image)
+
+	t.Run("skip the injected manifests", func(t *testing.T) {
+		again := &bytes.Buffer{}
+		err := Run([]string{"inject", "--dataset", "testdata/dataset.yaml", "--config", "testdata/configmap.yaml"},
+			strings.NewReader(out.String()), again)
+		assert.NoError(t, err)
+		assert.Equal(t, out.String(), again.String())
+	})
+}
+
+func TestRunLint(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, Run([]string{"lint", "testdata/dataset.yaml"}, nil, out))
+	assert.Contains(t, out.String(), "DataSet default/dataset-nginx is valid")
+
+	out.Reset()
+	err := Run([]string{"lint", "testdata/dataset.yaml", "testdata/invalid_dataset.yaml"}, nil, out)
+	assert.EqualError(t, err, "1 invalid DataSet(s) found")
+	assert.Contains(t, out.String(), `warning: error unmarshaling JSON: while decoding JSON: json: unknown field "versoin"`)
+	assert.Contains(t, out.String(), "spec.template.dataItems[0].version: Required value")
+	assert.Contains(t, out.String(), "spec.template.dataItems[0].localPath: Invalid value")
+	assert.Contains(t, out.String(), `spec.template.dataItems[0].dataSourceType: Unsupported value: "s3"`)
+}
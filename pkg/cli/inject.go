@@ -0,0 +1,193 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+	"github.com/kuda-io/kuda/pkg/webhook"
+)
+
+const (
+	// key of the webhook config in the webhook ConfigMap.
+	webhookConfigKey = "config.yaml"
+)
+
+// RunInject injects the kuda runtime into the workloads of the manifest, and writes the
+// injected manifest. Workloads not matching any DataSet are written unchanged.
+func (o *Options) RunInject() error {
+	if o.DataSetFile == "" {
+		return fmt.Errorf("the DataSets must be specified by --dataset")
+	}
+	if o.ConfigFile == "" {
+		return fmt.Errorf("the webhook config must be specified by --config")
+	}
+
+	config, err := loadWebhookConfig(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	datasets, err := loadDataSets(o.DataSetFile)
+	if err != nil {
+		return err
+	}
+	docs, err := readFile(o.Filename, o.In)
+	if err != nil {
+		return err
+	}
+
+	injected, err := injectDocuments(webhook.NewPodInjector(config, nil), docs, datasets)
+	if err != nil {
+		return err
+	}
+
+	return writeDocuments(o.Out, injected)
+}
+
+// injectDocuments injects the kuda runtime into the workloads of the documents.
+func injectDocuments(injector *webhook.PodInjector, docs []document, datasets []datav1alpha1.DataSet) ([][]byte, error) {
+	injected := make([][]byte, 0, len(docs))
+	for _, doc := range docs {
+		b, err := injectDocument(injector, doc, datasets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inject %s: %v", doc.Kind, err)
+		}
+		injected = append(injected, b)
+	}
+	return injected, nil
+}
+
+// injectDocument injects the document if it's a workload matching a dataset, and returns
+// the original document otherwise.
+func injectDocument(injector *webhook.PodInjector, doc document, datasets []datav1alpha1.DataSet) ([]byte, error) {
+	var (
+		obj      metav1.Object
+		template *corev1.PodTemplateSpec
+	)
+
+	switch {
+	case doc.APIVersion == "apps/v1" && doc.Kind == "Deployment":
+		deploy := &appsv1.Deployment{}
+		obj, template = deploy, &deploy.Spec.Template
+	case doc.APIVersion == "apps/v1" && doc.Kind == "StatefulSet":
+		sts := &appsv1.StatefulSet{}
+		obj, template = sts, &sts.Spec.Template
+	case doc.APIVersion == "apps/v1" && doc.Kind == "DaemonSet":
+		ds := &appsv1.DaemonSet{}
+		obj, template = ds, &ds.Spec.Template
+	case doc.APIVersion == "batch/v1" && doc.Kind == "Job":
+		job := &batchv1.Job{}
+		obj, template = job, &job.Spec.Template
+	case doc.APIVersion == "v1" && doc.Kind == "Pod":
+		pod := &corev1.Pod{}
+		if err := yaml.Unmarshal(doc.Raw, pod); err != nil {
+			return nil, err
+		}
+		if _, ok := pod.Annotations[datav1alpha1.KudaKeyDataSet]; ok {
+			return doc.Raw, nil
+		}
+		ds := matchDataSet(pod.Namespace, pod.Labels, datasets)
+		if ds == nil {
+			return doc.Raw, nil
+		}
+		injector.MutatePod(context.Background(), pod, ds)
+		return yaml.Marshal(pod)
+	default:
+		return doc.Raw, nil
+	}
+
+	if err := yaml.Unmarshal(doc.Raw, obj); err != nil {
+		return nil, err
+	}
+
+	ds := matchDataSet(obj.GetNamespace(), template.Labels, datasets)
+	if ds == nil || !injector.MutatePodTemplate(context.Background(), template, ds) {
+		return doc.Raw, nil
+	}
+
+	return yaml.Marshal(obj)
+}
+
+// matchDataSet returns the first dataset matching the labels, just like the webhook.
+// Manifests or datasets without namespace match any namespace.
+func matchDataSet(namespace string, labels map[string]string, datasets []datav1alpha1.DataSet) *datav1alpha1.DataSet {
+	for i := range datasets {
+		ds := &datasets[i]
+		if namespace != "" && ds.Namespace != "" && namespace != ds.Namespace {
+			continue
+		}
+		if utils.ContainsAll(labels, ds.Spec.WorkloadSelector) {
+			return ds
+		}
+	}
+	return nil
+}
+
+// loadDataSets returns the datasets in the file.
+func loadDataSets(name string) ([]datav1alpha1.DataSet, error) {
+	docs, err := readFile(name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	datasets := make([]datav1alpha1.DataSet, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Kind != "DataSet" {
+			continue
+		}
+		ds := datav1alpha1.DataSet{}
+		if err := yaml.Unmarshal(doc.Raw, &ds); err != nil {
+			return nil, err
+		}
+		datasets = append(datasets, ds)
+	}
+	if len(datasets) == 0 {
+		return nil, fmt.Errorf("no DataSet found in %s", name)
+	}
+
+	return datasets, nil
+}
+
+// loadWebhookConfig returns the webhook config from the config file, or from the manifest
+// of the webhook ConfigMap.
+func loadWebhookConfig(name string) (*webhook.Config, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := yaml.Unmarshal(b, cm); err == nil && cm.Kind == "ConfigMap" {
+		data, ok := cm.Data[webhookConfigKey]
+		if !ok {
+			return nil, fmt.Errorf("no %s found in ConfigMap %s", webhookConfigKey, cm.Name)
+		}
+		b = []byte(data)
+	}
+
+	return webhook.ParseConfig(b)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/webhook"
+)
+
+// RunLint validates the datasets in the files with the rules of the validating webhook.
+// Documents of other kinds are ignored.
+func (o *Options) RunLint(files []string) error {
+	invalid := 0
+	for _, name := range files {
+		docs, err := readFile(name, o.In)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if doc.Kind != "DataSet" {
+				continue
+			}
+			if !lintDataSet(o.Out, name, doc) {
+				invalid++
+			}
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d invalid DataSet(s) found", invalid)
+	}
+	return nil
+}
+
+// lintDataSet prints the errors of the dataset document, and returns true if it's valid.
+// Unknown fields are pruned by the API server, so they are only reported as warnings.
+func lintDataSet(out io.Writer, file string, doc document) bool {
+	ds := &datav1alpha1.DataSet{}
+	if err := yaml.Unmarshal(doc.Raw, ds); err != nil {
+		fmt.Fprintf(out, "%s: DataSet: %v\n", file, err)
+		return false
+	}
+
+	name := ds.Name
+	if ds.Namespace != "" {
+		name = ds.Namespace + "/" + ds.Name
+	}
+
+	if err := yaml.UnmarshalStrict(doc.Raw, &datav1alpha1.DataSet{}); err != nil {
+		fmt.Fprintf(out, "%s: DataSet %s: warning: %v\n", file, name, err)
+	}
+
+	errs := webhook.ValidateDataSet(ds, nil)
+	for _, err := range errs {
+		fmt.Fprintf(out, "%s: DataSet %s: %v\n", file, name, err)
+	}
+	if len(errs) > 0 {
+		return false
+	}
+
+	fmt.Fprintf(out, "%s: DataSet %s is valid\n", file, name)
+	return true
+}
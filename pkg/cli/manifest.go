@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// document is a single document of a multi-document YAML manifest.
+type document struct {
+	metav1.TypeMeta
+	Raw []byte
+}
+
+// readDocuments splits the multi-document YAML into documents, empty documents are dropped.
+func readDocuments(r io.Reader) ([]document, error) {
+	docs := make([]document, 0)
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		raw, err := reader.Read()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+
+		doc := document{Raw: bytes.TrimSpace(raw)}
+		if err := yaml.Unmarshal(raw, &doc.TypeMeta); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// readFile reads the documents in the file, "-" means reading from in.
+func readFile(name string, in io.Reader) ([]document, error) {
+	if name == "-" {
+		return readDocuments(in)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readDocuments(f)
+}
+
+// writeDocuments writes the documents as a multi-document YAML.
+func writeDocuments(w io.Writer, docs [][]byte) error {
+	for i, doc := range docs {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(append(bytes.TrimSpace(doc), '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	reasonActivated = "Activated"
+)
+
+// defaultActivationQuorum is the quorum of the activation policy without one.
+var defaultActivationQuorum = intstr.FromString("100%")
+
+// syncActivation bumps the activation generation for each new revision of the dataset, and
+// activates the generation once the quorum of the replicas have staged it. The activation
+// status is persisted before the data resources are updated by it, so that the generation
+// of the data resources is never ahead of the dataset.
+func (r *DataSetReconciler) syncActivation(ctx context.Context, instance *datav1alpha1.DataSet, revision string, dataList *datav1alpha1.DataList) error {
+	activation := getActivationStatus(instance, revision, dataList)
+	if reflect.DeepEqual(activation, instance.Status.Activation) {
+		return nil
+	}
+
+	activated := activation != nil && activation.ActiveGeneration == activation.Generation &&
+		(instance.Status.Activation == nil || instance.Status.Activation.ActiveGeneration != activation.ActiveGeneration)
+	if activated {
+		now := metav1.Now()
+		activation.LastActivationTime = &now
+	}
+
+	instance.Status.Activation = activation
+	if err := r.Status().Update(ctx, instance); err != nil {
+		return err
+	}
+	ctrllog.FromContext(ctx).Info("update dataset activation success", "activation", activation)
+
+	if activated && len(dataList.Items) > 0 {
+		r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonActivated, "Activated revision %s staged by %d/%d replica(s)",
+			revision, activation.Staged, len(dataList.Items))
+	}
+	return nil
+}
+
+// getActivationStatus returns the activation status of the dataset at the revision, or nil
+// if the dataset has no activation policy.
+func getActivationStatus(instance *datav1alpha1.DataSet, revision string, dataList *datav1alpha1.DataList) *datav1alpha1.ActivationStatus {
+	if instance.Spec.Activation == nil {
+		return nil
+	}
+
+	activation := &datav1alpha1.ActivationStatus{Revision: revision}
+	if instance.Status.Activation != nil {
+		activation = instance.Status.Activation.DeepCopy()
+	}
+	if activation.Revision != revision || activation.Generation == 0 {
+		activation.Generation++
+		activation.Revision = revision
+	}
+
+	activation.Staged = 0
+	for i := range dataList.Items {
+		if isDataStaged(&dataList.Items[i], revision, activation.Generation) {
+			activation.Staged++
+		}
+	}
+	if activation.Staged >= getActivationQuorum(instance.Spec.Activation, len(dataList.Items)) {
+		activation.ActiveGeneration = activation.Generation
+	}
+	return activation
+}
+
+// getActivationQuorum returns the number of the replicas which must stage a generation
+// before it's activated, the percentage is rounded up.
+func getActivationQuorum(policy *datav1alpha1.ActivationPolicy, replicas int) int {
+	quorum := &defaultActivationQuorum
+	if policy != nil && policy.Quorum != nil {
+		quorum = policy.Quorum
+	}
+	n, err := intstr.GetScaledValueFromIntOrPercent(quorum, replicas, true)
+	if err != nil || n > replicas {
+		return replicas
+	}
+	return n
+}
+
+// isDataStaged returns true if all the data items of the data resource are staged or
+// downloaded at the revision and the activation generation, so that they are switched to
+// without any download once the generation is activated.
+func isDataStaged(data *datav1alpha1.Data, revision string, generation int64) bool {
+	if data.Labels[datav1alpha1.KudaKeyRevision] != revision ||
+		data.Spec.Activation == nil || data.Spec.Activation.Generation != generation {
+		return false
+	}
+
+	phases := make(map[string]datav1alpha1.DataPhase, len(data.Status.DataItemsStatus))
+	for _, item := range data.Status.DataItemsStatus {
+		phases[item.Namespace+"/"+item.Name+"/"+item.Version] = item.Phase
+	}
+	for _, item := range data.Spec.DataItems {
+		switch phases[item.Namespace+"/"+item.Name+"/"+item.Version] {
+		case datav1alpha1.DataStaged, datav1alpha1.DataSuccess:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isActivationPending returns true if the data resource has staged the generation which is
+// activated by the dataset, but not activated itself yet.
+func isActivationPending(instance *datav1alpha1.DataSet, data *datav1alpha1.Data, revision string) bool {
+	activation := instance.Status.Activation
+	return activation != nil && activation.ActiveGeneration == activation.Generation &&
+		data.Spec.Activation != nil && data.Spec.Activation.ActiveGeneration != activation.ActiveGeneration &&
+		isDataStaged(data, revision, activation.Generation)
+}
+
+// getDataActivation returns the activation of the data resources of the dataset, or nil
+// if the dataset has no activation policy.
+func getDataActivation(instance *datav1alpha1.DataSet) *datav1alpha1.DataActivation {
+	if instance.Spec.Activation == nil || instance.Status.Activation == nil {
+		return nil
+	}
+	return &datav1alpha1.DataActivation{
+		Generation:       instance.Status.Activation.Generation,
+		ActiveGeneration: instance.Status.Activation.ActiveGeneration,
+	}
+}
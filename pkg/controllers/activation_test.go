@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestGetActivationQuorum(t *testing.T) {
+	assert.Equal(t, 4, getActivationQuorum(&v1alpha1.ActivationPolicy{}, 4))
+	quorum := intstr.FromString("90%")
+	assert.Equal(t, 9, getActivationQuorum(&v1alpha1.ActivationPolicy{Quorum: &quorum}, 10))
+	assert.Equal(t, 3, getActivationQuorum(&v1alpha1.ActivationPolicy{Quorum: &quorum}, 3))
+	quorum = intstr.FromInt(5)
+	assert.Equal(t, 3, getActivationQuorum(&v1alpha1.ActivationPolicy{Quorum: &quorum}, 3))
+	assert.Equal(t, 0, getActivationQuorum(nil, 0))
+}
+
+func TestSyncDataSetActivation(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	ds := getTestDataSet("test-ds", "dict")
+	ds.Namespace = "default"
+	ds.Spec.Activation = &v1alpha1.ActivationPolicy{}
+	assert.NoError(t, testDataSetReconciler.Create(ctx, ds))
+	podList := &v12.PodList{}
+	for _, name := range []string{"test-ds-abc-1", "test-ds-abc-2"} {
+		pod := getTestPod(name, true)
+		pod.Namespace = "default"
+		podList.Items = append(podList.Items, pod)
+	}
+	dataList := &v1alpha1.DataList{}
+	window := getUpdateWindow(ds, time.Now())
+
+	getActivation := func(i int) *v1alpha1.DataActivation {
+		data := &v1alpha1.Data{}
+		assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: dataList.Items[i].Name, Namespace: "default"}, data))
+		return data.Spec.Activation
+	}
+	stage := func(i int, phase v1alpha1.DataPhase) {
+		item := ds.Spec.Template.DataItems[0]
+		dataList.Items[i].Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+			{Name: item.Name, Namespace: item.Namespace, Version: item.Version, Phase: phase},
+		}
+	}
+
+	// The first generation is activated at once without any replica.
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nil, window))
+	assert.Len(t, dataList.Items, 2)
+	assert.Equal(t, &v1alpha1.DataActivation{Generation: 1, ActiveGeneration: 1}, getActivation(0))
+
+	// The new revision is staged before it's activated.
+	ds.Spec.Template.DataItems[0].Version = "v2"
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nil, window))
+	assert.Equal(t, &v1alpha1.DataActivation{Generation: 2, ActiveGeneration: 1}, getActivation(0))
+	assert.Equal(t, &v1alpha1.DataActivation{Generation: 2, ActiveGeneration: 1}, getActivation(1))
+
+	stage(0, v1alpha1.DataStaged)
+	stage(1, v1alpha1.DataDownloading)
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nil, window))
+	assert.Equal(t, 1, ds.Status.Activation.Staged)
+	assert.Equal(t, int64(1), ds.Status.Activation.ActiveGeneration)
+	assert.Equal(t, &v1alpha1.DataActivation{Generation: 2, ActiveGeneration: 1}, getActivation(0))
+
+	// It's activated on all the replicas at once, even if the downloads are limited.
+	stage(1, v1alpha1.DataStaged)
+	nsQuota := &namespaceQuota{downloads: 0}
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nsQuota, window))
+	assert.Equal(t, 2, ds.Status.Activation.Staged)
+	assert.NotNil(t, ds.Status.Activation.LastActivationTime)
+	assert.Equal(t, &v1alpha1.DataActivation{Generation: 2, ActiveGeneration: 2}, getActivation(0))
+	assert.Equal(t, &v1alpha1.DataActivation{Generation: 2, ActiveGeneration: 2}, getActivation(1))
+
+	// The activation is dropped with the policy.
+	ds.Spec.Activation = nil
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nil, window))
+	assert.Nil(t, ds.Status.Activation)
+	assert.Nil(t, getActivation(0))
+}
+
+func TestIsDataStaged(t *testing.T) {
+	data := getTestData("test-ds", "dict", "test-pod")
+	data.Spec.DataItems = append(data.Spec.DataItems, getTestDataItem("model"))
+	data.Spec.Activation = &v1alpha1.DataActivation{Generation: 2, ActiveGeneration: 1}
+	data.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+		{Name: "dict", Namespace: "test-ns", Version: "v1", Phase: v1alpha1.DataStaged},
+		{Name: "model", Namespace: "test-ns", Version: "v1", Phase: v1alpha1.DataDownloading},
+	}
+	assert.False(t, isDataStaged(data, "test-ds-v1", 2))
+
+	// The data items unchanged since the previous generation are downloaded already.
+	data.Status.DataItemsStatus[1].Phase = v1alpha1.DataSuccess
+	assert.True(t, isDataStaged(data, "test-ds-v1", 2))
+	assert.False(t, isDataStaged(data, "test-ds-v1", 3))
+	assert.False(t, isDataStaged(data, "test-ds-v2", 2))
+}
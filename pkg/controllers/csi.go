@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// getCSIDataSets returns the datasets referenced by the inline kuda CSI volumes of the pod.
+func getCSIDataSets(pod *v1.Pod) []string {
+	names := make([]string, 0)
+	for _, volume := range pod.Spec.Volumes {
+		if volume.CSI == nil || volume.CSI.Driver != datav1alpha1.KudaCSIDriverName {
+			continue
+		}
+		attributes := volume.CSI.VolumeAttributes
+		if ns := attributes[datav1alpha1.KudaCSIVolumeNamespace]; ns != "" && ns != pod.Namespace {
+			continue
+		}
+		if ds := attributes[datav1alpha1.KudaCSIVolumeDataSet]; ds != "" {
+			names = append(names, ds)
+		}
+	}
+	return names
+}
+
+// isPodServedByCSI returns true if the data of the pod is served by the kuda CSI driver
+// instead of the kuda runtime, there is no data resource for such pods.
+func isPodServedByCSI(pod *v1.Pod) bool {
+	return len(getCSIDataSets(pod)) > 0
+}
+
+// getCSIPodNodes returns the nodes of the scheduled pods referencing the dataset by the
+// inline kuda CSI volumes, the data is prefetched onto these nodes on demand so that the
+// volumes can be published.
+func (r *DataSetReconciler) getCSIPodNodes(ctx context.Context, instance *datav1alpha1.DataSet) ([]string, error) {
+	podList := &v1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]string, 0)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Spec.NodeName == "" || pod.GetDeletionTimestamp() != nil ||
+			pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, ds := range getCSIDataSets(pod) {
+			if ds == instance.Name {
+				nodes = append(nodes, pod.Spec.NodeName)
+				break
+			}
+		}
+	}
+	return nodes, nil
+}
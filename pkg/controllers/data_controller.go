@@ -28,12 +28,14 @@ import (
 	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/metrics"
 	"github.com/kuda-io/kuda/pkg/utils"
 )
 
@@ -42,12 +44,21 @@ const (
 
 	runtimeRole        = "kuda-runtime-role"
 	runtimeRoleBinding = "kuda-runtime-rolebinding"
+
+	reasonDataReset         = "DataReset"
+	reasonDataDownloading   = "DataDownloading"
+	reasonDataItemFailed    = "DataItemFailed"
+	reasonDataReady         = "DataReady"
+	reasonFinalizerRemoved  = "FinalizerRemoved"
+	reasonFailedUpdatePod   = "FailedUpdatePod"
+	reasonFailedRoleBinding = "FailedRoleBinding"
 )
 
 // DataReconciler reconciles a Data object
 type DataReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=data.kuda.io,resources=datas,verbs=get;list;watch;create;update;patch;delete
@@ -55,6 +66,7 @@ type DataReconciler struct {
 //+kubebuilder:rbac:groups=data.kuda.io,resources=datas/finalizers,verbs=update
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;update;create
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -82,11 +94,15 @@ func (r *DataReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 	pod := &v1.Pod{}
 	if err := r.Get(ctx, types.NamespacedName{Name: getPodNameByData(instance), Namespace: instance.Namespace}, pod); err != nil {
 		if errors.IsNotFound(err) {
+			if !controllerutil.ContainsFinalizer(instance, dataFinalizer) {
+				return ctrl.Result{}, nil
+			}
 			controllerutil.RemoveFinalizer(instance, dataFinalizer)
 			if err := r.Update(ctx, instance); err != nil {
 				log.Error(err, "failed to remove finalizer")
 				return ctrl.Result{}, err
 			}
+			r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonFinalizerRemoved, "Removed finalizer as pod %s no longer exists", getPodNameByData(instance))
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "failed to get pod")
@@ -119,11 +135,13 @@ func (r *DataReconciler) syncData(ctx context.Context, instance *datav1alpha1.Da
 
 	if err := r.updateRoleBinding(ctx, instance, pod); err != nil {
 		log.Error(err, "failed to update rolebinding")
+		r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedRoleBinding, "Failed to bind runtime role to service account %s: %v", pod.Spec.ServiceAccountName, err)
 		return err
 	}
 
 	if err := r.updatePodAnnotations(ctx, pod, dataTag); err != nil {
 		log.Error(err, "failed to update pod annotations")
+		r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedUpdatePod, "Failed to update data digest of pod %s: %v", pod.Name, err)
 		return err
 	}
 
@@ -140,6 +158,7 @@ func (r *DataReconciler) syncData(ctx context.Context, instance *datav1alpha1.Da
 				log.Error(err, "failed to remove finalizer")
 				return err
 			}
+			r.Recorder.Event(instance, v1.EventTypeNormal, reasonFinalizerRemoved, "Removed finalizer and data digest of the pod")
 		}
 		return nil
 	}
@@ -161,10 +180,13 @@ func (r *DataReconciler) updateDataStatus(ctx context.Context, instance *datav1a
 		err  error
 	)
 
+	oldStatus := instance.Status.DeepCopy()
 	newStatus := genLatestStatus(instance)
 	if v, ok := pod.Annotations[datav1alpha1.KudaKeyDigest]; !ok || v != dataTag {
 		newStatus = genDefaultStatus(instance)
 		diff = true
+	} else {
+		observeCompletedItems(instance, newStatus)
 	}
 
 	if !reflect.DeepEqual(newStatus, instance.Status) {
@@ -182,9 +204,50 @@ func (r *DataReconciler) updateDataStatus(ctx context.Context, instance *datav1a
 		ctrllog.FromContext(ctx).Info("update data status success")
 	}
 
+	r.recordStatusEvents(instance, pod, oldStatus, newStatus, diff)
+
 	return nil
 }
 
+// recordStatusEvents records events on the data resource and the pod for the status transitions.
+func (r *DataReconciler) recordStatusEvents(instance *datav1alpha1.Data, pod *v1.Pod, oldStatus, newStatus *datav1alpha1.DataStatus, reset bool) {
+	if reset {
+		if newStatus.Queued > 0 {
+			r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonDataReset, "Spec changed, %d data item(s) are queued for the download lease", newStatus.DataItems)
+			return
+		}
+		r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonDataReset, "Spec changed, waiting for %d data item(s) to be downloaded", newStatus.DataItems)
+		return
+	}
+
+	if newStatus.Downloading > oldStatus.Downloading {
+		r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonDataDownloading, "Downloading %d data item(s)", newStatus.Downloading)
+	}
+
+	// Only the data items newly failed are announced, the ones failed already were.
+	failed := make(map[string]bool, oldStatus.Failed)
+	for _, item := range oldStatus.DataItemsStatus {
+		if item.Phase == datav1alpha1.DataFailed {
+			failed[fmt.Sprintf("%s/%s@%s", item.Namespace, item.Name, item.Version)] = true
+		}
+	}
+	for _, item := range newStatus.DataItemsStatus {
+		if item.Phase != datav1alpha1.DataFailed || failed[fmt.Sprintf("%s/%s@%s", item.Namespace, item.Name, item.Version)] {
+			continue
+		}
+		for _, obj := range []runtime.Object{instance, pod} {
+			r.Recorder.Eventf(obj, v1.EventTypeWarning, reasonDataItemFailed, "Data item %s/%s@%s failed: %s",
+				item.Namespace, item.Name, item.Version, item.Message)
+		}
+	}
+
+	if newStatus.DataItems > 0 && newStatus.Success == newStatus.DataItems && oldStatus.Success != newStatus.Success {
+		for _, obj := range []runtime.Object{instance, pod} {
+			r.Recorder.Eventf(obj, v1.EventTypeNormal, reasonDataReady, "All %d data item(s) are ready", newStatus.DataItems)
+		}
+	}
+}
+
 // update role binding for the service account of pod.
 func (r *DataReconciler) updateRoleBinding(ctx context.Context, instance *datav1alpha1.Data, pod *v1.Pod) error {
 	roleBinding := &v13.RoleBinding{
@@ -241,40 +304,74 @@ func (r *DataReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
+// genDefaultStatus returns the status of the data items not started yet, they are queued
+// if the data resource waits for the download lease. The staged data items are kept staged,
+// since they are activated by the kuda runtime without a download.
 func genDefaultStatus(d *datav1alpha1.Data) *datav1alpha1.DataStatus {
+	phase := datav1alpha1.DataWaiting
+	if d.Spec.Queued {
+		phase = datav1alpha1.DataQueued
+	}
+
+	staged := make(map[string]datav1alpha1.DataItemStatus)
+	for _, item := range d.Status.DataItemsStatus {
+		if item.Phase == datav1alpha1.DataStaged {
+			staged[item.Namespace+"/"+item.Name+"/"+item.Version] = item
+		}
+	}
+
 	status := make(datav1alpha1.DataItemsStatus, 0, len(d.Spec.DataItems))
 	for _, data := range d.Spec.DataItems {
+		if item, ok := staged[data.Namespace+"/"+data.Name+"/"+data.Version]; ok {
+			status = append(status, item)
+			continue
+		}
 		status = append(status, datav1alpha1.DataItemStatus{
 			Name:      data.Name,
 			Namespace: data.Namespace,
 			Version:   data.Version,
-			Phase:     datav1alpha1.DataWaiting,
+			Phase:     phase,
 			StartTime: metav1.Now(),
 		})
 	}
 
-	return &datav1alpha1.DataStatus{
+	newStatus := &datav1alpha1.DataStatus{
 		DataItemsStatus: status,
 		DataItems:       len(d.Spec.DataItems),
-		Waiting:         len(d.Spec.DataItems),
 		Ready:           fmt.Sprintf("0/%d", len(d.Spec.DataItems)),
 	}
+	for _, item := range status {
+		switch item.Phase {
+		case datav1alpha1.DataStaged:
+			newStatus.Staged += 1
+		case datav1alpha1.DataQueued:
+			newStatus.Queued += 1
+		default:
+			newStatus.Waiting += 1
+		}
+	}
+
+	return newStatus
 }
 
 func genLatestStatus(d *datav1alpha1.Data) *datav1alpha1.DataStatus {
 	status := &datav1alpha1.DataStatus{
-		DataItemsStatus: d.Status.DataItemsStatus,
+		DataItemsStatus: d.Status.DataItemsStatus.DeepCopy(),
 		DataItems:       len(d.Spec.DataItems),
 	}
 
 	for _, item := range d.Status.DataItemsStatus {
 		switch item.Phase {
+		case datav1alpha1.DataQueued:
+			status.Queued += 1
 		case datav1alpha1.DataWaiting:
 			status.Waiting += 1
 		case datav1alpha1.DataSuccess:
 			status.Success += 1
 		case datav1alpha1.DataDownloading:
 			status.Downloading += 1
+		case datav1alpha1.DataStaged:
+			status.Staged += 1
 		case datav1alpha1.DataFailed:
 			status.Failed += 1
 		}
@@ -284,3 +381,23 @@ func genLatestStatus(d *datav1alpha1.Data) *datav1alpha1.DataStatus {
 
 	return status
 }
+
+// observeCompletedItems sets the completion time of the newly successful data items,
+// and observes their download durations.
+func observeCompletedItems(d *datav1alpha1.Data, status *datav1alpha1.DataStatus) {
+	sourceTypes := make(map[string]string, len(d.Spec.DataItems))
+	for _, item := range d.Spec.DataItems {
+		sourceTypes[item.Namespace+"/"+item.Name] = item.DataSourceType
+	}
+
+	now := metav1.Now()
+	for i := range status.DataItemsStatus {
+		item := &status.DataItemsStatus[i]
+		if item.Phase != datav1alpha1.DataSuccess || item.CompletionTime != nil {
+			continue
+		}
+		item.CompletionTime = &now
+		metrics.DownloadDuration.WithLabelValues(d.Namespace, d.Labels[datav1alpha1.KudaKeyDataSet], sourceTypes[item.Namespace+"/"+item.Name]).
+			Observe(now.Sub(item.StartTime.Time).Seconds())
+	}
+}
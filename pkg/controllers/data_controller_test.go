@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestRecordStatusEvents(t *testing.T) {
+	data := getTestData("test-ds", "test-data", "test-pod")
+	pod := getTestPod("test-pod", true)
+
+	t.Run("record failed data items with the runtime message", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		r := &DataReconciler{Recorder: recorder}
+
+		oldStatus := genDefaultStatus(data)
+		data.Status = *oldStatus.DeepCopy()
+		data.Status.DataItemsStatus[0].Phase = v1alpha1.DataFailed
+		data.Status.DataItemsStatus[0].Message = "file not found"
+		newStatus := genLatestStatus(data)
+
+		r.recordStatusEvents(data, &pod, oldStatus, newStatus, false)
+		assert.Len(t, recorder.Events, 2)
+		event := <-recorder.Events
+		assert.Contains(t, event, v12.EventTypeWarning)
+		assert.Contains(t, event, reasonDataItemFailed)
+		assert.Contains(t, event, "file not found")
+	})
+
+	t.Run("record only the newly failed data items", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		r := &DataReconciler{Recorder: recorder}
+
+		failed := data.DeepCopy()
+		failed.Spec.DataItems = append(failed.Spec.DataItems, getTestDataItem("model"))
+		failed.Status = *genDefaultStatus(failed)
+		failed.Status.DataItemsStatus[0].Phase = v1alpha1.DataFailed
+		failed.Status.DataItemsStatus[0].Message = "file not found"
+		oldStatus := genLatestStatus(failed)
+		failed.Status = *oldStatus.DeepCopy()
+		failed.Status.DataItemsStatus[1].Phase = v1alpha1.DataFailed
+		failed.Status.DataItemsStatus[1].Message = "permission denied"
+		newStatus := genLatestStatus(failed)
+
+		r.recordStatusEvents(failed, &pod, oldStatus, newStatus, false)
+		assert.Len(t, recorder.Events, 2)
+		for i := 0; i < 2; i++ {
+			event := <-recorder.Events
+			assert.Contains(t, event, "permission denied")
+			assert.NotContains(t, event, "file not found")
+		}
+	})
+
+	t.Run("record ready when all data items succeed", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		r := &DataReconciler{Recorder: recorder}
+
+		oldStatus := genDefaultStatus(data)
+		data.Status = *oldStatus
+		data.Status.DataItemsStatus[0].Phase = v1alpha1.DataSuccess
+		newStatus := genLatestStatus(data)
+
+		r.recordStatusEvents(data, &pod, oldStatus, newStatus, false)
+		assert.Len(t, recorder.Events, 2)
+		assert.Contains(t, <-recorder.Events, reasonDataReady)
+	})
+
+	t.Run("record queued data items on reset", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		r := &DataReconciler{Recorder: recorder}
+
+		queued := data.DeepCopy()
+		queued.Spec.Queued = true
+		status := genDefaultStatus(queued)
+		assert.Equal(t, 1, status.Queued)
+		assert.Equal(t, 0, status.Waiting)
+		assert.Equal(t, v1alpha1.DataQueued, status.DataItemsStatus[0].Phase)
+
+		r.recordStatusEvents(queued, &pod, nil, status, true)
+		assert.Len(t, recorder.Events, 1)
+		assert.Contains(t, <-recorder.Events, "queued for the download lease")
+	})
+
+	t.Run("keep the staged data items on reset", func(t *testing.T) {
+		staged := data.DeepCopy()
+		staged.Spec.DataItems = append(staged.Spec.DataItems, getTestDataItem("model"))
+		staged.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+			{Name: staged.Spec.DataItems[0].Name, Namespace: "test-ns", Version: "v1", Phase: v1alpha1.DataStaged},
+		}
+		status := genDefaultStatus(staged)
+		assert.Equal(t, 1, status.Staged)
+		assert.Equal(t, 1, status.Waiting)
+		assert.Equal(t, v1alpha1.DataStaged, status.DataItemsStatus[0].Phase)
+		assert.Equal(t, v1alpha1.DataWaiting, status.DataItemsStatus[1].Phase)
+	})
+
+	t.Run("record nothing if the status not changes", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		r := &DataReconciler{Recorder: recorder}
+
+		status := genLatestStatus(data)
+		r.recordStatusEvents(data, &pod, status, status, false)
+		assert.Len(t, recorder.Events, 0)
+	})
+}
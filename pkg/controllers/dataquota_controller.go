@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/quota"
+)
+
+// DataQuotaReconciler reports the usage of the namespace in the status of each DataQuota,
+// the quotas are enforced by the webhook and the DataSet controller.
+type DataQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=data.kuda.io,resources=dataquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=data.kuda.io,resources=dataquotas/status,verbs=get;update;patch
+
+// Reconcile updates the usage of the namespace in the DataQuota status.
+func (r *DataQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
+	instance := &datav1alpha1.DataQuota{}
+	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "failed to get DataQuota")
+		return ctrl.Result{}, err
+	}
+
+	dsList := &datav1alpha1.DataSetList{}
+	if err := r.List(ctx, dsList, client.InNamespace(req.Namespace)); err != nil {
+		log.Error(err, "failed to list datasets")
+		return ctrl.Result{}, err
+	}
+	dataList := &datav1alpha1.DataList{}
+	if err := r.List(ctx, dataList, client.InNamespace(req.Namespace)); err != nil {
+		log.Error(err, "failed to list data resource")
+		return ctrl.Result{}, err
+	}
+
+	newStatus := genDataQuotaStatus(instance, dsList.Items, dataList.Items)
+	if !reflect.DeepEqual(newStatus, &instance.Status) {
+		instance.Status = *newStatus
+		if err := r.Status().Update(ctx, instance); err != nil {
+			log.Error(err, "failed to update DataQuota status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// genDataQuotaStatus returns the usage of the namespace by its datasets and data resources.
+func genDataQuotaStatus(instance *datav1alpha1.DataQuota, dataSets []datav1alpha1.DataSet, dataList []datav1alpha1.Data) *datav1alpha1.DataQuotaStatus {
+	status := &datav1alpha1.DataQuotaStatus{
+		Used:               quota.Usage(dataSets),
+		ObservedGeneration: instance.Generation,
+	}
+	status.Used.Downloads = quota.Downloads(dataList)
+
+	for name := range quota.GetExceededDataSets(instance, dataSets) {
+		status.ExceededDataSets = append(status.ExceededDataSets, name)
+	}
+	sort.Strings(status.ExceededDataSets)
+
+	return status
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DataQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The usage changes with the data items of the datasets, and the downloads of the data.
+	dataSetPredicates := predicate.GenerationChangedPredicate{}
+	dataPredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return quota.IsDownloading(e.ObjectOld.(*datav1alpha1.Data)) != quota.IsDownloading(e.ObjectNew.(*datav1alpha1.Data))
+		},
+	}
+	namespaceHandlers := handler.MapFunc(func(object client.Object) []reconcile.Request {
+		requests := make([]reconcile.Request, 0)
+		quotaList := &datav1alpha1.DataQuotaList{}
+		if err := r.List(context.Background(), quotaList, client.InNamespace(object.GetNamespace())); err != nil {
+			ctrllog.Log.Error(err, "failed to list data quotas", "namespace", object.GetNamespace())
+			return requests
+		}
+		for _, dataQuota := range quotaList.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      dataQuota.Name,
+				Namespace: dataQuota.Namespace,
+			}})
+		}
+		return requests
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&datav1alpha1.DataQuota{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: &datav1alpha1.DataSet{}},
+			handler.EnqueueRequestsFromMapFunc(namespaceHandlers),
+			builder.WithPredicates(dataSetPredicates)).
+		Watches(
+			&source.Kind{Type: &datav1alpha1.Data{}},
+			handler.EnqueueRequestsFromMapFunc(namespaceHandlers),
+			builder.WithPredicates(dataPredicates)).
+		Complete(r)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func newTestQuotaDataSet(name, size string, created time.Time) *v1alpha1.DataSet {
+	ds := getTestDataSet(name, "model")
+	ds.Namespace = "default"
+	ds.UID = types.UID(name)
+	ds.CreationTimestamp = v1.NewTime(created)
+	quantity := resource.MustParse(size)
+	ds.Spec.Template.DataItems[0].Size = &quantity
+	return ds
+}
+
+func TestDataQuotaReconcile(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+	r := &DataQuotaReconciler{Client: testDataSetReconciler.Client, Scheme: testDataSetReconciler.Scheme}
+
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	maxSize := resource.MustParse("10Gi")
+	quota := &v1alpha1.DataQuota{
+		ObjectMeta: v1.ObjectMeta{Name: "quota", Namespace: "default"},
+		Spec:       v1alpha1.DataQuotaSpec{MaxSize: &maxSize},
+	}
+	data := getTestData("model-ds", "model", "test-pod")
+	data.Namespace = "default"
+	for _, object := range []client.Object{
+		quota,
+		newTestQuotaDataSet("model-ds", "8Gi", now),
+		newTestQuotaDataSet("dict-ds", "4Gi", now.Add(time.Minute)),
+		data,
+	} {
+		assert.NoError(t, r.Create(ctx, object))
+	}
+
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "quota", Namespace: "default"}})
+	assert.NoError(t, err)
+	quota = &v1alpha1.DataQuota{}
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: "quota", Namespace: "default"}, quota))
+	assert.Equal(t, 2, quota.Status.Used.DataSets)
+	assert.Equal(t, 2, quota.Status.Used.Items)
+	assert.Equal(t, "12Gi", quota.Status.Used.Size.String())
+	assert.Equal(t, 1, quota.Status.Used.Downloads)
+	assert.Equal(t, []string{"dict-ds"}, quota.Status.ExceededDataSets)
+}
+
+func TestSyncDataSetWithinQuota(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	maxSize := resource.MustParse("10Gi")
+	quota := &v1alpha1.DataQuota{
+		ObjectMeta: v1.ObjectMeta{Name: "quota", Namespace: "default"},
+		Spec:       v1alpha1.DataQuotaSpec{MaxSize: &maxSize, MaxConcurrentDownloads: pointer.Int32Ptr(1)},
+	}
+	model := newTestQuotaDataSet("model-ds", "8Gi", now)
+	dict := newTestQuotaDataSet("dict-ds", "4Gi", now.Add(time.Minute))
+	for _, object := range []client.Object{quota, model, dict} {
+		assert.NoError(t, testDataSetReconciler.Create(ctx, object))
+	}
+	podList := &v12.PodList{}
+	for _, name := range []string{"model-ds-abc-1", "model-ds-abc-2"} {
+		pod := getTestPod(name, true)
+		pod.Namespace = "default"
+		podList.Items = append(podList.Items, pod)
+	}
+
+	// Only one of the pods starts downloading at a time.
+	nsQuota, err := testDataSetReconciler.getNamespaceQuota(ctx, model)
+	assert.NoError(t, err)
+	dataList := &v1alpha1.DataList{}
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, model, podList, dataList, nsQuota, getUpdateWindow(model, time.Now())))
+	assert.Len(t, dataList.Items, 1)
+	assert.True(t, nsQuota.isQueued())
+	condition := meta.FindStatusCondition(model.Status.Conditions, v1alpha1.DataSetWithinQuota)
+	assert.Equal(t, v1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonDownloadsQueued, condition.Reason)
+
+	// The queued one starts once the other completes.
+	data := &v1alpha1.Data{}
+	assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: dataList.Items[0].Name, Namespace: "default"}, data))
+	item := data.Spec.DataItems[0]
+	data.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+		{Name: item.Name, Namespace: item.Namespace, Version: item.Version, Phase: v1alpha1.DataSuccess},
+	}
+	assert.NoError(t, testDataSetReconciler.Status().Update(ctx, data))
+	nsQuota, err = testDataSetReconciler.getNamespaceQuota(ctx, model)
+	assert.NoError(t, err)
+	dataList = &v1alpha1.DataList{Items: []v1alpha1.Data{*data}}
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, model, podList, dataList, nsQuota, getUpdateWindow(model, time.Now())))
+	assert.Len(t, dataList.Items, 2)
+	assert.False(t, nsQuota.isQueued())
+	condition = meta.FindStatusCondition(model.Status.Conditions, v1alpha1.DataSetWithinQuota)
+	assert.Equal(t, v1.ConditionTrue, condition.Status)
+
+	// Nothing is delivered for the dataset exceeding the quota.
+	nsQuota, err = testDataSetReconciler.getNamespaceQuota(ctx, dict)
+	assert.NoError(t, err)
+	assert.Equal(t, "exceeded DataQuota quota: the total size 12Gi exceeds the max size 10Gi", nsQuota.exceeded)
+	assert.False(t, nsQuota.allowDownload(nil))
+	assert.False(t, nsQuota.isQueued())
+
+	// Nothing is limited without quotas.
+	assert.NoError(t, testDataSetReconciler.Delete(ctx, quota))
+	nsQuota, err = testDataSetReconciler.getNamespaceQuota(ctx, dict)
+	assert.NoError(t, err)
+	assert.Nil(t, nsQuota)
+	assert.True(t, nsQuota.allowDownload(nil))
+	assert.Nil(t, newQuotaCondition(dict, nsQuota))
+}
@@ -20,13 +20,18 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,12 +43,32 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	reasonCreatedData      = "CreatedData"
+	reasonUpdatedData      = "UpdatedData"
+	reasonPrunedData       = "PrunedData"
+	reasonFailedCreateData = "FailedCreateData"
+	reasonFailedUpdateData = "FailedUpdateData"
+	reasonFailedPruneData  = "FailedPruneData"
 )
 
 // DataSetReconciler reconciles a DataSet object
 type DataSetReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Prefetch describes the kuda runtime prefetching the data onto nodes.
+	Prefetch PrefetchConfig
+	// DownloadLease describes the limits of the concurrent downloads, the data resources are
+	// queued for the download leases if limited.
+	DownloadLease DownloadLeaseConfig
+
+	// namespaces are the namespaces which have had datasets, the pods missed injection in the
+	// other namespaces are never mapped to the datasets by the workload selector.
+	namespaces sync.Map
 }
 
 //+kubebuilder:rbac:groups=data.kuda.io,resources=datasets,verbs=get;list;watch;create;update;patch;delete
@@ -51,8 +76,15 @@ type DataSetReconciler struct {
 //+kubebuilder:rbac:groups=data.kuda.io,resources=datasets/finalizers,verbs=update
 //+kubebuilder:rbac:groups=data.kuda.io,resources=datas,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=data.kuda.io,resources=datas/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=data.kuda.io,resources=dataquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=pods/exec,verbs=get;list;patch;update;create
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -75,6 +107,8 @@ func (r *DataSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
+	r.namespaces.Store(req.Namespace, true)
+
 	// Get the pod list that match workloadSelector in DataSet
 	podList := &v1.PodList{}
 	podListOpts := []client.ListOption{
@@ -97,49 +131,123 @@ func (r *DataSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
+	// Get the data quota of the namespace
+	nsQuota, err := r.getNamespaceQuota(ctx, instance)
+	if err != nil {
+		log.Error(err, "failed to get data quota")
+		return ctrl.Result{}, err
+	}
+
+	// Get the update windows of the DataSet
+	now := time.Now()
+	window := getUpdateWindow(instance, now)
+
 	// Sync DataSet
-	if err := r.syncDataSet(ctx, instance, podList, dataList); err != nil {
+	if err := r.syncDataSet(ctx, instance, podList, dataList, nsQuota, window); err != nil {
 		log.Error(err, "sync dataset error")
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// Retry the downloads queued until the others in the namespace complete.
+	requeueAfter := window.requeueAfter(now)
+	if nsQuota.isQueued() && (requeueAfter == 0 || quotaRequeueInterval < requeueAfter) {
+		requeueAfter = quotaRequeueInterval
+	}
+	// Check the workloads restarted for the pods missed injection once the backoff passes.
+	if after := getRestartRequeueAfter(instance, now); after > 0 && (requeueAfter == 0 || after < requeueAfter) {
+		requeueAfter = after
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // syncDataSet takes action(create/update/delete) on each data resource by the corresponding pod.
-func (r *DataSetReconciler) syncDataSet(ctx context.Context, instance *datav1alpha1.DataSet, podList *v1.PodList, dataList *datav1alpha1.DataList) error {
+// The data resources are only created or updated within the data quota of the namespace,
+// and the ones of the existing pods are only updated within the update windows.
+func (r *DataSetReconciler) syncDataSet(ctx context.Context, instance *datav1alpha1.DataSet, podList *v1.PodList, dataList *datav1alpha1.DataList, nsQuota *namespaceQuota, window *updateWindow) error {
 	log := ctrllog.FromContext(ctx)
 
+	// Data resources are only maintained for the pods with the kuda runtime,
+	// the others will never be served.
+	podList, uninjectedPods := filterInjectedPods(podList)
+
 	podMap := convertPodListToMap(podList)
 	dataMap := convertDataListToMap(dataList)
 
+	revision, err := r.syncRevisions(ctx, instance)
+	if err != nil {
+		log.Error(err, "failed to sync revisions")
+		return err
+	}
+
+	if err := r.syncActivation(ctx, instance, revision, dataList); err != nil {
+		log.Error(err, "failed to sync activation")
+		return err
+	}
+
 	for _, pod := range podList.Items {
 		dataName := getDataNameByPod(instance.Name, pod.Name)
 		if dataOld, ok := dataMap[dataName]; ok {
-			if err := r.updateDataResource(ctx, instance, dataOld); err != nil {
+			// The data resources of existing pods are kept as they are during pausing.
+			if instance.Spec.Paused {
+				continue
+			}
+			upToDate := r.isDataUpToDate(instance, dataOld, revision)
+			// The updates are held until the update window opens.
+			if !upToDate && !window.open {
+				window.held++
+				continue
+			}
+			// Activating the staged data downloads nothing.
+			if !upToDate && !isActivationPending(instance, dataOld, revision) && !nsQuota.allowDownload(dataOld) {
+				continue
+			}
+			if err := r.updateDataResource(ctx, instance, dataOld, revision); err != nil {
 				log.Error(err, "failed to update data resource")
+				r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedUpdateData, "Failed to update Data %s: %v", dataName, err)
 				return err
 			}
 			continue
 		}
 
 		// Create if the data resource is not exist.
-		data, err := r.createDataResource(ctx, instance, pod.Name)
+		if !nsQuota.allowDownload(nil) {
+			continue
+		}
+		data, err := r.createDataResource(ctx, instance, pod.Name, revision)
 		if err != nil {
 			log.Error(err, "failed to create date resource")
+			r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedCreateData, "Failed to create Data %s: %v", dataName, err)
 			return err
 		}
+		if data == nil {
+			continue
+		}
 		dataList.Items = append(dataList.Items, *data)
 	}
 
 	// delete data resource if the corresponding pod is not exist
-	if err := r.pruneDataResources(ctx, dataList, podMap); err != nil {
+	if err := r.pruneDataResources(ctx, instance, dataList, podMap); err != nil {
 		log.Error(err, "failed to delete data resource")
 		return err
 	}
 
+	// take actions on the pods missed the injection
+	restarts, err := r.handleUninjectedPods(ctx, instance, uninjectedPods, time.Now())
+	if err != nil {
+		log.Error(err, "failed to handle uninjected pods")
+		return err
+	}
+
+	// prefetch the data onto the selected nodes
+	prefetch, err := r.syncPrefetch(ctx, instance, revision, nsQuota)
+	if err != nil {
+		log.Error(err, "failed to prefetch data")
+		return err
+	}
+
 	// update status of the dataset
-	if err := r.updateDataSetStatus(ctx, instance, dataList); err != nil {
+	if err := r.updateDataSetStatus(ctx, instance, dataList, podMap, uninjectedPods, restarts, revision, prefetch, nsQuota, window); err != nil {
 		log.Error(err, "failed to update dataset status", "name", instance.Name)
 		return err
 	}
@@ -148,8 +256,8 @@ func (r *DataSetReconciler) syncDataSet(ctx context.Context, instance *datav1alp
 }
 
 // createDataResource create a new data resource for the pod.
-func (r *DataSetReconciler) createDataResource(ctx context.Context, instance *datav1alpha1.DataSet, podName string) (*datav1alpha1.Data, error) {
-	data := r.newDataResource(instance, podName)
+func (r *DataSetReconciler) createDataResource(ctx context.Context, instance *datav1alpha1.DataSet, podName, revision string) (*datav1alpha1.Data, error) {
+	data := r.newDataResource(instance, podName, revision)
 	if err := ctrl.SetControllerReference(instance, data, r.Scheme); err != nil {
 		return nil, err
 	}
@@ -163,50 +271,71 @@ func (r *DataSetReconciler) createDataResource(ctx context.Context, instance *da
 	}
 
 	ctrllog.FromContext(ctx).Info("create data resource success", "data.Name", data.Name, "data.Namespace", data.Namespace)
+	r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonCreatedData, "Created Data %s for pod %s", data.Name, podName)
 
 	return data, nil
 }
 
-// newDataResource returns a data object for the pod.
-func (r *DataSetReconciler) newDataResource(instance *datav1alpha1.DataSet, podName string) *datav1alpha1.Data {
+// newDataResource returns a data object for the pod, which is queued for the download lease
+// if the downloads are limited.
+func (r *DataSetReconciler) newDataResource(instance *datav1alpha1.DataSet, podName, revision string) *datav1alpha1.Data {
 	data := &datav1alpha1.Data{
 		ObjectMeta: v12.ObjectMeta{
 			Name:      getDataNameByPod(instance.Name, podName),
 			Namespace: instance.Namespace,
 			Labels: map[string]string{
-				datav1alpha1.KudaKeyDataSet: instance.Name,
-				datav1alpha1.KudaKeyPod:     podName,
+				datav1alpha1.KudaKeyDataSet:  instance.Name,
+				datav1alpha1.KudaKeyPod:      podName,
+				datav1alpha1.KudaKeyRevision: revision,
 			},
 		},
 		Spec: datav1alpha1.DataSpec{
 			DataItems:   instance.Spec.Template.DataItems,
 			DataSources: instance.Spec.Template.DataSources,
 			Lifecycle:   instance.Spec.Template.Lifecycle,
+			Queued:      isDownloadQueued(r.DownloadLease, instance),
+			Refreshes:   getDataItemRefreshes(instance.Spec.Template.DataItems, time.Now()),
+			Activation:  getDataActivation(instance),
 		},
 	}
 
 	return data
 }
 
+// isDataUpToDate returns true if the data resource is at the current template, whether it
+// holds the download lease or not.
+func (r *DataSetReconciler) isDataUpToDate(instance *datav1alpha1.DataSet, data *datav1alpha1.Data, revision string) bool {
+	spec := r.newDataResource(instance, getPodNameByData(data), revision).Spec
+	spec.Queued = data.Spec.Queued
+	spec.BandwidthLimit = data.Spec.BandwidthLimit
+	return reflect.DeepEqual(data.Spec, spec) && data.Labels[datav1alpha1.KudaKeyRevision] == revision
+}
+
 // updateDataResource will update the data resource if it is not the latest.
-func (r *DataSetReconciler) updateDataResource(ctx context.Context, instance *datav1alpha1.DataSet, dataOld *datav1alpha1.Data) error {
+func (r *DataSetReconciler) updateDataResource(ctx context.Context, instance *datav1alpha1.DataSet, dataOld *datav1alpha1.Data, revision string) error {
 	podName := getPodNameByData(dataOld)
 
-	dataNew := r.newDataResource(instance, podName)
+	dataNew := r.newDataResource(instance, podName, revision)
 
-	if !reflect.DeepEqual(dataOld.Spec, dataNew.Spec) {
+	if !r.isDataUpToDate(instance, dataOld, revision) {
 		dataOld.Spec = dataNew.Spec
+		if dataOld.Labels == nil {
+			dataOld.Labels = make(map[string]string)
+		}
+		dataOld.Labels[datav1alpha1.KudaKeyRevision] = revision
 		if err := r.Update(ctx, dataOld); err != nil {
 			return err
 		}
 		ctrllog.FromContext(ctx).Info("update data resource success", "data.Name", dataOld.Name, "data.Namespace", dataOld.Namespace)
+		r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonUpdatedData, "Updated Data %s for pod %s", dataOld.Name, podName)
+		r.Recorder.Eventf(dataOld, v1.EventTypeNormal, reasonUpdatedData, "Updated spec from DataSet %s", instance.Name)
 	}
 
 	return nil
 }
 
 // pruneDataResources clean up data resource if the pod has been deleted.
-func (r *DataSetReconciler) pruneDataResources(ctx context.Context, dataList *datav1alpha1.DataList, podMap map[string]*v1.Pod) error {
+func (r *DataSetReconciler) pruneDataResources(ctx context.Context, instance *datav1alpha1.DataSet, dataList *datav1alpha1.DataList, podMap map[string]*v1.Pod) error {
 	items := make([]datav1alpha1.Data, 0)
 
 	for _, data := range dataList.Items {
@@ -219,10 +348,12 @@ func (r *DataSetReconciler) pruneDataResources(ctx context.Context, dataList *da
 		if err := r.Delete(ctx, &data); err != nil && !errors.IsNotFound(err) {
 			items = append(items, data)
 			ctrllog.FromContext(ctx).Error(err, "failed to delete data resource", "name", data.Name, "namespace", data.Namespace)
+			r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedPruneData, "Failed to delete Data %s: %v", data.Name, err)
 			return err
 		}
 
 		ctrllog.FromContext(ctx).Info("delete data resource success", "data.Name", data.Name, "data.Namespace", data.Namespace)
+		r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonPrunedData, "Deleted Data %s as pod %s no longer exists", data.Name, podName)
 	}
 
 	dataList.Items = items
@@ -231,20 +362,55 @@ func (r *DataSetReconciler) pruneDataResources(ctx context.Context, dataList *da
 }
 
 // Only when all the data items of an instance are download successfully, the instance is considered to be successful
-func (r *DataSetReconciler) updateDataSetStatus(ctx context.Context, instance *datav1alpha1.DataSet, dataList *datav1alpha1.DataList) error {
+func (r *DataSetReconciler) updateDataSetStatus(ctx context.Context, instance *datav1alpha1.DataSet, dataList *datav1alpha1.DataList, podMap map[string]*v1.Pod, uninjectedPods []*v1.Pod, restarts []datav1alpha1.WorkloadRestart, revision string, prefetch []datav1alpha1.PrefetchNodeStatus, nsQuota *namespaceQuota, window *updateWindow) error {
 	dataItemsNum := len(instance.Spec.Template.DataItems)
 
 	newStatus := datav1alpha1.DataSetStatus{
-		DataItems: dataItemsNum,
-		Replicas:  len(dataList.Items),
+		DataItems:          dataItemsNum,
+		Replicas:           len(dataList.Items),
+		UninjectedReplicas: len(uninjectedPods),
+		CurrentRevision:    revision,
+		ObservedGeneration: instance.Generation,
+		Conditions:         instance.Status.DeepCopy().Conditions,
+		DiscoveredVersions: instance.Status.DiscoveredVersions,
+		Activation:         instance.Status.Activation,
+		Restarts:           restarts,
+	}
+	injected := newInjectedCondition(instance, uninjectedPods, restarts, time.Now())
+	if previous := meta.FindStatusCondition(instance.Status.Conditions, datav1alpha1.DataSetInjected); injected.Reason == reasonRestartNotFixed && (previous == nil || previous.Reason != injected.Reason) {
+		r.Recorder.Event(instance, v1.EventTypeWarning, injected.Reason, injected.Message)
+	}
+	meta.SetStatusCondition(&newStatus.Conditions, injected)
+	if condition := newQuotaCondition(instance, nsQuota); condition != nil {
+		previous := meta.FindStatusCondition(instance.Status.Conditions, datav1alpha1.DataSetWithinQuota)
+		if condition.Status == v12.ConditionFalse && (previous == nil || previous.Reason != condition.Reason) {
+			r.Recorder.Event(instance, v1.EventTypeWarning, condition.Reason, condition.Message)
+		}
+		meta.SetStatusCondition(&newStatus.Conditions, *condition)
+	} else {
+		meta.RemoveStatusCondition(&newStatus.Conditions, datav1alpha1.DataSetWithinQuota)
+	}
+	if condition := newProgressingCondition(instance, window); condition != nil {
+		previous := meta.FindStatusCondition(instance.Status.Conditions, datav1alpha1.DataSetProgressing)
+		if condition.Status == v12.ConditionFalse && (previous == nil || previous.Status != condition.Status) {
+			r.Recorder.Event(instance, v1.EventTypeNormal, condition.Reason, condition.Message)
+		}
+		meta.SetStatusCondition(&newStatus.Conditions, *condition)
+	} else {
+		meta.RemoveStatusCondition(&newStatus.Conditions, datav1alpha1.DataSetProgressing)
 	}
 
 	for _, data := range dataList.Items {
 		if data.Status.Success == dataItemsNum {
 			newStatus.SuccessReplicas += 1
 		}
+		if data.Labels[datav1alpha1.KudaKeyRevision] == revision {
+			newStatus.UpdatedReplicas += 1
+		}
 	}
 	newStatus.Ready = fmt.Sprintf("%d/%d", newStatus.SuccessReplicas, len(dataList.Items))
+	newStatus.Nodes = getNodeDistribution(dataList, podMap, dataItemsNum)
+	newStatus.Prefetch = prefetch
 
 	if !reflect.DeepEqual(newStatus, instance.Status) {
 		instance.Status = newStatus
@@ -257,6 +423,37 @@ func (r *DataSetReconciler) updateDataSetStatus(ctx context.Context, instance *d
 	return nil
 }
 
+// getNodeDistribution returns the replicas on each node, pods not scheduled yet are skipped.
+func getNodeDistribution(dataList *datav1alpha1.DataList, podMap map[string]*v1.Pod, dataItemsNum int) []datav1alpha1.NodeDistribution {
+	distribution := make(map[string]*datav1alpha1.NodeDistribution)
+	for _, data := range dataList.Items {
+		pod, ok := podMap[getPodNameByData(&data)]
+		if !ok || pod.Spec.NodeName == "" {
+			continue
+		}
+		node, ok := distribution[pod.Spec.NodeName]
+		if !ok {
+			node = &datav1alpha1.NodeDistribution{NodeName: pod.Spec.NodeName}
+			distribution[pod.Spec.NodeName] = node
+		}
+		node.Replicas += 1
+		if data.Status.Success == dataItemsNum {
+			node.SuccessReplicas += 1
+		}
+	}
+
+	if len(distribution) == 0 {
+		return nil
+	}
+	nodes := make([]datav1alpha1.NodeDistribution, 0, len(distribution))
+	for _, node := range distribution {
+		nodes = append(nodes, *node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].NodeName < nodes[j].NodeName })
+
+	return nodes
+}
+
 // Get dataset from pod annotations that injected by webhook.
 func (r *DataSetReconciler) getDataSetForPod(object client.Object) (string, bool) {
 	if ds, ok := object.GetAnnotations()[datav1alpha1.KudaKeyDataSet]; ok && ds != "" {
@@ -266,34 +463,127 @@ func (r *DataSetReconciler) getDataSetForPod(object client.Object) (string, bool
 	return "", false
 }
 
+// Get datasets whose workload selector matches the pod, used for the pods missed injection.
+func (r *DataSetReconciler) findDataSetsForPod(object client.Object) []string {
+	dsList := &datav1alpha1.DataSetList{}
+	if err := r.List(context.Background(), dsList, client.InNamespace(object.GetNamespace())); err != nil {
+		ctrllog.Log.Error(err, "failed to list datasets for pod", "pod.Name", object.GetName())
+		return nil
+	}
+
+	names := make([]string, 0)
+	for _, ds := range dsList.Items {
+		if utils.ContainsAll(object.GetLabels(), ds.Spec.WorkloadSelector) {
+			names = append(names, ds.Name)
+		}
+	}
+
+	return names
+}
+
+// isPodWatched returns true if the creation or deletion of the pod may change the datasets,
+// i.e. the pod is injected or served by the kuda CSI driver, or missed injection in a
+// namespace having datasets.
+func (r *DataSetReconciler) isPodWatched(object client.Object) bool {
+	pod, ok := object.(*v1.Pod)
+	if !ok {
+		return false
+	}
+	if _, ok := r.getDataSetForPod(pod); ok || isPodServedByCSI(pod) {
+		return true
+	}
+	if isPodInjected(pod) {
+		return false
+	}
+	_, ok = r.namespaces.Load(pod.Namespace)
+	return ok
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *DataSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Pods missed the injection have no dataset annotation, so their creations and deletions
+	// are mapped to the datasets by the workload selector, only in the namespaces having
+	// datasets. The pods served by the kuda CSI driver are also watched for scheduling, to
+	// prefetch onto their nodes.
 	podPredicates := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
-			if _, exist := r.getDataSetForPod(e.Object); exist {
-				return true
-			}
-			return false
+			return r.isPodWatched(e.Object)
 		},
-		UpdateFunc: func(updateEvent event.UpdateEvent) bool {
-			return false
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, newPod := e.ObjectOld.(*v1.Pod), e.ObjectNew.(*v1.Pod)
+			return oldPod.Spec.NodeName == "" && newPod.Spec.NodeName != "" && isPodServedByCSI(newPod)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
-			if _, exist := r.getDataSetForPod(e.Object); exist {
-				return true
-			}
-			return false
+			return r.isPodWatched(e.Object)
 		},
 	}
 	podHandlers := handler.MapFunc(func(object client.Object) []reconcile.Request {
 		requests := make([]reconcile.Request, 0)
+		if pod, ok := object.(*v1.Pod); ok && isPodServedByCSI(pod) {
+			for _, ds := range getCSIDataSets(pod) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      ds,
+					Namespace: object.GetNamespace(),
+				}})
+			}
+			return requests
+		}
 		if ds, exist := r.getDataSetForPod(object); exist {
 			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
 				Name:      ds,
 				Namespace: object.GetNamespace(),
 			}})
+			return requests
 		}
 
+		if pod, ok := object.(*v1.Pod); !ok || isPodInjected(pod) {
+			return requests
+		}
+		for _, ds := range r.findDataSetsForPod(object) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      ds,
+				Namespace: object.GetNamespace(),
+			}})
+		}
+
+		return requests
+	})
+	// Nodes are selected for prefetch by labels, readiness and schedulability.
+	nodePredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, newNode := e.ObjectOld.(*v1.Node), e.ObjectNew.(*v1.Node)
+			return !reflect.DeepEqual(oldNode.Labels, newNode.Labels) || isNodeAvailable(oldNode) != isNodeAvailable(newNode)
+		},
+	}
+	nodeHandlers := handler.MapFunc(func(object client.Object) []reconcile.Request {
+		requests := make([]reconcile.Request, 0)
+		dsList := &datav1alpha1.DataSetList{}
+		if err := r.List(context.Background(), dsList); err != nil {
+			return requests
+		}
+		for _, ds := range dsList.Items {
+			if ds.Spec.Prefetch != nil || len(ds.Status.Prefetch) > 0 {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      ds.Name,
+					Namespace: ds.Namespace,
+				}})
+			}
+		}
+		return requests
+	})
+	// The datasets held back by the data quotas are delivered once the quotas change.
+	quotaHandlers := handler.MapFunc(func(object client.Object) []reconcile.Request {
+		requests := make([]reconcile.Request, 0)
+		dsList := &datav1alpha1.DataSetList{}
+		if err := r.List(context.Background(), dsList, client.InNamespace(object.GetNamespace())); err != nil {
+			return requests
+		}
+		for _, ds := range dsList.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      ds.Name,
+				Namespace: ds.Namespace,
+			}})
+		}
 		return requests
 	})
 	return ctrl.NewControllerManagedBy(mgr).
@@ -303,21 +593,29 @@ func (r *DataSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			&source.Kind{Type: &v1.Pod{}},
 			handler.EnqueueRequestsFromMapFunc(podHandlers),
 			builder.WithPredicates(podPredicates)).
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(nodeHandlers),
+			builder.WithPredicates(nodePredicates)).
+		Watches(
+			&source.Kind{Type: &datav1alpha1.DataQuota{}},
+			handler.EnqueueRequestsFromMapFunc(quotaHandlers),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
 
 func convertPodListToMap(podList *v1.PodList) map[string]*v1.Pod {
 	podMap := make(map[string]*v1.Pod, podList.Size())
-	for _, pod := range podList.Items {
-		podMap[pod.Name] = &pod
+	for i := range podList.Items {
+		podMap[podList.Items[i].Name] = &podList.Items[i]
 	}
 	return podMap
 }
 
 func convertDataListToMap(dataList *datav1alpha1.DataList) map[string]*datav1alpha1.Data {
 	dataMap := make(map[string]*datav1alpha1.Data, dataList.ListMeta.Size())
-	for _, data := range dataList.Items {
-		dataMap[data.Name] = &data
+	for i := range dataList.Items {
+		dataMap[dataList.Items[i].Name] = &dataList.Items[i]
 	}
 	return dataMap
 }
@@ -25,6 +25,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
@@ -40,14 +41,14 @@ func TestCreateDataResource(t *testing.T) {
 
 	t.Run("create data resource success", func(t *testing.T) {
 		dataset := getTestDataSet(datasetName, dataItemName)
-		data, err := testDataSetReconciler.createDataResource(context.Background(), dataset, podName)
+		data, err := testDataSetReconciler.createDataResource(context.Background(), dataset, podName, "test-ds-v1")
 		assert.NoError(t, err)
 		assert.Equal(t, getDataNameByPod(datasetName, podName), data.Name)
 	})
 
 	t.Run("create data resource when it already exists", func(t *testing.T) {
 		dataset := getTestDataSet(datasetName, dataItemName)
-		_, err := testDataSetReconciler.createDataResource(context.Background(), dataset, podName)
+		_, err := testDataSetReconciler.createDataResource(context.Background(), dataset, podName, "test-ds-v1")
 		assert.NoError(t, err)
 	})
 }
@@ -68,13 +69,13 @@ func TestUpdateDataResource(t *testing.T) {
 		assert.NoError(t, err)
 
 		data.Spec.DataItems[0].LocalPath = "/local/test.conf"
-		err := testDataSetReconciler.updateDataResource(context.Background(), dataset, data)
+		err := testDataSetReconciler.updateDataResource(context.Background(), dataset, data, "test-ds-v1")
 		assert.NoError(t, err)
 	})
 	t.Run("update data resource when it not changes", func(t *testing.T) {
 		dataset := getTestDataSet(datasetName, dataItemName)
 		data := getTestData(datasetName, dataItemName, podName)
-		err := testDataSetReconciler.updateDataResource(context.Background(), dataset, data)
+		err := testDataSetReconciler.updateDataResource(context.Background(), dataset, data, "test-ds-v1")
 		assert.NoError(t, err)
 	})
 }
@@ -100,7 +101,7 @@ func TestPruneDataResources(t *testing.T) {
 			},
 		}
 
-		err := testDataSetReconciler.pruneDataResources(context.Background(), dataList, podMap)
+		err := testDataSetReconciler.pruneDataResources(context.Background(), getTestDataSet(datasetName, dataItemName), dataList, podMap)
 		assert.NoError(t, err)
 	})
 
@@ -111,7 +112,7 @@ func TestPruneDataResources(t *testing.T) {
 		}
 		podMap := map[string]*v12.Pod{}
 
-		err := testDataSetReconciler.pruneDataResources(context.Background(), dataList, podMap)
+		err := testDataSetReconciler.pruneDataResources(context.Background(), getTestDataSet(datasetName, dataItemName), dataList, podMap)
 		assert.NoError(t, err)
 	})
 }
@@ -130,6 +131,7 @@ func getTestDataSetReconciler() (*DataSetReconciler, error) {
 
 	cli := fake.NewClientBuilder().WithScheme(s).Build()
 	dsReconciler.Client = cli
+	dsReconciler.Recorder = record.NewFakeRecorder(100)
 
 	return dsReconciler, nil
 }
@@ -164,7 +166,8 @@ func getTestData(datasetName, dataItemName, podName string) *v1alpha1.Data {
 		ObjectMeta: v1.ObjectMeta{
 			Name: getDataNameByPod(datasetName, podName),
 			Labels: map[string]string{
-				v1alpha1.KudaKeyPod: podName,
+				v1alpha1.KudaKeyPod:      podName,
+				v1alpha1.KudaKeyRevision: "test-ds-v1",
 			},
 		},
 		Spec: v1alpha1.DataSpec{
@@ -0,0 +1,393 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/quota"
+)
+
+const (
+	reasonLeaseGranted = "DownloadLeaseGranted"
+
+	dataSourceTypeHdfs    = "hdfs"
+	dataSourceTypeAlluxio = "alluxio"
+
+	// the only request of the download lease controller, the leases are handed out across
+	// all the data resources of the cluster at once.
+	downloadLeaseRequestName = "download-leases"
+)
+
+// DownloadLeaseConfig describes the limits of the concurrent downloads, zero means unlimited.
+type DownloadLeaseConfig struct {
+	// MaxPerDataSet is the max concurrent downloads of a DataSet, which can be overridden by
+	// the download policy of the DataSet.
+	MaxPerDataSet int
+	// MaxPerSource is the max concurrent downloads from a data source, e.g. an HDFS cluster.
+	MaxPerSource int
+	// MaxPerNode is the max concurrent downloads onto a node.
+	MaxPerNode int
+	// BandwidthPerSource and BandwidthPerNode are the bytes per second of a data source and
+	// a node shared by the downloads, which are handed out to the kuda runtimes with the
+	// leases as the bandwidth limits.
+	BandwidthPerSource int64
+	BandwidthPerNode   int64
+}
+
+// isEnabled returns true if the downloads are limited by the manager.
+func (c DownloadLeaseConfig) isEnabled() bool {
+	return c.MaxPerDataSet > 0 || c.MaxPerSource > 0 || c.MaxPerNode > 0 ||
+		c.BandwidthPerSource > 0 || c.BandwidthPerNode > 0
+}
+
+// isDownloadQueued returns true if the data resources of the dataset wait for the download
+// leases before downloading.
+func isDownloadQueued(config DownloadLeaseConfig, instance *datav1alpha1.DataSet) bool {
+	return config.isEnabled() || (instance.Spec.Download != nil && instance.Spec.Download.MaxConcurrent != nil)
+}
+
+// DownloadLeaseReconciler hands out the download leases to the queued data resources, so
+// that a DataSet change doesn't make all its runtimes download at once and flood the data
+// sources. The lease is held by the data resource until all its data items are done.
+type DownloadLeaseReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Config   DownloadLeaseConfig
+}
+
+//+kubebuilder:rbac:groups=data.kuda.io,resources=datas,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=data.kuda.io,resources=datasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=scheduling.k8s.io,resources=priorityclasses,verbs=get;list;watch
+
+// leaseCandidate is a data resource with what its download is limited by.
+type leaseCandidate struct {
+	data     *datav1alpha1.Data
+	dataset  string
+	node     string
+	sources  []string
+	priority int32
+}
+
+// Reconcile hands out the leases to the queued data resources by priority, and then by the
+// creation time, within the limits of their DataSets, data sources and nodes.
+func (r *DownloadLeaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
+	dataList := &datav1alpha1.DataList{}
+	if err := r.List(ctx, dataList); err != nil {
+		log.Error(err, "failed to list data resource")
+		return ctrl.Result{}, err
+	}
+	dsList := &datav1alpha1.DataSetList{}
+	if err := r.List(ctx, dsList); err != nil {
+		log.Error(err, "failed to list datasets")
+		return ctrl.Result{}, err
+	}
+	priorities, err := r.getPriorities(ctx)
+	if err != nil {
+		log.Error(err, "failed to list priority classes")
+		return ctrl.Result{}, err
+	}
+
+	policies := make(map[string]*datav1alpha1.DownloadPolicy, len(dsList.Items))
+	for _, ds := range dsList.Items {
+		policies[ds.Namespace+"/"+ds.Name] = ds.Spec.Download
+	}
+
+	leases := newLeaseCounter()
+	queued := make([]*leaseCandidate, 0)
+	for i := range dataList.Items {
+		data := &dataList.Items[i]
+		if data.GetDeletionTimestamp() != nil || !quota.IsDownloading(data) {
+			continue
+		}
+		candidate, err := r.newLeaseCandidate(ctx, data, policies, priorities)
+		if err != nil {
+			log.Error(err, "failed to get the node of data resource", "data.Name", data.Name)
+			return ctrl.Result{}, err
+		}
+		if data.Spec.Queued {
+			queued = append(queued, candidate)
+		} else {
+			leases.add(candidate)
+		}
+	}
+
+	sort.SliceStable(queued, func(i, j int) bool {
+		if queued[i].priority != queued[j].priority {
+			return queued[i].priority > queued[j].priority
+		}
+		ti, tj := queued[i].data.CreationTimestamp, queued[j].data.CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return queued[i].data.Namespace+"/"+queued[i].data.Name < queued[j].data.Namespace+"/"+queued[j].data.Name
+	})
+	for _, candidate := range queued {
+		if !r.isLeaseAvailable(leases, candidate, policies[candidate.dataset]) {
+			continue
+		}
+		candidate.data.Spec.Queued = false
+		candidate.data.Spec.BandwidthLimit = r.getBandwidthLimit(leases, candidate)
+		if err := r.Update(ctx, candidate.data); err != nil {
+			if errors.IsNotFound(err) || errors.IsConflict(err) {
+				// The data resource is changed and will be handled in the next round.
+				continue
+			}
+			log.Error(err, "failed to grant download lease", "data.Name", candidate.data.Name)
+			return ctrl.Result{}, err
+		}
+		leases.add(candidate)
+		r.Recorder.Event(candidate.data, v1.EventTypeNormal, reasonLeaseGranted, "Granted the download lease")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getPriorities returns the values of the priority classes, with the global default one
+// under the empty name.
+func (r *DownloadLeaseReconciler) getPriorities(ctx context.Context) (map[string]int32, error) {
+	classList := &schedulingv1.PriorityClassList{}
+	if err := r.List(ctx, classList); err != nil {
+		return nil, err
+	}
+	priorities := make(map[string]int32, len(classList.Items)+1)
+	for _, class := range classList.Items {
+		priorities[class.Name] = class.Value
+		if class.GlobalDefault {
+			priorities[""] = class.Value
+		}
+	}
+	return priorities, nil
+}
+
+// newLeaseCandidate returns the candidate of the data resource. The node is known from the
+// label of the prefetch data, or from the pod otherwise, which is empty until scheduled.
+func (r *DownloadLeaseReconciler) newLeaseCandidate(ctx context.Context, data *datav1alpha1.Data, policies map[string]*datav1alpha1.DownloadPolicy, priorities map[string]int32) (*leaseCandidate, error) {
+	candidate := &leaseCandidate{
+		data:    data,
+		node:    data.Labels[datav1alpha1.KudaKeyNode],
+		sources: getDataSourceKeys(&data.Spec),
+	}
+	if ds := data.Labels[datav1alpha1.KudaKeyDataSet]; ds != "" {
+		candidate.dataset = data.Namespace + "/" + ds
+	} else if ds := data.Labels[datav1alpha1.KudaKeyPrefetch]; ds != "" {
+		candidate.dataset = data.Namespace + "/" + ds
+	}
+
+	className := ""
+	if policy := policies[candidate.dataset]; policy != nil {
+		className = policy.PriorityClassName
+	}
+	candidate.priority = priorities[className]
+
+	if candidate.node == "" && (r.Config.MaxPerNode > 0 || r.Config.BandwidthPerNode > 0) {
+		pod := &v1.Pod{}
+		err := r.Get(ctx, types.NamespacedName{Name: getPodNameByData(data), Namespace: data.Namespace}, pod)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		candidate.node = pod.Spec.NodeName
+	}
+
+	return candidate, nil
+}
+
+// isLeaseAvailable returns true if the candidate is within all the limits. The pods not
+// scheduled yet wait if the downloads onto nodes are limited.
+func (r *DownloadLeaseReconciler) isLeaseAvailable(leases *leaseCounter, candidate *leaseCandidate, policy *datav1alpha1.DownloadPolicy) bool {
+	maxPerDataSet := r.Config.MaxPerDataSet
+	if policy != nil && policy.MaxConcurrent != nil {
+		maxPerDataSet = int(*policy.MaxConcurrent)
+	}
+	if maxPerDataSet > 0 && leases.datasets[candidate.dataset] >= maxPerDataSet {
+		return false
+	}
+	if r.Config.MaxPerNode > 0 && (candidate.node == "" || leases.nodes[candidate.node] >= r.Config.MaxPerNode) {
+		return false
+	}
+	if r.Config.MaxPerSource > 0 {
+		for _, source := range candidate.sources {
+			if leases.sources[source] >= r.Config.MaxPerSource {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// getBandwidthLimit returns the share of the bandwidth of the data sources and the node of
+// the candidate, the least one of which limits its download. The bandwidth is divided by
+// the max concurrent downloads if limited, so that the downloads within the limit never
+// exceed it together, or by the leases held including the candidate otherwise.
+func (r *DownloadLeaseReconciler) getBandwidthLimit(leases *leaseCounter, candidate *leaseCandidate) *resource.Quantity {
+	var limit int64
+	share := func(bandwidth int64, max, held int) {
+		if bandwidth <= 0 {
+			return
+		}
+		if max <= 0 {
+			max = held + 1
+		}
+		if s := bandwidth / int64(max); limit == 0 || s < limit {
+			limit = s
+		}
+	}
+	for _, source := range candidate.sources {
+		share(r.Config.BandwidthPerSource, r.Config.MaxPerSource, leases.sources[source])
+	}
+	if candidate.node != "" {
+		share(r.Config.BandwidthPerNode, r.Config.MaxPerNode, leases.nodes[candidate.node])
+	}
+
+	if limit <= 0 {
+		return nil
+	}
+	return resource.NewQuantity(limit, resource.BinarySI)
+}
+
+// leaseCounter counts the leases held by the DataSets, the data sources and the nodes.
+type leaseCounter struct {
+	datasets map[string]int
+	sources  map[string]int
+	nodes    map[string]int
+}
+
+func newLeaseCounter() *leaseCounter {
+	return &leaseCounter{
+		datasets: make(map[string]int),
+		sources:  make(map[string]int),
+		nodes:    make(map[string]int),
+	}
+}
+
+func (c *leaseCounter) add(candidate *leaseCandidate) {
+	c.datasets[candidate.dataset] += 1
+	if candidate.node != "" {
+		c.nodes[candidate.node] += 1
+	}
+	for _, source := range candidate.sources {
+		c.sources[source] += 1
+	}
+}
+
+// getDataSourceKeys returns the data sources the data items are downloaded from, which are
+// identified by their addresses.
+func getDataSourceKeys(spec *datav1alpha1.DataSpec) []string {
+	if spec.DataSources == nil {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, item := range spec.DataItems {
+		used[item.DataSourceType] = true
+	}
+	keys := make([]string, 0, len(used))
+	if hdfs := spec.DataSources.Hdfs; hdfs != nil && used[dataSourceTypeHdfs] {
+		addresses := append([]string{}, hdfs.Addresses...)
+		sort.Strings(addresses)
+		keys = append(keys, fmt.Sprintf("%s/%s", dataSourceTypeHdfs, strings.Join(addresses, ",")))
+	}
+	if alluxio := spec.DataSources.Alluxio; alluxio != nil && used[dataSourceTypeAlluxio] {
+		keys = append(keys, fmt.Sprintf("%s/%s:%d", dataSourceTypeAlluxio, alluxio.Host, alluxio.Port))
+	}
+	return keys
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DownloadLeaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	c, err := controller.New("downloadlease", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// All the changes are mapped to the only request, so that the leases are handed out
+	// once for a burst of changes.
+	leaseHandlers := handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: downloadLeaseRequestName}}}
+	})
+	// Leases are handed out when the data resources are queued, and taken back when they
+	// are done or deleted.
+	dataPredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldData, newData := e.ObjectOld.(*datav1alpha1.Data), e.ObjectNew.(*datav1alpha1.Data)
+			return oldData.Spec.Queued != newData.Spec.Queued || quota.IsDownloading(oldData) != quota.IsDownloading(newData)
+		},
+	}
+	if err := c.Watch(&source.Kind{Type: &datav1alpha1.Data{}}, leaseHandlers, dataPredicates); err != nil {
+		return err
+	}
+	dataSetPredicates := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldDataSet, newDataSet := e.ObjectOld.(*datav1alpha1.DataSet), e.ObjectNew.(*datav1alpha1.DataSet)
+			return !reflect.DeepEqual(oldDataSet.Spec.Download, newDataSet.Spec.Download)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
+	if err := c.Watch(&source.Kind{Type: &datav1alpha1.DataSet{}}, leaseHandlers, dataSetPredicates); err != nil {
+		return err
+	}
+	if r.Config.MaxPerNode > 0 {
+		// The pods wait for the leases until scheduled.
+		podPredicates := predicate.Funcs{
+			CreateFunc: func(e event.CreateEvent) bool {
+				return false
+			},
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				oldPod, newPod := e.ObjectOld.(*v1.Pod), e.ObjectNew.(*v1.Pod)
+				return oldPod.Spec.NodeName == "" && newPod.Spec.NodeName != "" && isPodInjected(newPod)
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				return false
+			},
+		}
+		if err := c.Watch(&source.Kind{Type: &v1.Pod{}}, leaseHandlers, podPredicates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func newTestQueuedData(dataSetName, podName, nodeName string, queued bool, created time.Time) []client.Object {
+	data := getTestData(dataSetName, "model", podName)
+	data.Namespace = "default"
+	data.Labels[v1alpha1.KudaKeyDataSet] = dataSetName
+	data.CreationTimestamp = v1.NewTime(created)
+	data.Spec.Queued = queued
+
+	pod := getTestPod(podName, true)
+	pod.Namespace = "default"
+	pod.Spec.NodeName = nodeName
+	return []client.Object{data, &pod}
+}
+
+func TestDownloadLeaseReconcile(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+	r := &DownloadLeaseReconciler{
+		Client:   testDataSetReconciler.Client,
+		Scheme:   testDataSetReconciler.Scheme,
+		Recorder: testDataSetReconciler.Recorder,
+		Config:   DownloadLeaseConfig{MaxPerSource: 2, MaxPerNode: 1},
+	}
+
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	model := getTestDataSet("model-ds", "model")
+	model.Namespace = "default"
+	model.Spec.Download = &v1alpha1.DownloadPolicy{PriorityClassName: "high"}
+	dict := getTestDataSet("dict-ds", "model")
+	dict.Namespace = "default"
+	dict.Spec.Download = &v1alpha1.DownloadPolicy{MaxConcurrent: pointer.Int32Ptr(2)}
+	objects := []client.Object{
+		&schedulingv1.PriorityClass{ObjectMeta: v1.ObjectMeta{Name: "high"}, Value: 1000},
+		model,
+		dict,
+	}
+	objects = append(objects, newTestQueuedData("dict-ds", "dict-1", "node-1", false, now)...)
+	objects = append(objects, newTestQueuedData("dict-ds", "dict-2", "node-2", true, now.Add(time.Second))...)
+	objects = append(objects, newTestQueuedData("model-ds", "model-1", "node-1", true, now.Add(2*time.Second))...)
+	objects = append(objects, newTestQueuedData("model-ds", "model-2", "node-3", true, now.Add(3*time.Second))...)
+	objects = append(objects, newTestQueuedData("model-ds", "model-3", "", true, now)...)
+	for _, object := range objects {
+		assert.NoError(t, r.Create(ctx, object))
+	}
+
+	isQueued := func(dataSetName, podName string) bool {
+		data := &v1alpha1.Data{}
+		assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: getDataNameByPod(dataSetName, podName), Namespace: "default"}, data))
+		return data.Spec.Queued
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: downloadLeaseRequestName}}
+
+	// The higher priority one takes the last lease of the data source, the one on a busy
+	// node and the one not scheduled yet wait.
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.True(t, isQueued("dict-ds", "dict-2"))
+	assert.True(t, isQueued("model-ds", "model-1"))
+	assert.False(t, isQueued("model-ds", "model-2"))
+	assert.True(t, isQueued("model-ds", "model-3"))
+
+	// The lease is taken back once the download completes.
+	data := &v1alpha1.Data{}
+	assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: getDataNameByPod("dict-ds", "dict-1"), Namespace: "default"}, data))
+	item := data.Spec.DataItems[0]
+	data.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+		{Name: item.Name, Namespace: item.Namespace, Version: item.Version, Phase: v1alpha1.DataSuccess},
+	}
+	assert.NoError(t, r.Status().Update(ctx, data))
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.True(t, isQueued("dict-ds", "dict-2"))
+	assert.False(t, isQueued("model-ds", "model-1"))
+	assert.True(t, isQueued("model-ds", "model-3"))
+}
+
+func TestIsLeaseAvailable(t *testing.T) {
+	r := &DownloadLeaseReconciler{Config: DownloadLeaseConfig{MaxPerDataSet: 1}}
+	leases := newLeaseCounter()
+	held := &leaseCandidate{dataset: "default/model-ds", sources: []string{"hdfs/localhost:8020"}}
+	leases.add(held)
+
+	candidate := &leaseCandidate{dataset: "default/model-ds", sources: []string{"hdfs/localhost:8020"}}
+	assert.False(t, r.isLeaseAvailable(leases, candidate, nil))
+	// The policy of the dataset overrides the limit of the manager.
+	assert.True(t, r.isLeaseAvailable(leases, candidate, &v1alpha1.DownloadPolicy{MaxConcurrent: pointer.Int32Ptr(2)}))
+	assert.True(t, r.isLeaseAvailable(leases, candidate, &v1alpha1.DownloadPolicy{MaxConcurrent: pointer.Int32Ptr(0)}))
+	assert.True(t, r.isLeaseAvailable(leases, &leaseCandidate{dataset: "default/dict-ds"}, nil))
+}
+
+func TestGetBandwidthLimit(t *testing.T) {
+	r := &DownloadLeaseReconciler{Config: DownloadLeaseConfig{BandwidthPerSource: 1000, BandwidthPerNode: 300}}
+	leases := newLeaseCounter()
+	leases.add(&leaseCandidate{dataset: "default/model-ds", node: "node-1", sources: []string{"hdfs/localhost:8020"}})
+
+	// The bandwidth is shared by the leases held including the candidate.
+	candidate := &leaseCandidate{dataset: "default/model-ds", node: "node-2", sources: []string{"hdfs/localhost:8020"}}
+	assert.Equal(t, resource.NewQuantity(300, resource.BinarySI), r.getBandwidthLimit(leases, candidate))
+	candidate.node = "node-1"
+	assert.Equal(t, resource.NewQuantity(150, resource.BinarySI), r.getBandwidthLimit(leases, candidate))
+
+	// The bandwidth is divided by the max concurrent downloads if limited.
+	r.Config.MaxPerSource = 10
+	candidate.node = ""
+	assert.Equal(t, resource.NewQuantity(100, resource.BinarySI), r.getBandwidthLimit(leases, candidate))
+
+	r.Config = DownloadLeaseConfig{MaxPerSource: 2}
+	assert.Nil(t, r.getBandwidthLimit(leases, candidate))
+}
+
+func TestGetDataSourceKeys(t *testing.T) {
+	data := getTestData("test-ds", "model", "test-pod")
+	data.Spec.DataSources.Hdfs.Addresses = []string{"nn-2:8020", "nn-1:8020"}
+	data.Spec.DataSources.Alluxio = &v1alpha1.AlluxioDataSource{Host: "alluxio", Port: 19998}
+	assert.Equal(t, []string{"hdfs/nn-1:8020,nn-2:8020"}, getDataSourceKeys(&data.Spec))
+
+	data.Spec.DataItems[0].DataSourceType = dataSourceTypeAlluxio
+	assert.Equal(t, []string{"alluxio/alluxio:19998"}, getDataSourceKeys(&data.Spec))
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	reasonPodsInjected    = "PodsInjected"
+	reasonPodsNotInjected = "PodsNotInjected"
+	reasonMissingInject   = "MissingInjection"
+	reasonRestartWorkload = "RestartWorkload"
+	reasonRestartNotFixed = "RestartNotEffective"
+
+	// max number of pod names shown in the condition message.
+	maxPodNamesInMessage = 5
+
+	// restartBackoff is the time waited after restarting a workload before it's restarted
+	// again, and maxRestartAttempts is the number of the restarts before giving up, e.g. the
+	// webhook keeps down.
+	restartBackoff     = 10 * time.Minute
+	maxRestartAttempts = 3
+)
+
+// isPodInjected returns true if the kuda runtime container has been injected to the pod.
+func isPodInjected(pod *v1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == datav1alpha1.KudaRuntimeContainerName {
+			return true
+		}
+	}
+	return false
+}
+
+// filterInjectedPods splits the pod list into the injected pods and the pods missed injection.
+// Pods being deleted are not considered as missed injection, and the pods served by the kuda
+// CSI driver are in neither of them.
+func filterInjectedPods(podList *v1.PodList) (*v1.PodList, []*v1.Pod) {
+	injected := &v1.PodList{Items: make([]v1.Pod, 0, len(podList.Items))}
+	uninjected := make([]*v1.Pod, 0)
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if isPodServedByCSI(pod) {
+			continue
+		}
+		if isPodInjected(pod) {
+			injected.Items = append(injected.Items, *pod)
+			continue
+		}
+		if pod.GetDeletionTimestamp() == nil {
+			uninjected = append(uninjected, pod)
+		}
+	}
+
+	return injected, uninjected
+}
+
+// newInjectedCondition returns the Injected condition by the pods missed injection and the
+// restarts of their workloads.
+func newInjectedCondition(instance *datav1alpha1.DataSet, uninjectedPods []*v1.Pod, restarts []datav1alpha1.WorkloadRestart, now time.Time) metav1.Condition {
+	if len(uninjectedPods) == 0 {
+		return metav1.Condition{
+			Type:               datav1alpha1.DataSetInjected,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonPodsInjected,
+			Message:            "all pods are injected with the kuda runtime",
+			ObservedGeneration: instance.Generation,
+		}
+	}
+
+	names := make([]string, 0, maxPodNamesInMessage)
+	for i, pod := range uninjectedPods {
+		if i >= maxPodNamesInMessage {
+			names = append(names, "...")
+			break
+		}
+		names = append(names, pod.Name)
+	}
+
+	condition := metav1.Condition{
+		Type:               datav1alpha1.DataSetInjected,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonPodsNotInjected,
+		Message:            fmt.Sprintf("%d pod(s) missed the kuda runtime injection: %s", len(uninjectedPods), strings.Join(names, ", ")),
+		ObservedGeneration: instance.Generation,
+	}
+	workloads := make([]string, 0)
+	for _, restart := range restarts {
+		if isRestartExhausted(restart, now) {
+			workloads = append(workloads, restart.Kind+"/"+restart.Name)
+		}
+	}
+	if len(workloads) > 0 {
+		condition.Reason = reasonRestartNotFixed
+		condition.Message += fmt.Sprintf("; restart did not fix injection of %s", strings.Join(workloads, ", "))
+	}
+	return condition
+}
+
+// isRestartExhausted returns true if the workload is not restarted anymore, i.e. its pods
+// still missed injection after the last attempt.
+func isRestartExhausted(restart datav1alpha1.WorkloadRestart, now time.Time) bool {
+	return restart.Attempts >= maxRestartAttempts && !now.Before(restart.LastRestartTime.Add(restartBackoff))
+}
+
+// getRestartRequeueAfter returns the time after which the workloads restarted are checked
+// again, it's 0 if none is waiting for the backoff.
+func getRestartRequeueAfter(instance *datav1alpha1.DataSet, now time.Time) time.Duration {
+	var requeueAfter time.Duration
+	for _, restart := range instance.Status.Restarts {
+		if isRestartExhausted(restart, now) {
+			continue
+		}
+		after := restart.LastRestartTime.Add(restartBackoff).Sub(now)
+		if after > 0 && (requeueAfter == 0 || after < requeueAfter) {
+			requeueAfter = after
+		}
+	}
+	return requeueAfter
+}
+
+// handleUninjectedPods reports the pods missed injection, and restarts the owning
+// workloads if the MissingInjectionPolicy of the dataset is Restart. The restarts of the
+// workloads still missed injection are returned to be recorded in the status, a workload
+// is restarted at most maxRestartAttempts times, once per restartBackoff.
+func (r *DataSetReconciler) handleUninjectedPods(ctx context.Context, instance *datav1alpha1.DataSet, uninjectedPods []*v1.Pod, now time.Time) ([]datav1alpha1.WorkloadRestart, error) {
+	// Only record events when the pods missed injection changed, to avoid flooding
+	// events on every reconciliation.
+	if len(uninjectedPods) != instance.Status.UninjectedReplicas {
+		for _, pod := range uninjectedPods {
+			r.Recorder.Eventf(pod, v1.EventTypeWarning, reasonMissingInject,
+				"Pod matches DataSet %s but has no %s container", instance.Name, datav1alpha1.KudaRuntimeContainerName)
+		}
+		if len(uninjectedPods) > 0 {
+			r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonPodsNotInjected,
+				"%d pod(s) missed the kuda runtime injection", len(uninjectedPods))
+		}
+	}
+
+	if instance.Spec.MissingInjectionPolicy != datav1alpha1.MissingInjectionRestart {
+		return nil, nil
+	}
+
+	previous := make(map[string]datav1alpha1.WorkloadRestart, len(instance.Status.Restarts))
+	for _, restart := range instance.Status.Restarts {
+		previous[restart.Kind+"/"+restart.Name] = restart
+	}
+	restarts := make([]datav1alpha1.WorkloadRestart, 0)
+	handled := make(map[string]bool)
+	for _, pod := range uninjectedPods {
+		workload, err := r.getWorkloadForPod(ctx, pod)
+		if err != nil {
+			return nil, err
+		}
+		if workload == nil {
+			ctrllog.FromContext(ctx).Info("no workload to restart for the pod missed injection", "pod.Name", pod.Name)
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", getWorkloadKind(workload), workload.GetName())
+		if handled[key] {
+			continue
+		}
+		handled[key] = true
+
+		restart, ok := previous[key]
+		if !ok {
+			restart = datav1alpha1.WorkloadRestart{Kind: getWorkloadKind(workload), Name: workload.GetName()}
+		}
+		if ok && (restart.Attempts >= maxRestartAttempts || now.Before(restart.LastRestartTime.Add(restartBackoff))) {
+			restarts = append(restarts, restart)
+			continue
+		}
+		restarted, err := r.restartWorkload(ctx, instance, workload, pod, now)
+		if err != nil {
+			return nil, err
+		}
+		if restarted {
+			restart.Attempts++
+			restart.LastRestartTime = metav1.NewTime(now)
+		}
+		if restart.Attempts > 0 {
+			restarts = append(restarts, restart)
+		}
+	}
+
+	if len(restarts) == 0 {
+		return nil, nil
+	}
+	return restarts, nil
+}
+
+// restartWorkload triggers a rolling restart of the workload by updating the annotation
+// of its pod template, just like `kubectl rollout restart`, and returns true if restarted.
+// Workloads already restarted after the creation of the pod are skipped.
+func (r *DataSetReconciler) restartWorkload(ctx context.Context, instance *datav1alpha1.DataSet, workload client.Object, pod *v1.Pod, now time.Time) (bool, error) {
+	template := getPodTemplate(workload)
+	if template == nil {
+		return false, nil
+	}
+
+	if v, ok := template.Annotations[datav1alpha1.KudaKeyRestartedAt]; ok {
+		if t, err := time.Parse(time.RFC3339, v); err == nil && !t.Before(pod.CreationTimestamp.Time) {
+			return false, nil
+		}
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[datav1alpha1.KudaKeyRestartedAt] = now.Format(time.RFC3339)
+	if err := r.Patch(ctx, workload, patch); err != nil {
+		return false, err
+	}
+
+	r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonRestartWorkload,
+		"Restart %s %s to inject the kuda runtime into pod %s", getWorkloadKind(workload), workload.GetName(), pod.Name)
+	ctrllog.FromContext(ctx).Info("restart workload for the pod missed injection", "workload", workload.GetName(), "pod.Name", pod.Name)
+
+	return true, nil
+}
+
+// getWorkloadForPod returns the Deployment, StatefulSet or DaemonSet controlling the pod.
+func (r *DataSetReconciler) getWorkloadForPod(ctx context.Context, pod *v1.Pod) (client.Object, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil, nil
+	}
+
+	var workload client.Object
+	switch ref.Kind {
+	case "ReplicaSet":
+		rs := &appsv1.ReplicaSet{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: pod.Namespace}, rs); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		ref = metav1.GetControllerOf(rs)
+		if ref == nil || ref.Kind != "Deployment" {
+			return nil, nil
+		}
+		workload = &appsv1.Deployment{}
+	case "StatefulSet":
+		workload = &appsv1.StatefulSet{}
+	case "DaemonSet":
+		workload = &appsv1.DaemonSet{}
+	default:
+		return nil, nil
+	}
+
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: pod.Namespace}, workload); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return workload, nil
+}
+
+func getPodTemplate(workload client.Object) *v1.PodTemplateSpec {
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template
+	}
+	return nil
+}
+
+func getWorkloadKind(workload client.Object) string {
+	switch workload.(type) {
+	case *appsv1.Deployment:
+		return "Deployment"
+	case *appsv1.StatefulSet:
+		return "StatefulSet"
+	case *appsv1.DaemonSet:
+		return "DaemonSet"
+	}
+	return ""
+}
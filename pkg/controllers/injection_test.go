@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestFilterInjectedPods(t *testing.T) {
+	podList := &v12.PodList{
+		Items: []v12.Pod{
+			getTestPod("injected", true),
+			getTestPod("uninjected", false),
+		},
+	}
+
+	injected, uninjected := filterInjectedPods(podList)
+	assert.Len(t, injected.Items, 1)
+	assert.Equal(t, "injected", injected.Items[0].Name)
+	assert.Len(t, uninjected, 1)
+	assert.Equal(t, "uninjected", uninjected[0].Name)
+
+	now := time.Now()
+	cond := newInjectedCondition(&v1alpha1.DataSet{}, uninjected, nil, now)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, reasonPodsNotInjected, cond.Reason)
+	assert.Contains(t, cond.Message, "uninjected")
+
+	// The restarts of the workload did not fix the injection.
+	restarts := []v1alpha1.WorkloadRestart{{Kind: "Deployment", Name: "test", Attempts: maxRestartAttempts, LastRestartTime: v1.NewTime(now.Add(-time.Minute))}}
+	cond = newInjectedCondition(&v1alpha1.DataSet{}, uninjected, restarts, now)
+	assert.Equal(t, reasonPodsNotInjected, cond.Reason)
+	cond = newInjectedCondition(&v1alpha1.DataSet{}, uninjected, restarts, now.Add(restartBackoff))
+	assert.Equal(t, reasonRestartNotFixed, cond.Reason)
+	assert.Contains(t, cond.Message, "restart did not fix injection of Deployment/test")
+}
+
+func TestHandleUninjectedPods(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	deployment := &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{Name: "test-deploy", Namespace: "default"},
+	}
+	assert.NoError(t, testDataSetReconciler.Create(ctx, deployment))
+
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-deploy-6b474476c4",
+			Namespace: "default",
+			OwnerReferences: []v1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: deployment.Name, Controller: pointer.BoolPtr(true)},
+			},
+		},
+	}
+	assert.NoError(t, testDataSetReconciler.Create(ctx, rs))
+
+	pod := getTestPod("test-deploy-6b474476c4-wrn2x", false)
+	pod.Namespace = "default"
+	pod.CreationTimestamp = v1.NewTime(time.Now().Add(-time.Minute))
+	pod.OwnerReferences = []v1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, Controller: pointer.BoolPtr(true)},
+	}
+
+	t.Run("ignore pods missed injection by default", func(t *testing.T) {
+		dataset := getTestDataSet("test-ds", "test-data")
+		restarts, err := testDataSetReconciler.handleUninjectedPods(ctx, dataset, []*v12.Pod{&pod}, time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, restarts)
+
+		found := &appsv1.Deployment{}
+		assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: deployment.Name, Namespace: "default"}, found))
+		assert.NotContains(t, found.Spec.Template.Annotations, v1alpha1.KudaKeyRestartedAt)
+	})
+
+	t.Run("restart the workload of pods missed injection", func(t *testing.T) {
+		dataset := getTestDataSet("test-ds", "test-data")
+		dataset.Spec.MissingInjectionPolicy = v1alpha1.MissingInjectionRestart
+		now := time.Now()
+		restarts, err := testDataSetReconciler.handleUninjectedPods(ctx, dataset, []*v12.Pod{&pod}, now)
+		assert.NoError(t, err)
+		if assert.Len(t, restarts, 1) {
+			assert.Equal(t, "Deployment", restarts[0].Kind)
+			assert.Equal(t, 1, restarts[0].Attempts)
+		}
+
+		found := &appsv1.Deployment{}
+		assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: deployment.Name, Namespace: "default"}, found))
+		assert.Contains(t, found.Spec.Template.Annotations, v1alpha1.KudaKeyRestartedAt)
+	})
+
+	t.Run("back off the restarts not fixing injection", func(t *testing.T) {
+		dataset := getTestDataSet("test-ds", "test-data")
+		dataset.Spec.MissingInjectionPolicy = v1alpha1.MissingInjectionRestart
+		now := time.Now().Add(time.Hour)
+		dataset.Status.Restarts = []v1alpha1.WorkloadRestart{{Kind: "Deployment", Name: deployment.Name, Attempts: 1, LastRestartTime: v1.NewTime(now.Add(-time.Minute))}}
+		// The pod recreated by the restart still missed injection, e.g. the webhook is down.
+		recreated := pod.DeepCopy()
+		recreated.CreationTimestamp = v1.NewTime(now)
+
+		// The workload is not restarted again within the backoff.
+		restarts, err := testDataSetReconciler.handleUninjectedPods(ctx, dataset, []*v12.Pod{recreated}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, dataset.Status.Restarts, restarts)
+		assert.Equal(t, restartBackoff-time.Minute, getRestartRequeueAfter(dataset, now))
+
+		now = now.Add(restartBackoff)
+		restarts, err = testDataSetReconciler.handleUninjectedPods(ctx, dataset, []*v12.Pod{recreated}, now)
+		assert.NoError(t, err)
+		if assert.Len(t, restarts, 1) {
+			assert.Equal(t, 2, restarts[0].Attempts)
+			assert.Equal(t, now.Unix(), restarts[0].LastRestartTime.Unix())
+		}
+
+		// The workload is never restarted after the max attempts.
+		dataset.Status.Restarts[0].Attempts = maxRestartAttempts
+		dataset.Status.Restarts[0].LastRestartTime = v1.NewTime(now.Add(-restartBackoff))
+		restarts, err = testDataSetReconciler.handleUninjectedPods(ctx, dataset, []*v12.Pod{recreated}, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, dataset.Status.Restarts, restarts)
+		assert.Zero(t, getRestartRequeueAfter(dataset, now))
+
+		// The restarts are forgotten once the pods are injected.
+		restarts, err = testDataSetReconciler.handleUninjectedPods(ctx, dataset, nil, now)
+		assert.NoError(t, err)
+		assert.Empty(t, restarts)
+	})
+}
+
+func TestIsPodWatched(t *testing.T) {
+	r := &DataSetReconciler{}
+	pod := getTestPod("uninjected", false)
+	pod.Namespace = "default"
+	assert.False(t, r.isPodWatched(&pod))
+
+	// The pods missed injection are watched in the namespaces having datasets.
+	r.namespaces.Store("default", true)
+	assert.True(t, r.isPodWatched(&pod))
+	pod.Namespace = "other"
+	assert.False(t, r.isPodWatched(&pod))
+
+	injected := getTestPod("injected", true)
+	injected.Namespace = "other"
+	assert.False(t, r.isPodWatched(&injected))
+	injected.Annotations = map[string]string{v1alpha1.KudaKeyDataSet: "test-ds"}
+	assert.True(t, r.isPodWatched(&injected))
+}
+
+func getTestPod(name string, injected bool) v12.Pod {
+	pod := v12.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app": "test"},
+		},
+		Spec: v12.PodSpec{
+			Containers: []v12.Container{{Name: "app"}},
+		},
+	}
+	if injected {
+		pod.Spec.Containers = append(pod.Spec.Containers, v12.Container{Name: v1alpha1.KudaRuntimeContainerName})
+	}
+	return pod
+}
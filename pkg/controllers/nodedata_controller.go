@@ -0,0 +1,299 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	// field index of the pods by the node name.
+	podNodeNameField = "spec.nodeName"
+)
+
+// NodeDataReconciler maintains the NodeData of each node from the Data statuses of the
+// pods on the node.
+type NodeDataReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// HostCacheIsolation is the isolation of the host cache, by which the data items are
+	// recorded with their tenants.
+	HostCacheIsolation string
+}
+
+//+kubebuilder:rbac:groups=data.kuda.io,resources=nodedatas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=data.kuda.io,resources=nodedatas/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// Reconcile records the data items downloaded by the pods on the node. Data items are
+// kept after the pods are gone since they remain in the host path, they are only removed
+// by the node agent cleaning up the host path.
+func (r *NodeDataReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
+	node := &v1.Node{}
+	if err := r.Get(ctx, req.NamespacedName, node); err != nil {
+		if errors.IsNotFound(err) {
+			// The NodeData is garbage collected with the node.
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "failed to get node")
+		return ctrl.Result{}, err
+	}
+
+	usedItems, err := r.getUsedItems(ctx, node.Name)
+	if err != nil {
+		log.Error(err, "failed to get data items used on the node")
+		return ctrl.Result{}, err
+	}
+
+	instance := &datav1alpha1.NodeData{}
+	if err := r.Get(ctx, types.NamespacedName{Name: node.Name}, instance); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to get NodeData")
+			return ctrl.Result{}, err
+		}
+		instance, err = r.createNodeData(ctx, node)
+		if err != nil {
+			log.Error(err, "failed to create NodeData")
+			return ctrl.Result{}, err
+		}
+	}
+
+	newStatus := genNodeDataStatus(instance.Status.Items, usedItems, metav1.Now())
+	if !reflect.DeepEqual(newStatus, &instance.Status) {
+		instance.Status = *newStatus
+		if err := r.Status().Update(ctx, instance); err != nil {
+			log.Error(err, "failed to update NodeData status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getUsedItems returns the successful data items of the pods on the node, with the number
+// of pods using them.
+func (r *NodeDataReconciler) getUsedItems(ctx context.Context, nodeName string) (map[string]*datav1alpha1.CachedDataItem, error) {
+	podList := &v1.PodList{}
+	if err := r.List(ctx, podList, client.MatchingFields{podNodeNameField: nodeName}); err != nil {
+		return nil, err
+	}
+
+	usedItems := make(map[string]*datav1alpha1.CachedDataItem)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != nodeName || !isPodInjected(&pod) {
+			continue
+		}
+
+		dataList := &datav1alpha1.DataList{}
+		if err := r.List(ctx, dataList, client.InNamespace(pod.Namespace),
+			client.MatchingLabels{datav1alpha1.KudaKeyPod: pod.Name}); err != nil {
+			return nil, err
+		}
+		for _, data := range dataList.Items {
+			dataset := data.Labels[datav1alpha1.KudaKeyDataSet]
+			if owner := metav1.GetControllerOf(&data); owner != nil {
+				dataset = owner.Name
+			}
+			tenant := utils.HostCacheSubPath(r.HostCacheIsolation, data.Namespace, dataset)
+			for _, item := range data.Status.DataItemsStatus {
+				if item.Phase != datav1alpha1.DataSuccess {
+					continue
+				}
+				used := &datav1alpha1.CachedDataItem{Name: item.Name, Namespace: item.Namespace, Version: item.Version, Tenant: tenant}
+				key := getTenantItemKey(used)
+				if existing, ok := usedItems[key]; ok {
+					used = existing
+				} else {
+					usedItems[key] = used
+				}
+				used.Pods += 1
+				// The extracted files are cached instead of the archive.
+				size := item.Size
+				if item.ExtractedSize > 0 {
+					size = item.ExtractedSize
+				}
+				if size > used.Size {
+					used.Size = size
+				}
+			}
+		}
+	}
+
+	return usedItems, nil
+}
+
+// createNodeData creates the NodeData of the node, which is owned by the node.
+func (r *NodeDataReconciler) createNodeData(ctx context.Context, node *v1.Node) (*datav1alpha1.NodeData, error) {
+	instance := &datav1alpha1.NodeData{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: node.Name,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(node, v1.SchemeGroupVersion.WithKind("Node")),
+			},
+		},
+	}
+	if err := r.Create(ctx, instance); err != nil {
+		return nil, err
+	}
+	ctrllog.FromContext(ctx).Info("create NodeData success", "node", node.Name)
+
+	return instance, nil
+}
+
+// genNodeDataStatus merges the data items used on the node into the cached ones. The last
+// used time of a data item is refreshed when the number of pods using it changes.
+func genNodeDataStatus(cachedItems []datav1alpha1.CachedDataItem, usedItems map[string]*datav1alpha1.CachedDataItem, now metav1.Time) *datav1alpha1.NodeDataStatus {
+	items := make([]datav1alpha1.CachedDataItem, 0, len(cachedItems)+len(usedItems))
+	seen := make(map[string]bool, len(cachedItems))
+
+	for _, cached := range cachedItems {
+		key := getTenantItemKey(&cached)
+		seen[key] = true
+
+		item := cached
+		item.Pods = 0
+		if used, ok := usedItems[key]; ok {
+			item.Pods = used.Pods
+			if used.Size > 0 {
+				item.Size = used.Size
+			}
+		}
+		if item.Pods != cached.Pods {
+			item.LastUsed = now
+		}
+		items = append(items, item)
+	}
+
+	for key, used := range usedItems {
+		if seen[key] {
+			continue
+		}
+		item := *used
+		item.LastUsed = now
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return getTenantItemKey(&items[i]) < getTenantItemKey(&items[j])
+	})
+
+	status := &datav1alpha1.NodeDataStatus{ItemsNum: len(items)}
+	for _, item := range items {
+		status.Size += item.Size
+	}
+	if len(items) > 0 {
+		status.Items = items
+	}
+
+	return status
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeDataReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1.Pod{}, podNodeNameField, func(object client.Object) []string {
+		pod := object.(*v1.Pod)
+		if pod.Spec.NodeName == "" {
+			return nil
+		}
+		return []string{pod.Spec.NodeName}
+	}); err != nil {
+		return err
+	}
+
+	// Node updates are mostly heartbeats, only the creations are watched to create the
+	// NodeData, and the deletions are handled by the garbage collector.
+	nodePredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
+	// Pods are mapped to their nodes once scheduled.
+	podPredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.(*v1.Pod).Spec.NodeName != e.ObjectNew.(*v1.Pod).Spec.NodeName
+		},
+	}
+	podHandlers := handler.MapFunc(func(object client.Object) []reconcile.Request {
+		pod, ok := object.(*v1.Pod)
+		if !ok || pod.Spec.NodeName == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: pod.Spec.NodeName}}}
+	})
+	dataHandlers := handler.MapFunc(func(object client.Object) []reconcile.Request {
+		pod := &v1.Pod{}
+		key := types.NamespacedName{Name: object.GetLabels()[datav1alpha1.KudaKeyPod], Namespace: object.GetNamespace()}
+		if err := r.Get(context.Background(), key, pod); err != nil || pod.Spec.NodeName == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: pod.Spec.NodeName}}}
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&datav1alpha1.NodeData{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			&handler.EnqueueRequestForObject{},
+			builder.WithPredicates(nodePredicates)).
+		Watches(
+			&source.Kind{Type: &v1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(podHandlers),
+			builder.WithPredicates(podPredicates)).
+		Watches(
+			&source.Kind{Type: &datav1alpha1.Data{}},
+			handler.EnqueueRequestsFromMapFunc(dataHandlers)).
+		Complete(r)
+}
+
+func getCachedItemKey(namespace, name, version string) string {
+	return fmt.Sprintf("%s/%s@%s", namespace, name, version)
+}
+
+// getTenantItemKey returns the key of the data item cached in the host cache of its tenant.
+func getTenantItemKey(item *datav1alpha1.CachedDataItem) string {
+	key := getCachedItemKey(item.Namespace, item.Name, item.Version)
+	if item.Tenant == "" {
+		return key
+	}
+	return item.Tenant + ":" + key
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v12 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+func TestGenNodeDataStatus(t *testing.T) {
+	lastUsed := v1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	now := v1.NewTime(time.Now().Truncate(time.Second))
+
+	cachedItems := []v1alpha1.CachedDataItem{
+		{Name: "model", Namespace: "ns", Version: "v1", Size: 100, Pods: 1, LastUsed: lastUsed},
+		{Name: "model", Namespace: "ns", Version: "v0", Size: 80, Pods: 1, LastUsed: lastUsed},
+		{Name: "dict", Namespace: "ns", Version: "v1", Size: 10, LastUsed: lastUsed},
+	}
+	usedItems := map[string]*v1alpha1.CachedDataItem{
+		getCachedItemKey("ns", "model", "v1"): {Name: "model", Namespace: "ns", Version: "v1", Size: 100, Pods: 1},
+		getCachedItemKey("ns", "conf", "v2"):  {Name: "conf", Namespace: "ns", Version: "v2", Size: 1, Pods: 2},
+	}
+
+	status := genNodeDataStatus(cachedItems, usedItems, now)
+	assert.Equal(t, &v1alpha1.NodeDataStatus{
+		Items: []v1alpha1.CachedDataItem{
+			{Name: "conf", Namespace: "ns", Version: "v2", Size: 1, Pods: 2, LastUsed: now},
+			{Name: "dict", Namespace: "ns", Version: "v1", Size: 10, LastUsed: lastUsed},
+			{Name: "model", Namespace: "ns", Version: "v0", Size: 80, LastUsed: now},
+			{Name: "model", Namespace: "ns", Version: "v1", Size: 100, Pods: 1, LastUsed: lastUsed},
+		},
+		ItemsNum: 4,
+		Size:     191,
+	}, status)
+
+	assert.Equal(t, &v1alpha1.NodeDataStatus{}, genNodeDataStatus(nil, nil, now))
+}
+
+func TestNodeDataReconcile(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(s))
+	assert.NoError(t, scheme.AddToScheme(s))
+
+	node := &v12.Node{ObjectMeta: v1.ObjectMeta{Name: "node-a", UID: "node-a"}}
+	pod := getTestPod("test-pod", true)
+	pod.Namespace = "default"
+	pod.Spec.NodeName = "node-a"
+	other := getTestPod("other-pod", true)
+	other.Namespace = "default"
+	other.Spec.NodeName = "node-b"
+
+	data := getTestData("test-ds", "model", "test-pod")
+	data.Namespace = "default"
+	data.Labels[v1alpha1.KudaKeyDataSet] = "test-ds"
+	data.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+		{Name: "model", Namespace: "test-ns", Version: "v1", Phase: v1alpha1.DataSuccess, Size: 100},
+	}
+	// The same data item is cached by another dataset in its own host cache.
+	tenantPod := getTestPod("tenant-pod", true)
+	tenantPod.Namespace = "default"
+	tenantPod.Spec.NodeName = "node-a"
+	tenantData := getTestData("tenant-ds", "model", "tenant-pod")
+	tenantData.Namespace = "default"
+	tenantData.Labels[v1alpha1.KudaKeyDataSet] = "tenant-ds"
+	tenantData.Status.DataItemsStatus = data.Status.DataItemsStatus
+	otherData := getTestData("test-ds", "dict", "other-pod")
+	otherData.Namespace = "default"
+	otherData.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+		{Name: "dict", Namespace: "test-ns", Version: "v1", Phase: v1alpha1.DataSuccess, Size: 10},
+	}
+
+	r := &NodeDataReconciler{
+		Client:             fake.NewClientBuilder().WithScheme(s).WithObjects(node, &pod, &other, &tenantPod, data, otherData, tenantData).Build(),
+		Scheme:             s,
+		HostCacheIsolation: utils.HostCacheIsolationDataSet,
+	}
+	ctx := context.Background()
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "node-a"}})
+	assert.NoError(t, err)
+
+	nodeData := &v1alpha1.NodeData{}
+	assert.NoError(t, r.Get(ctx, client.ObjectKey{Name: "node-a"}, nodeData))
+	assert.Equal(t, "node-a", nodeData.OwnerReferences[0].Name)
+	assert.Equal(t, 2, nodeData.Status.ItemsNum)
+	assert.Equal(t, int64(200), nodeData.Status.Size)
+	assert.Equal(t, "model", nodeData.Status.Items[0].Name)
+	assert.Equal(t, "default/tenant-ds", nodeData.Status.Items[0].Tenant)
+	assert.Equal(t, "default/test-ds", nodeData.Status.Items[1].Tenant)
+	assert.Equal(t, 1, nodeData.Status.Items[1].Pods)
+
+	// The data item is kept after the pod is deleted.
+	assert.NoError(t, r.Delete(ctx, &pod))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "node-a"}})
+	assert.NoError(t, err)
+	nodeData = &v1alpha1.NodeData{}
+	assert.NoError(t, r.Get(ctx, client.ObjectKey{Name: "node-a"}, nodeData))
+	assert.Equal(t, 2, nodeData.Status.ItemsNum)
+	assert.Equal(t, 0, nodeData.Status.Items[1].Pods)
+}
+
+func TestGetNodeDistribution(t *testing.T) {
+	podA := getTestPod("test-pod-a", true)
+	podA.Spec.NodeName = "node-b"
+	podB := getTestPod("test-pod-b", true)
+	podB.Spec.NodeName = "node-a"
+	podC := getTestPod("test-pod-c", true)
+	podC.Spec.NodeName = "node-b"
+	pending := getTestPod("test-pending", true)
+	podMap := map[string]*v12.Pod{"test-pod-a": &podA, "test-pod-b": &podB, "test-pod-c": &podC, "test-pending": &pending}
+
+	dataList := &v1alpha1.DataList{}
+	for _, name := range []string{"test-pod-a", "test-pod-b", "test-pod-c", "test-pending"} {
+		data := getTestData("test-ds", "model", name)
+		if name != "test-pod-c" {
+			data.Status.Success = 1
+		}
+		dataList.Items = append(dataList.Items, *data)
+	}
+
+	assert.Equal(t, []v1alpha1.NodeDistribution{
+		{NodeName: "node-a", Replicas: 1, SuccessReplicas: 1},
+		{NodeName: "node-b", Replicas: 2, SuccessReplicas: 1},
+	}, getNodeDistribution(dataList, podMap, 1))
+	assert.Nil(t, getNodeDistribution(&v1alpha1.DataList{}, podMap, 1))
+}
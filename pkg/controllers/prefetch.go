@@ -0,0 +1,439 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/dedup"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	reasonPrefetchStarted   = "PrefetchStarted"
+	reasonPrefetchSucceeded = "PrefetchSucceeded"
+	reasonPrefetchRemoved   = "PrefetchRemoved"
+	reasonFailedPrefetch    = "FailedPrefetch"
+
+	prefetchDataPath = "/kuda/data"
+	prefetchInfoPath = "/etc/podinfo"
+
+	volumeNameShareData = "share-data"
+	volumeNameHostData  = "host-data"
+	volumeNamePodData   = "pod-data"
+)
+
+// PrefetchConfig describes the kuda runtime of the prefetch pods, which should be the same
+// as the runtime injected by the webhook so that the data is cached in the same host path.
+type PrefetchConfig struct {
+	RuntimeImage      string
+	HostPath          string
+	RuntimeServerPort uint
+	// HostCacheIsolation is the isolation of the host cache, the data is prefetched into the
+	// sub directory the pods of the dataset are entitled to.
+	HostCacheIsolation string
+	// HostCacheDedup deduplicates the data items in the content store of the host cache.
+	HostCacheDedup bool
+}
+
+// syncPrefetch prefetches the data of the template onto the nodes selected by the prefetch
+// spec. A data resource is maintained for each node as the record of the progress, and a
+// kuda runtime pod is run on the node until all the data items are downloaded. The nodes
+// are left pending if the data quota of the namespace doesn't allow the downloads.
+func (r *DataSetReconciler) syncPrefetch(ctx context.Context, instance *datav1alpha1.DataSet, revision string, nsQuota *namespaceQuota) ([]datav1alpha1.PrefetchNodeStatus, error) {
+	log := ctrllog.FromContext(ctx)
+
+	dataList := &datav1alpha1.DataList{}
+	if err := r.List(ctx, dataList, client.InNamespace(instance.Namespace),
+		client.MatchingLabels{datav1alpha1.KudaKeyPrefetch: instance.Name}); err != nil {
+		return nil, err
+	}
+	dataMap := convertDataListToMap(dataList)
+
+	nodes, err := r.getPrefetchNodes(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]datav1alpha1.PrefetchNodeStatus, 0, len(nodes))
+	for _, node := range nodes {
+		podName := getPrefetchPodName(instance.Name, node)
+		data, ok := dataMap[getDataNameByPod(instance.Name, podName)]
+		if ok {
+			delete(dataMap, data.Name)
+			if !isPrefetchDataUpToDate(instance, data, revision) && !nsQuota.allowDownload(data) {
+				statuses = append(statuses, *genPrefetchNodeStatus(data, node, revision))
+				continue
+			}
+			if err := r.updatePrefetchData(ctx, instance, data, revision); err != nil {
+				log.Error(err, "failed to update prefetch data", "node", node)
+				return nil, err
+			}
+		} else {
+			if !nsQuota.allowDownload(nil) {
+				statuses = append(statuses, datav1alpha1.PrefetchNodeStatus{NodeName: node, Phase: datav1alpha1.PrefetchPending, Revision: revision})
+				continue
+			}
+			if data, err = r.createPrefetchData(ctx, instance, node, revision); err != nil {
+				log.Error(err, "failed to create prefetch data", "node", node)
+				r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedPrefetch, "Failed to prefetch data onto node %s: %v", node, err)
+				return nil, err
+			}
+		}
+
+		status := genPrefetchNodeStatus(data, node, revision)
+		if status.Phase == datav1alpha1.PrefetchSucceeded {
+			// The runtime keeps serving after downloaded, so the pod is removed once done.
+			if err := r.deletePrefetchPod(ctx, instance.Namespace, podName); err != nil {
+				log.Error(err, "failed to delete prefetch pod", "node", node)
+				return nil, err
+			}
+			if previous := getPrefetchNodeStatus(instance.Status.Prefetch, node); previous == nil || previous.Phase != status.Phase {
+				r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonPrefetchSucceeded, "Prefetched revision %s onto node %s", revision, node)
+			}
+		} else if err := r.ensurePrefetchPod(ctx, instance, podName, node); err != nil {
+			log.Error(err, "failed to create prefetch pod", "node", node)
+			r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedPrefetch, "Failed to prefetch data onto node %s: %v", node, err)
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+
+	// Clean up the nodes no longer selected.
+	for _, data := range dataMap {
+		if err := r.deletePrefetchPod(ctx, data.Namespace, getPodNameByData(data)); err != nil {
+			return nil, err
+		}
+		if err := r.Delete(ctx, data); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonPrefetchRemoved, "Stopped prefetching onto node %s", data.Labels[datav1alpha1.KudaKeyNode])
+	}
+
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	return statuses, nil
+}
+
+// getPrefetchNodes returns the sorted names of the ready and schedulable nodes matching the
+// node selector of the prefetch spec, together with the nodes of the pods waiting for the
+// data of the dataset by the kuda CSI volumes.
+func (r *DataSetReconciler) getPrefetchNodes(ctx context.Context, instance *datav1alpha1.DataSet) ([]string, error) {
+	if instance.GetDeletionTimestamp() != nil {
+		return nil, nil
+	}
+
+	selected := make(map[string]bool)
+	if instance.Spec.Prefetch != nil {
+		nodeList := &v1.NodeList{}
+		if err := r.List(ctx, nodeList, client.MatchingLabels(instance.Spec.Prefetch.NodeSelector)); err != nil {
+			return nil, err
+		}
+		for _, node := range nodeList.Items {
+			if utils.ContainsAll(node.Labels, instance.Spec.Prefetch.NodeSelector) && isNodeAvailable(&node) {
+				selected[node.Name] = true
+			}
+		}
+	}
+
+	csiNodes, err := r.getCSIPodNodes(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range csiNodes {
+		selected[node] = true
+	}
+	if len(selected) == 0 {
+		return nil, nil
+	}
+
+	// Nothing is prefetched if the pods of the namespace don't use the host cache.
+	ns := &v1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: instance.Namespace}, ns); err != nil {
+		return nil, err
+	}
+	if ns.Labels[datav1alpha1.KudaKeyHostCache] == datav1alpha1.HostCacheDisabled {
+		return nil, nil
+	}
+
+	nodes := make([]string, 0, len(selected))
+	for node := range selected {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	return nodes, nil
+}
+
+// createPrefetchData creates the data resource recording the prefetch onto the node. The
+// lifecycle handlers are dropped since there is no app container to act on.
+func (r *DataSetReconciler) createPrefetchData(ctx context.Context, instance *datav1alpha1.DataSet, node, revision string) (*datav1alpha1.Data, error) {
+	podName := getPrefetchPodName(instance.Name, node)
+	data := &datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getDataNameByPod(instance.Name, podName),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				datav1alpha1.KudaKeyPrefetch: instance.Name,
+				datav1alpha1.KudaKeyPod:      podName,
+				datav1alpha1.KudaKeyNode:     node,
+				datav1alpha1.KudaKeyRevision: revision,
+			},
+		},
+		Spec: newPrefetchDataSpec(instance),
+	}
+	data.Spec.Queued = isDownloadQueued(r.DownloadLease, instance)
+	if err := ctrl.SetControllerReference(instance, data, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Create(ctx, data); err != nil {
+		return nil, err
+	}
+
+	ctrllog.FromContext(ctx).Info("create prefetch data success", "data.Name", data.Name, "node", node)
+	r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonPrefetchStarted, "Started prefetching revision %s onto node %s", revision, node)
+
+	return data, nil
+}
+
+// isPrefetchDataUpToDate returns true if the data resource is at the current template,
+// whether it holds the download lease or not.
+func isPrefetchDataUpToDate(instance *datav1alpha1.DataSet, data *datav1alpha1.Data, revision string) bool {
+	spec := newPrefetchDataSpec(instance)
+	spec.Queued = data.Spec.Queued
+	spec.BandwidthLimit = data.Spec.BandwidthLimit
+	return reflect.DeepEqual(data.Spec, spec) && data.Labels[datav1alpha1.KudaKeyRevision] == revision
+}
+
+// updatePrefetchData updates the data resource to the current template.
+func (r *DataSetReconciler) updatePrefetchData(ctx context.Context, instance *datav1alpha1.DataSet, data *datav1alpha1.Data, revision string) error {
+	if isPrefetchDataUpToDate(instance, data, revision) {
+		return nil
+	}
+
+	data.Spec = newPrefetchDataSpec(instance)
+	data.Spec.Queued = isDownloadQueued(r.DownloadLease, instance)
+	data.Labels[datav1alpha1.KudaKeyRevision] = revision
+	if err := r.Update(ctx, data); err != nil {
+		return err
+	}
+	// The status of the previous revision is reset, otherwise it may be taken as done
+	// before the runtime pod is recreated.
+	data.Status = *genDefaultStatus(data)
+	if err := r.Status().Update(ctx, data); err != nil {
+		return err
+	}
+	r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonPrefetchStarted, "Started prefetching revision %s onto node %s", revision, data.Labels[datav1alpha1.KudaKeyNode])
+
+	return nil
+}
+
+// ensurePrefetchPod creates the kuda runtime pod on the node if not exist.
+func (r *DataSetReconciler) ensurePrefetchPod(ctx context.Context, instance *datav1alpha1.DataSet, podName, node string) error {
+	pod := &v1.Pod{}
+	err := r.Get(ctx, types.NamespacedName{Name: podName, Namespace: instance.Namespace}, pod)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	pod = r.newPrefetchPod(instance, podName, node)
+	if err := ctrl.SetControllerReference(instance, pod, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, pod); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	ctrllog.FromContext(ctx).Info("create prefetch pod success", "pod.Name", podName, "node", node)
+
+	return nil
+}
+
+// deletePrefetchPod deletes the prefetch pod if exist.
+func (r *DataSetReconciler) deletePrefetchPod(ctx context.Context, namespace, podName string) error {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: namespace}}
+	if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// newPrefetchPod returns the pod running the kuda runtime alone on the node, it's the same
+// as the runtime injected by the webhook except that the runtime is the main container.
+func (r *DataSetReconciler) newPrefetchPod(instance *datav1alpha1.DataSet, podName, node string) *v1.Pod {
+	dirOrCreate := v1.HostPathDirectoryOrCreate
+	hostPath := filepath.Join(r.Prefetch.HostPath, utils.HostCacheSubPath(r.Prefetch.HostCacheIsolation, instance.Namespace, instance.Name))
+	args := []string{
+		fmt.Sprintf("--download-root-dir=%s", r.Prefetch.HostPath),
+		fmt.Sprintf("--local-root-dir=%s", prefetchDataPath),
+		fmt.Sprintf("--notice-server-port=%d", r.Prefetch.RuntimeServerPort),
+	}
+	if r.Prefetch.HostCacheDedup {
+		args = append(args, fmt.Sprintf("--content-dir=%s", filepath.Join(r.Prefetch.HostPath, dedup.DirName)))
+	}
+
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				datav1alpha1.KudaKeyPrefetch: instance.Name,
+			},
+			Annotations: map[string]string{
+				datav1alpha1.KudaKeyDataSet: instance.Name,
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName: node,
+			// The nodes are selected explicitly, so their taints are tolerated like daemons.
+			Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}},
+			Containers: []v1.Container{
+				{
+					Name:  datav1alpha1.KudaRuntimeContainerName,
+					Image: r.Prefetch.RuntimeImage,
+					Args:  args,
+					VolumeMounts: []v1.VolumeMount{
+						{Name: volumeNamePodData, MountPath: prefetchInfoPath},
+						{Name: volumeNameShareData, MountPath: prefetchDataPath},
+						{Name: volumeNameHostData, MountPath: r.Prefetch.HostPath},
+					},
+					Env: []v1.EnvVar{
+						{Name: datav1alpha1.KudaRuntimeEnvDataSetName, Value: instance.Name},
+						{Name: datav1alpha1.KudaRuntimeEnvDataSetNamespace, Value: instance.Namespace},
+						{Name: datav1alpha1.KudaRuntimeEnvPodName, Value: podName},
+						{Name: datav1alpha1.KudaRuntimeEnvPodIP, ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "status.podIP"}}},
+						{Name: datav1alpha1.KudaRuntimeEnvMainContainerName, Value: datav1alpha1.KudaRuntimeContainerName},
+					},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name:         volumeNameShareData,
+					VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+				},
+				{
+					Name:         volumeNameHostData,
+					VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: hostPath, Type: &dirOrCreate}},
+				},
+				{
+					Name: volumeNamePodData,
+					VolumeSource: v1.VolumeSource{DownwardAPI: &v1.DownwardAPIVolumeSource{Items: []v1.DownwardAPIVolumeFile{
+						{Path: "annotations", FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.annotations"}},
+					}}},
+				},
+			},
+		},
+	}
+}
+
+// newPrefetchDataSpec returns the data spec of the template without lifecycle handlers.
+func newPrefetchDataSpec(instance *datav1alpha1.DataSet) datav1alpha1.DataSpec {
+	items := make([]datav1alpha1.DataItem, 0, len(instance.Spec.Template.DataItems))
+	for _, item := range instance.Spec.Template.DataItems {
+		item.Lifecycle = nil
+		items = append(items, item)
+	}
+
+	return datav1alpha1.DataSpec{
+		DataItems:   items,
+		DataSources: instance.Spec.Template.DataSources,
+	}
+}
+
+// genPrefetchNodeStatus returns the progress of the prefetch onto the node by its data
+// resource. It's pending until the runtime starts downloading any data item of the revision.
+func genPrefetchNodeStatus(data *datav1alpha1.Data, node, revision string) *datav1alpha1.PrefetchNodeStatus {
+	status := &datav1alpha1.PrefetchNodeStatus{
+		NodeName: node,
+		Phase:    datav1alpha1.PrefetchPending,
+		Revision: revision,
+	}
+	if data.Labels[datav1alpha1.KudaKeyRevision] != revision {
+		return status
+	}
+
+	phases := make(map[string]datav1alpha1.DataPhase, len(data.Status.DataItemsStatus))
+	for _, item := range data.Status.DataItemsStatus {
+		phases[getCachedItemKey(item.Namespace, item.Name, item.Version)] = item.Phase
+	}
+	failed, waiting := 0, 0
+	for _, item := range data.Spec.DataItems {
+		switch phases[getCachedItemKey(item.Namespace, item.Name, item.Version)] {
+		case datav1alpha1.DataSuccess:
+			status.Success += 1
+		case datav1alpha1.DataFailed:
+			failed += 1
+		case datav1alpha1.DataQueued, datav1alpha1.DataWaiting, "":
+			waiting += 1
+		}
+	}
+
+	switch {
+	case status.Success == len(data.Spec.DataItems):
+		status.Phase = datav1alpha1.PrefetchSucceeded
+	case waiting == len(data.Spec.DataItems):
+		status.Phase = datav1alpha1.PrefetchPending
+	case failed > 0:
+		status.Phase = datav1alpha1.PrefetchFailed
+	default:
+		status.Phase = datav1alpha1.PrefetchDownloading
+	}
+
+	return status
+}
+
+func getPrefetchNodeStatus(statuses []datav1alpha1.PrefetchNodeStatus, node string) *datav1alpha1.PrefetchNodeStatus {
+	for i := range statuses {
+		if statuses[i].NodeName == node {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// getPrefetchPodName returns the prefetch pod name of the node. The node name is hashed to
+// keep the name short and in the form of the pod names of workloads.
+func getPrefetchPodName(dsName, node string) string {
+	hash, _ := utils.MD5(node)
+	return fmt.Sprintf("%s-prefetch-%s", dsName, hash[:10])
+}
+
+// isNodeAvailable returns true if the node is ready and schedulable.
+func isNodeAvailable(node *v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
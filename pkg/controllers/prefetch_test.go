@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+func TestSyncPrefetch(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+	testDataSetReconciler.Prefetch = PrefetchConfig{
+		RuntimeImage:       "kuda-runtime:latest",
+		HostPath:           "/var/lib/kuda",
+		RuntimeServerPort:  8888,
+		HostCacheIsolation: utils.HostCacheIsolationDataSet,
+		HostCacheDedup:     true,
+	}
+
+	ctx := context.Background()
+	newNode := func(name string, labels map[string]string, ready bool) *v12.Node {
+		status := v12.ConditionTrue
+		if !ready {
+			status = v12.ConditionFalse
+		}
+		return &v12.Node{
+			ObjectMeta: v1.ObjectMeta{Name: name, Labels: labels},
+			Status:     v12.NodeStatus{Conditions: []v12.NodeCondition{{Type: v12.NodeReady, Status: status}}},
+		}
+	}
+	assert.NoError(t, testDataSetReconciler.Create(ctx, &v12.Namespace{ObjectMeta: v1.ObjectMeta{Name: "default"}}))
+	gpu := map[string]string{"pool": "gpu"}
+	for _, node := range []*v12.Node{
+		newNode("node-a", gpu, true),
+		newNode("node-b", gpu, true),
+		newNode("node-c", gpu, false),
+		newNode("node-d", nil, true),
+	} {
+		assert.NoError(t, testDataSetReconciler.Create(ctx, node))
+	}
+
+	instance := getTestDataSet("test-ds", "model")
+	instance.Namespace = "default"
+	instance.UID = "test-ds"
+	instance.Spec.Template.DataItems[0].Lifecycle = &v1alpha1.Lifecycle{
+		PostDownload: &v1alpha1.LifecycleHandler{Exec: &v12.ExecAction{Command: []string{"reload"}}},
+	}
+	instance.Spec.Prefetch = &v1alpha1.PrefetchSpec{NodeSelector: gpu}
+
+	statuses, err := testDataSetReconciler.syncPrefetch(ctx, instance, "test-ds-v1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha1.PrefetchNodeStatus{
+		{NodeName: "node-a", Phase: v1alpha1.PrefetchPending, Revision: "test-ds-v1"},
+		{NodeName: "node-b", Phase: v1alpha1.PrefetchPending, Revision: "test-ds-v1"},
+	}, statuses)
+
+	podName := getPrefetchPodName("test-ds", "node-a")
+	pod := &v12.Pod{}
+	assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: podName, Namespace: "default"}, pod))
+	assert.Equal(t, "node-a", pod.Spec.NodeName)
+	assert.Equal(t, "test-ds", pod.Annotations[v1alpha1.KudaKeyDataSet])
+	assert.True(t, isPodInjected(pod))
+	assert.Equal(t, "/var/lib/kuda/default/test-ds", pod.Spec.Volumes[1].HostPath.Path)
+	assert.Contains(t, pod.Spec.Containers[0].Args, "--content-dir=/var/lib/kuda/.kuda-content")
+
+	data := &v1alpha1.Data{}
+	assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: getDataNameByPod("test-ds", podName), Namespace: "default"}, data))
+	assert.Equal(t, podName, data.Labels[v1alpha1.KudaKeyPod])
+	assert.Equal(t, "node-a", data.Labels[v1alpha1.KudaKeyNode])
+	assert.Nil(t, data.Spec.DataItems[0].Lifecycle)
+	assert.Empty(t, data.Labels[v1alpha1.KudaKeyDataSet])
+
+	// The pod is removed once the data items are downloaded.
+	item := instance.Spec.Template.DataItems[0]
+	data.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+		{Name: item.Name, Namespace: item.Namespace, Version: item.Version, Phase: v1alpha1.DataSuccess},
+	}
+	assert.NoError(t, testDataSetReconciler.Status().Update(ctx, data))
+
+	statuses, err = testDataSetReconciler.syncPrefetch(ctx, instance, "test-ds-v1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.PrefetchNodeStatus{NodeName: "node-a", Phase: v1alpha1.PrefetchSucceeded, Revision: "test-ds-v1", Success: 1}, statuses[0])
+	err = testDataSetReconciler.Get(ctx, types.NamespacedName{Name: podName, Namespace: "default"}, pod)
+	assert.True(t, errors.IsNotFound(err))
+
+	// The pod is run again for the new revision.
+	statuses, err = testDataSetReconciler.syncPrefetch(ctx, instance, "test-ds-v2", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.PrefetchPending, statuses[0].Phase)
+	assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: podName, Namespace: "default"}, pod))
+
+	// Everything is cleaned up once the host cache is disabled for the namespace.
+	ns := &v12.Namespace{}
+	assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: "default"}, ns))
+	ns.Labels = map[string]string{v1alpha1.KudaKeyHostCache: v1alpha1.HostCacheDisabled}
+	assert.NoError(t, testDataSetReconciler.Update(ctx, ns))
+	statuses, err = testDataSetReconciler.syncPrefetch(ctx, instance, "test-ds-v2", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, statuses)
+	dataList := &v1alpha1.DataList{}
+	assert.NoError(t, testDataSetReconciler.List(ctx, dataList))
+	assert.Empty(t, dataList.Items)
+
+	// Or the prefetch is removed.
+	instance.Spec.Prefetch = nil
+	statuses, err = testDataSetReconciler.syncPrefetch(ctx, instance, "test-ds-v2", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, statuses)
+	assert.NoError(t, testDataSetReconciler.List(ctx, dataList))
+	assert.Empty(t, dataList.Items)
+	podList := &v12.PodList{}
+	assert.NoError(t, testDataSetReconciler.List(ctx, podList))
+	assert.Empty(t, podList.Items)
+}
+
+func TestSyncPrefetchForCSIPods(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+	testDataSetReconciler.Prefetch = PrefetchConfig{
+		RuntimeImage:      "kuda-runtime:latest",
+		HostPath:          "/var/lib/kuda",
+		RuntimeServerPort: 8888,
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, testDataSetReconciler.Create(ctx, &v12.Namespace{ObjectMeta: v1.ObjectMeta{Name: "default"}}))
+	newCSIPod := func(name, node, dataset string) *v12.Pod {
+		return &v12.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v12.PodSpec{
+				NodeName: node,
+				Volumes: []v12.Volume{{
+					Name: "kuda-data",
+					VolumeSource: v12.VolumeSource{CSI: &v12.CSIVolumeSource{
+						Driver:           v1alpha1.KudaCSIDriverName,
+						VolumeAttributes: map[string]string{v1alpha1.KudaCSIVolumeDataSet: dataset},
+					}},
+				}},
+			},
+		}
+	}
+	for _, pod := range []*v12.Pod{
+		newCSIPod("app-1", "node-a", "test-ds"),
+		newCSIPod("app-2", "node-b", "test-ds"),
+		newCSIPod("app-3", "", "test-ds"),
+		newCSIPod("app-4", "node-c", "other-ds"),
+		newCSIPod("app-5", "node-a", "test-ds"),
+	} {
+		assert.NoError(t, testDataSetReconciler.Create(ctx, pod))
+	}
+
+	// The data is prefetched onto the nodes of the scheduled pods without the prefetch spec.
+	instance := getTestDataSet("test-ds", "model")
+	instance.Namespace = "default"
+	instance.UID = "test-ds"
+	statuses, err := testDataSetReconciler.syncPrefetch(ctx, instance, "test-ds-v1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha1.PrefetchNodeStatus{
+		{NodeName: "node-a", Phase: v1alpha1.PrefetchPending, Revision: "test-ds-v1"},
+		{NodeName: "node-b", Phase: v1alpha1.PrefetchPending, Revision: "test-ds-v1"},
+	}, statuses)
+
+	// The CSI pods are neither injected nor missed the injection.
+	injected, uninjected := filterInjectedPods(&v12.PodList{Items: []v12.Pod{*newCSIPod("app-1", "node-a", "test-ds")}})
+	assert.Empty(t, injected.Items)
+	assert.Empty(t, uninjected)
+
+	pod := &v12.Pod{}
+	assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: "app-2", Namespace: "default"}, pod))
+	assert.NoError(t, testDataSetReconciler.Delete(ctx, pod))
+	statuses, err = testDataSetReconciler.syncPrefetch(ctx, instance, "test-ds-v1", nil)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "node-a", statuses[0].NodeName)
+}
+
+func TestGenPrefetchNodeStatus(t *testing.T) {
+	data := getTestData("test-ds", "model", "test-ds-prefetch-abc")
+	data.Spec.DataItems = append(data.Spec.DataItems, getTestDataItem("dict"))
+
+	status := genPrefetchNodeStatus(data, "node-a", "test-ds-v2")
+	assert.Equal(t, v1alpha1.PrefetchPending, status.Phase)
+
+	data.Status.DataItemsStatus = v1alpha1.DataItemsStatus{
+		{Name: "model", Namespace: "test-ns", Version: "v1", Phase: v1alpha1.DataSuccess},
+		{Name: "dict", Namespace: "test-ns", Version: "v0", Phase: v1alpha1.DataSuccess},
+	}
+	status = genPrefetchNodeStatus(data, "node-a", "test-ds-v1")
+	assert.Equal(t, &v1alpha1.PrefetchNodeStatus{NodeName: "node-a", Phase: v1alpha1.PrefetchDownloading, Revision: "test-ds-v1", Success: 1}, status)
+
+	data.Status.DataItemsStatus[1] = v1alpha1.DataItemStatus{Name: "dict", Namespace: "test-ns", Version: "v1", Phase: v1alpha1.DataFailed}
+	assert.Equal(t, v1alpha1.PrefetchFailed, genPrefetchNodeStatus(data, "node-a", "test-ds-v1").Phase)
+}
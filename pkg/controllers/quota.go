@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/quota"
+)
+
+const (
+	reasonWithinQuota     = "WithinQuota"
+	reasonQuotaExceeded   = "QuotaExceeded"
+	reasonDownloadsQueued = "DownloadsQueued"
+
+	// the interval to retry the downloads queued by the quotas.
+	quotaRequeueInterval = 10 * time.Second
+)
+
+// namespaceQuota is the quota of the data delivered by a dataset in a reconciliation, it
+// tracks the downloads started by the dataset. A nil namespaceQuota is unlimited.
+type namespaceQuota struct {
+	// exceeded is the message if the dataset exceeds the size or items limits, its data
+	// is not delivered at all.
+	exceeded string
+	// downloads is the number of downloads allowed to start, negative if unlimited.
+	downloads int
+	// limitedBy is the quota limiting the concurrent downloads.
+	limitedBy string
+	// queued is the number of downloads held back by the quota.
+	queued int
+}
+
+// getNamespaceQuota returns the quota of the dataset by the DataQuotas in its namespace,
+// or nil if there is none.
+func (r *DataSetReconciler) getNamespaceQuota(ctx context.Context, instance *datav1alpha1.DataSet) (*namespaceQuota, error) {
+	quotaList := &datav1alpha1.DataQuotaList{}
+	if err := r.List(ctx, quotaList, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+	if len(quotaList.Items) == 0 {
+		return nil, nil
+	}
+
+	dsList := &datav1alpha1.DataSetList{}
+	if err := r.List(ctx, dsList, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+	dataList := &datav1alpha1.DataList{}
+	if err := r.List(ctx, dataList, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+	downloads := quota.Downloads(dataList.Items)
+
+	q := &namespaceQuota{downloads: -1}
+	for i := range quotaList.Items {
+		dataQuota := &quotaList.Items[i]
+		if msg, ok := quota.GetExceededDataSets(dataQuota, dsList.Items)[instance.Name]; ok && q.exceeded == "" {
+			q.exceeded = fmt.Sprintf("exceeded DataQuota %s: %s", dataQuota.Name, msg)
+		}
+		if max := dataQuota.Spec.MaxConcurrentDownloads; max != nil {
+			available := int(*max) - downloads
+			if available < 0 {
+				available = 0
+			}
+			if q.downloads < 0 || available < q.downloads {
+				q.downloads = available
+				q.limitedBy = dataQuota.Name
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// allowDownload returns true if the data, or a new one if nil, is allowed to download the
+// current template. The data already downloading keeps its slot of the concurrent downloads.
+func (q *namespaceQuota) allowDownload(data *datav1alpha1.Data) bool {
+	if q == nil {
+		return true
+	}
+	if q.exceeded != "" {
+		return false
+	}
+	if q.downloads < 0 || (data != nil && quota.IsDownloading(data)) {
+		return true
+	}
+	if q.downloads == 0 {
+		q.queued += 1
+		return false
+	}
+	q.downloads -= 1
+	return true
+}
+
+// isQueued returns true if any download is held back until the others complete.
+func (q *namespaceQuota) isQueued() bool {
+	return q != nil && q.exceeded == "" && q.queued > 0
+}
+
+// newQuotaCondition returns the WithinQuota condition of the dataset, or nil if there is
+// no quota in the namespace.
+func newQuotaCondition(instance *datav1alpha1.DataSet, q *namespaceQuota) *metav1.Condition {
+	if q == nil {
+		return nil
+	}
+
+	condition := &metav1.Condition{
+		Type:               datav1alpha1.DataSetWithinQuota,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonWithinQuota,
+		Message:            "the dataset is within the data quotas of the namespace",
+		ObservedGeneration: instance.Generation,
+	}
+	switch {
+	case q.exceeded != "":
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonQuotaExceeded
+		condition.Message = q.exceeded
+	case q.queued > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonDownloadsQueued
+		condition.Message = fmt.Sprintf("%d download(s) are queued by the max concurrent downloads of DataQuota %s", q.queued, q.limitedBy)
+	}
+	return condition
+}
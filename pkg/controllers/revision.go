@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	defaultRevisionHistoryLimit = 10
+
+	// length of the template hash used in the revision name.
+	revisionHashLength = 10
+)
+
+// syncRevisions makes sure a ControllerRevision exists for the current template of the
+// dataset, and truncates the revision history exceeding the limit. It returns the name of
+// the current revision.
+func (r *DataSetReconciler) syncRevisions(ctx context.Context, instance *datav1alpha1.DataSet) (string, error) {
+	revisionName, err := getRevisionName(instance)
+	if err != nil {
+		return "", err
+	}
+
+	revisionList := &appsv1.ControllerRevisionList{}
+	if err := r.List(ctx, revisionList, client.InNamespace(instance.Namespace),
+		client.MatchingLabels{datav1alpha1.KudaKeyDataSet: instance.Name}); err != nil {
+		return "", err
+	}
+	revisions := revisionList.Items
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+
+	var (
+		current     *appsv1.ControllerRevision
+		maxRevision int64
+	)
+	for i := range revisions {
+		if revisions[i].Name == revisionName {
+			current = &revisions[i]
+		}
+		if revisions[i].Revision > maxRevision {
+			maxRevision = revisions[i].Revision
+		}
+	}
+
+	switch {
+	case current == nil:
+		current, err = r.createRevision(ctx, instance, revisionName, maxRevision+1)
+		if err != nil {
+			return "", err
+		}
+		revisions = append(revisions, *current)
+	case current.Revision < maxRevision:
+		// The template is rolled back to an old revision, bump it to be the latest.
+		current.Revision = maxRevision + 1
+		if err := r.Update(ctx, current); err != nil {
+			return "", err
+		}
+		sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	}
+
+	if err := r.truncateRevisions(ctx, instance, revisions, revisionName); err != nil {
+		return "", err
+	}
+
+	return revisionName, nil
+}
+
+// createRevision creates the ControllerRevision holding the current template of the dataset.
+func (r *DataSetReconciler) createRevision(ctx context.Context, instance *datav1alpha1.DataSet, name string, revision int64) (*appsv1.ControllerRevision, error) {
+	raw, err := json.Marshal(instance.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := &appsv1.ControllerRevision{
+		ObjectMeta: v12.ObjectMeta{
+			Name:      name,
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				datav1alpha1.KudaKeyDataSet: instance.Name,
+			},
+		},
+		Data:     runtime.RawExtension{Raw: raw},
+		Revision: revision,
+	}
+	if err := ctrl.SetControllerReference(instance, cr, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	if err := r.Create(ctx, cr); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	ctrllog.FromContext(ctx).Info("create controller revision success", "revision", name, "number", revision)
+
+	return cr, nil
+}
+
+// truncateRevisions deletes the oldest revisions exceeding the history limit, the sorted
+// revisions are required.
+func (r *DataSetReconciler) truncateRevisions(ctx context.Context, instance *datav1alpha1.DataSet, revisions []appsv1.ControllerRevision, current string) error {
+	limit := defaultRevisionHistoryLimit
+	if instance.Spec.RevisionHistoryLimit != nil {
+		limit = int(*instance.Spec.RevisionHistoryLimit)
+	}
+
+	// the current revision is not counted into the history.
+	exceeded := len(revisions) - 1 - limit
+	for i := 0; i < len(revisions) && exceeded > 0; i++ {
+		if revisions[i].Name == current {
+			continue
+		}
+		if err := r.Delete(ctx, &revisions[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		exceeded--
+	}
+
+	return nil
+}
+
+// getRevisionName returns the name of the revision for the current template of the dataset.
+func getRevisionName(instance *datav1alpha1.DataSet) (string, error) {
+	hash, err := utils.MD5(instance.Spec.Template)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", instance.Name, hash[:revisionHashLength]), nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestSyncRevisions(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	dataset := getTestDataSet("test-ds", "test-data")
+	dataset.Namespace = "default"
+	dataset.Spec.RevisionHistoryLimit = pointer.Int32Ptr(1)
+
+	listRevisions := func() []appsv1.ControllerRevision {
+		revisions := &appsv1.ControllerRevisionList{}
+		assert.NoError(t, testDataSetReconciler.List(ctx, revisions, client.InNamespace("default")))
+		return revisions.Items
+	}
+
+	v1, err := testDataSetReconciler.syncRevisions(ctx, dataset)
+	assert.NoError(t, err)
+	assert.Len(t, listRevisions(), 1)
+
+	t.Run("create a new revision when the template changes", func(t *testing.T) {
+		dataset.Spec.Template.DataItems[0].Version = "v2"
+		v2, err := testDataSetReconciler.syncRevisions(ctx, dataset)
+		assert.NoError(t, err)
+		assert.NotEqual(t, v1, v2)
+		assert.Len(t, listRevisions(), 2)
+	})
+
+	t.Run("bump the old revision when the template rolls back", func(t *testing.T) {
+		dataset.Spec.Template.DataItems[0].Version = "v1"
+		current, err := testDataSetReconciler.syncRevisions(ctx, dataset)
+		assert.NoError(t, err)
+		assert.Equal(t, v1, current)
+
+		cr := &appsv1.ControllerRevision{}
+		assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: v1, Namespace: "default"}, cr))
+		assert.Equal(t, int64(3), cr.Revision)
+		assert.Equal(t, "test-ds", cr.Labels[v1alpha1.KudaKeyDataSet])
+	})
+
+	t.Run("truncate revisions exceeding the history limit", func(t *testing.T) {
+		dataset.Spec.Template.DataItems[0].Version = "v3"
+		_, err := testDataSetReconciler.syncRevisions(ctx, dataset)
+		assert.NoError(t, err)
+		assert.Len(t, listRevisions(), 2)
+	})
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	reasonUpdateWindowOpen    = "UpdateWindowOpen"
+	reasonUpdateWindowClosed  = "UpdateWindowClosed"
+	reasonOutsideUpdateWindow = "OutsideUpdateWindow"
+)
+
+// scheduleLookbacks are how far back the latest scheduled time is searched, from the
+// shortest so that the frequent schedules are not iterated over a long period.
+var scheduleLookbacks = []time.Duration{
+	time.Minute,
+	time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	31 * 24 * time.Hour,
+	366 * 24 * time.Hour,
+	5 * 366 * 24 * time.Hour,
+}
+
+// lastScheduleTime returns the latest time scheduled no later than now, or zero if there
+// is none in the last five years.
+func lastScheduleTime(schedule cron.Schedule, now time.Time) time.Time {
+	for _, lookback := range scheduleLookbacks {
+		last := schedule.Next(now.Add(-lookback))
+		if last.IsZero() || last.After(now) {
+			continue
+		}
+		for {
+			next := schedule.Next(last)
+			if next.IsZero() || next.After(now) {
+				return last
+			}
+			last = next
+		}
+	}
+	return time.Time{}
+}
+
+// getDataItemRefreshes returns the latest scheduled re-syncs of the data items with a
+// schedule, the invalid schedules are skipped.
+func getDataItemRefreshes(items []datav1alpha1.DataItem, now time.Time) []datav1alpha1.DataItemRefresh {
+	var refreshes []datav1alpha1.DataItemRefresh
+	for _, item := range items {
+		if item.Schedule == "" {
+			continue
+		}
+		schedule, err := cron.ParseStandard(item.Schedule)
+		if err != nil {
+			continue
+		}
+		last := lastScheduleTime(schedule, now)
+		if last.IsZero() {
+			continue
+		}
+		refreshes = append(refreshes, datav1alpha1.DataItemRefresh{
+			Name:      item.Name,
+			Namespace: item.Namespace,
+			Time:      metav1.NewTime(last),
+		})
+	}
+	return refreshes
+}
+
+// updateWindow is the state of the update windows of a dataset at a time.
+type updateWindow struct {
+	// enabled is true if the dataset has any update window.
+	enabled bool
+	// open is true if the updates are rolled out at the time.
+	open bool
+	// closeTime is when the open windows close.
+	closeTime time.Time
+	// nextOpenTime is when the next window opens.
+	nextOpenTime time.Time
+	// nextRefreshTime is when the next re-sync of the data items is scheduled.
+	nextRefreshTime time.Time
+	// held is the number of the data resources whose updates are held until a window opens.
+	held int
+}
+
+// getUpdateWindow returns the state of the update windows of the dataset at the time, the
+// invalid schedules are skipped.
+func getUpdateWindow(instance *datav1alpha1.DataSet, now time.Time) *updateWindow {
+	window := &updateWindow{
+		enabled: len(instance.Spec.UpdateWindows) > 0,
+		open:    len(instance.Spec.UpdateWindows) == 0,
+	}
+	for _, w := range instance.Spec.UpdateWindows {
+		schedule, err := cron.ParseStandard(w.Schedule)
+		if err != nil {
+			continue
+		}
+		if last := lastScheduleTime(schedule, now); !last.IsZero() && now.Before(last.Add(w.Duration.Duration)) {
+			window.open = true
+			if end := last.Add(w.Duration.Duration); end.After(window.closeTime) {
+				window.closeTime = end
+			}
+		}
+		if next := schedule.Next(now); !next.IsZero() && (window.nextOpenTime.IsZero() || next.Before(window.nextOpenTime)) {
+			window.nextOpenTime = next
+		}
+	}
+
+	for _, item := range instance.Spec.Template.DataItems {
+		if item.Schedule == "" {
+			continue
+		}
+		schedule, err := cron.ParseStandard(item.Schedule)
+		if err != nil {
+			continue
+		}
+		if next := schedule.Next(now); !next.IsZero() && (window.nextRefreshTime.IsZero() || next.Before(window.nextRefreshTime)) {
+			window.nextRefreshTime = next
+		}
+	}
+	return window
+}
+
+// requeueAfter returns how long to wait before the dataset is reconciled again for the
+// scheduled re-syncs and the updates held, or zero if not needed.
+func (w *updateWindow) requeueAfter(now time.Time) time.Duration {
+	next := w.nextRefreshTime
+	if w.held > 0 && !w.nextOpenTime.IsZero() && (next.IsZero() || w.nextOpenTime.Before(next)) {
+		next = w.nextOpenTime
+	}
+	if next.IsZero() {
+		return 0
+	}
+	return next.Sub(now)
+}
+
+// newProgressingCondition returns the Progressing condition of the dataset, or nil if there
+// is no update window.
+func newProgressingCondition(instance *datav1alpha1.DataSet, w *updateWindow) *metav1.Condition {
+	if !w.enabled {
+		return nil
+	}
+
+	condition := &metav1.Condition{
+		Type:               datav1alpha1.DataSetProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonUpdateWindowOpen,
+		Message:            fmt.Sprintf("updates are rolled out until the update window closes at %s", w.closeTime.Format(time.RFC3339)),
+		ObservedGeneration: instance.Generation,
+	}
+	switch {
+	case w.open:
+	case w.held > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonOutsideUpdateWindow
+		condition.Message = fmt.Sprintf("updates of %d data resource(s) are held until the update window opens at %s", w.held, formatTime(w.nextOpenTime))
+	default:
+		condition.Reason = reasonUpdateWindowClosed
+		condition.Message = fmt.Sprintf("no update is pending, the next update window opens at %s", formatTime(w.nextOpenTime))
+	}
+	return condition
+}
+
+// formatTime returns the time in RFC3339, or unknown if it's zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestLastScheduleTime(t *testing.T) {
+	now := time.Date(2021, 11, 8, 10, 3, 0, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{schedule: "0 2 * * *", want: time.Date(2021, 11, 8, 2, 0, 0, 0, time.UTC)},
+		{schedule: "0 12 * * *", want: time.Date(2021, 11, 7, 12, 0, 0, 0, time.UTC)},
+		{schedule: "*/5 * * * *", want: time.Date(2021, 11, 8, 10, 0, 0, 0, time.UTC)},
+		{schedule: "3 10 * * *", want: now},
+		{schedule: "0 0 1 1 *", want: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 29 2 *", want: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := cron.ParseStandard(tt.schedule)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, lastScheduleTime(schedule, now), tt.schedule)
+	}
+}
+
+func TestGetDataItemRefreshes(t *testing.T) {
+	now := time.Date(2021, 11, 8, 10, 3, 0, 0, time.UTC)
+	items := []v1alpha1.DataItem{getTestDataItem("model"), getTestDataItem("dict")}
+	items[1].Schedule = "0 2 * * *"
+
+	assert.Equal(t, []v1alpha1.DataItemRefresh{
+		{Name: "dict", Namespace: "test-ns", Time: v1.NewTime(time.Date(2021, 11, 8, 2, 0, 0, 0, time.UTC))},
+	}, getDataItemRefreshes(items, now))
+	assert.Nil(t, getDataItemRefreshes(items[:1], now))
+}
+
+func TestGetUpdateWindow(t *testing.T) {
+	ds := getTestDataSet("test-ds", "dict")
+	ds.Spec.Template.DataItems[0].Schedule = "0 2 * * *"
+
+	// The updates are rolled out anytime without windows.
+	now := time.Date(2021, 11, 8, 10, 0, 0, 0, time.UTC)
+	window := getUpdateWindow(ds, now)
+	assert.True(t, window.open)
+	assert.Nil(t, newProgressingCondition(ds, window))
+	assert.Equal(t, 16*time.Hour, window.requeueAfter(now))
+
+	ds.Spec.UpdateWindows = []v1alpha1.UpdateWindow{
+		{Schedule: "0 1 * * *", Duration: v1.Duration{Duration: 2 * time.Hour}},
+		{Schedule: "0 13 * * 6", Duration: v1.Duration{Duration: time.Hour}},
+	}
+	now = time.Date(2021, 11, 8, 2, 30, 0, 0, time.UTC)
+	window = getUpdateWindow(ds, now)
+	assert.True(t, window.open)
+	assert.Equal(t, time.Date(2021, 11, 8, 3, 0, 0, 0, time.UTC), window.closeTime)
+	condition := newProgressingCondition(ds, window)
+	assert.Equal(t, v1.ConditionTrue, condition.Status)
+	assert.Equal(t, reasonUpdateWindowOpen, condition.Reason)
+
+	// The dataset is requeued once the window opens if any update is held.
+	now = time.Date(2021, 11, 8, 3, 0, 0, 0, time.UTC)
+	window = getUpdateWindow(ds, now)
+	assert.False(t, window.open)
+	assert.Equal(t, time.Date(2021, 11, 9, 1, 0, 0, 0, time.UTC), window.nextOpenTime)
+	assert.Equal(t, reasonUpdateWindowClosed, newProgressingCondition(ds, window).Reason)
+	assert.Equal(t, 23*time.Hour, window.requeueAfter(now))
+	window.held = 2
+	assert.Equal(t, 22*time.Hour, window.requeueAfter(now))
+	condition = newProgressingCondition(ds, window)
+	assert.Equal(t, v1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonOutsideUpdateWindow, condition.Reason)
+	assert.Equal(t, "updates of 2 data resource(s) are held until the update window opens at 2021-11-09T01:00:00Z", condition.Message)
+}
+
+func TestSyncDataSetUpdateWindow(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	ds := getTestDataSet("test-ds", "dict")
+	ds.Namespace = "default"
+	ds.Spec.UpdateWindows = []v1alpha1.UpdateWindow{{Schedule: "0 1 * * *", Duration: v1.Duration{Duration: time.Hour}}}
+	assert.NoError(t, testDataSetReconciler.Create(ctx, ds))
+	pod := getTestPod("test-ds-abc-1", true)
+	pod.Namespace = "default"
+	podList := &v12.PodList{Items: []v12.Pod{pod}}
+	dataList := &v1alpha1.DataList{}
+	now := time.Date(2021, 11, 8, 1, 30, 0, 0, time.UTC)
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nil, getUpdateWindow(ds, now)))
+	assert.Len(t, dataList.Items, 1)
+
+	getVersion := func() string {
+		data := &v1alpha1.Data{}
+		assert.NoError(t, testDataSetReconciler.Get(ctx, types.NamespacedName{Name: dataList.Items[0].Name, Namespace: "default"}, data))
+		return data.Spec.DataItems[0].Version
+	}
+
+	// The template change is held outside the window.
+	ds.Spec.Template.DataItems[0].Version = "v2"
+	now = time.Date(2021, 11, 8, 2, 0, 0, 0, time.UTC)
+	window := getUpdateWindow(ds, now)
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nil, window))
+	assert.Equal(t, "v1", getVersion())
+	assert.Equal(t, 1, window.held)
+	condition := meta.FindStatusCondition(ds.Status.Conditions, v1alpha1.DataSetProgressing)
+	assert.Equal(t, v1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonOutsideUpdateWindow, condition.Reason)
+
+	// It's rolled out once the window opens.
+	now = time.Date(2021, 11, 9, 1, 0, 0, 0, time.UTC)
+	window = getUpdateWindow(ds, now)
+	assert.NoError(t, testDataSetReconciler.syncDataSet(ctx, ds, podList, dataList, nil, window))
+	assert.Equal(t, "v2", getVersion())
+	assert.Equal(t, 0, window.held)
+	condition = meta.FindStatusCondition(ds.Status.Conditions, v1alpha1.DataSetProgressing)
+	assert.Equal(t, v1.ConditionTrue, condition.Status)
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/discovery"
+)
+
+const (
+	reasonVersionDiscovered     = "VersionDiscovered"
+	reasonFailedDiscoverVersion = "FailedDiscoverVersion"
+	reasonVersionOverridden     = "VersionOverridden"
+
+	// timeout of a request to the remote store.
+	discoveryRequestTimeout = 30 * time.Second
+)
+
+// VersionDiscoveryReconciler polls the remote stores for the data items with the version
+// policy, and updates their versions in the template of the DataSet, which is then rolled
+// out as any other change of the template. The versions changed in the template by the
+// others, e.g. rollout undo or GitOps, are not overridden.
+type VersionDiscoveryReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// NewStore returns the remote store of the data source, defaults to the REST APIs of the
+	// data sources.
+	NewStore func(sources *datav1alpha1.DataSources, sourceType string) (discovery.Store, error)
+}
+
+//+kubebuilder:rbac:groups=data.kuda.io,resources=datasets,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=data.kuda.io,resources=datasets/status,verbs=get;update;patch
+
+// Reconcile polls the remote stores of the data items due, and updates the template with
+// the latest versions.
+func (r *VersionDiscoveryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
+	instance := &datav1alpha1.DataSet{}
+	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "failed to get DataSet")
+		return ctrl.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	previous := make(map[string]datav1alpha1.DiscoveredVersion, len(instance.Status.DiscoveredVersions))
+	for _, discovered := range instance.Status.DiscoveredVersions {
+		previous[discovered.Namespace+"/"+discovered.Name] = discovered
+	}
+
+	now := metav1.Now()
+	template := instance.Spec.Template.DeepCopy()
+	statuses := make([]datav1alpha1.DiscoveredVersion, 0)
+	updated := make([]string, 0)
+	var requeueAfter time.Duration
+	for i := range template.DataItems {
+		item := &template.DataItems[i]
+		policy := item.VersionPolicy
+		if policy == nil {
+			continue
+		}
+
+		interval := discovery.Interval(policy)
+		last, ok := previous[item.Namespace+"/"+item.Name]
+		status := last
+		if !ok || status.ParentPath != policy.ParentPath || now.Sub(status.LastPollTime.Time) >= interval {
+			status = r.discoverVersion(ctx, instance, template.DataSources, item, last, now)
+		}
+		if wait := interval - now.Sub(status.LastPollTime.Time); requeueAfter == 0 || wait < requeueAfter {
+			requeueAfter = wait
+		}
+
+		// The version is overridden if it's changed from the one discovered last time, and
+		// it's not the one just discovered either.
+		status.Overridden = ok && last.ParentPath == policy.ParentPath && last.Version != "" &&
+			item.Version != last.Version && item.Version != status.Version
+		statuses = append(statuses, status)
+		if status.Overridden && !last.Overridden {
+			r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonVersionOverridden,
+				"Version %s of data item %s/%s is changed in the template, the discovered versions are not applied until it's set to %s",
+				item.Version, item.Namespace, item.Name, status.Version)
+		}
+		if status.Version == "" || status.Overridden {
+			continue
+		}
+		remotePath := discovery.RemotePath(policy, status.Version)
+		if item.Version != status.Version || item.RemotePath != remotePath {
+			item.Version, item.RemotePath = status.Version, remotePath
+			updated = append(updated, item.Namespace+"/"+item.Name+":"+status.Version)
+		}
+	}
+	if len(statuses) == 0 {
+		statuses = nil
+	}
+
+	if len(updated) > 0 {
+		instance.Spec.Template = *template
+		if err := r.Update(ctx, instance); err != nil {
+			log.Error(err, "failed to update the versions of the data items")
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(instance, v1.EventTypeNormal, reasonVersionDiscovered, "Updated data items to the latest versions %v", updated)
+		log.Info("update the versions of the data items", "items", updated)
+	}
+	if !reflect.DeepEqual(statuses, instance.Status.DiscoveredVersions) {
+		instance.Status.DiscoveredVersions = statuses
+		if err := r.Status().Update(ctx, instance); err != nil {
+			log.Error(err, "failed to update the discovered versions")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// discoverVersion polls the remote store for the latest version of the data item. The
+// version discovered before is kept if the poll fails.
+func (r *VersionDiscoveryReconciler) discoverVersion(ctx context.Context, instance *datav1alpha1.DataSet, sources *datav1alpha1.DataSources,
+	item *datav1alpha1.DataItem, previous datav1alpha1.DiscoveredVersion, now metav1.Time) datav1alpha1.DiscoveredVersion {
+	status := datav1alpha1.DiscoveredVersion{
+		Name:         item.Name,
+		Namespace:    item.Namespace,
+		ParentPath:   item.VersionPolicy.ParentPath,
+		LastPollTime: now,
+	}
+	if previous.ParentPath == status.ParentPath {
+		status.Version = previous.Version
+	}
+
+	newStore := r.NewStore
+	if newStore == nil {
+		newStore = newDiscoveryStore
+	}
+	latest := ""
+	store, err := newStore(sources, item.DataSourceType)
+	if err == nil {
+		latest, err = discovery.Latest(ctx, store, item.VersionPolicy)
+	}
+	if err != nil {
+		status.Message = err.Error()
+		if previous.Message != status.Message {
+			r.Recorder.Eventf(instance, v1.EventTypeWarning, reasonFailedDiscoverVersion, "Failed to discover the version of data item %s/%s: %v", item.Namespace, item.Name, err)
+		}
+		return status
+	}
+	if latest != "" {
+		status.Version = latest
+	}
+	return status
+}
+
+// newDiscoveryStore returns the remote store over the REST APIs of the data source.
+func newDiscoveryStore(sources *datav1alpha1.DataSources, sourceType string) (discovery.Store, error) {
+	return discovery.NewStore(sources, sourceType, &http.Client{Timeout: discoveryRequestTimeout})
+}
+
+// hasVersionPolicy returns true if any data item of the dataset has the version policy.
+func hasVersionPolicy(ds *datav1alpha1.DataSet) bool {
+	for _, item := range ds.Spec.Template.DataItems {
+		if item.VersionPolicy != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VersionDiscoveryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The remote stores are polled by requeueing the datasets, and once their templates change.
+	dataSetPredicates := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return hasVersionPolicy(e.Object.(*datav1alpha1.DataSet))
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() && hasVersionPolicy(e.ObjectNew.(*datav1alpha1.DataSet))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("versiondiscovery").
+		For(&datav1alpha1.DataSet{}, builder.WithPredicates(dataSetPredicates)).
+		Complete(r)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/discovery"
+)
+
+// testVersionStore is a remote store of the directories with the files.
+type testVersionStore struct {
+	files map[string][]string
+	err   error
+}
+
+func (s *testVersionStore) ListDirs(ctx context.Context, dir string) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.files[dir], nil
+}
+
+func (s *testVersionStore) Exists(ctx context.Context, file string) (bool, error) {
+	_, ok := s.files[file]
+	return ok, s.err
+}
+
+func TestVersionDiscoveryReconcile(t *testing.T) {
+	testDataSetReconciler, err := getTestDataSetReconciler()
+	assert.NoError(t, err)
+	store := &testVersionStore{files: map[string][]string{
+		"/models/ranker":                     {"1636358400", "1636444800"},
+		"/models/ranker/1636358400/_SUCCESS": nil,
+	}}
+	r := &VersionDiscoveryReconciler{
+		Client:   testDataSetReconciler.Client,
+		Scheme:   testDataSetReconciler.Scheme,
+		Recorder: testDataSetReconciler.Recorder,
+		NewStore: func(sources *v1alpha1.DataSources, sourceType string) (discovery.Store, error) {
+			return store, nil
+		},
+	}
+
+	ctx := context.Background()
+	ds := getTestDataSet("ranker-ds", "ranker")
+	ds.Namespace = "default"
+	ds.Spec.Template.DataItems[0].VersionPolicy = &v1alpha1.VersionPolicy{
+		ParentPath: "/models/ranker",
+		Order:      v1alpha1.VersionOrderNumeric,
+		MarkerFile: "_SUCCESS",
+		Interval:   &v1.Duration{Duration: time.Minute},
+	}
+	assert.NoError(t, r.Create(ctx, ds))
+
+	getDataSet := func() *v1alpha1.DataSet {
+		ds := &v1alpha1.DataSet{}
+		assert.NoError(t, r.Get(ctx, types.NamespacedName{Name: "ranker-ds", Namespace: "default"}, ds))
+		return ds
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "ranker-ds", Namespace: "default"}}
+
+	// The version being written has no marker file yet.
+	result, err := r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= time.Minute)
+	ds = getDataSet()
+	assert.Equal(t, "1636358400", ds.Spec.Template.DataItems[0].Version)
+	assert.Equal(t, "/models/ranker/1636358400", ds.Spec.Template.DataItems[0].RemotePath)
+	assert.Len(t, ds.Status.DiscoveredVersions, 1)
+	assert.Equal(t, "1636358400", ds.Status.DiscoveredVersions[0].Version)
+
+	// The remote store is not polled again until the interval elapses.
+	store.files["/models/ranker/1636444800/_SUCCESS"] = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "1636358400", getDataSet().Spec.Template.DataItems[0].Version)
+
+	ds = getDataSet()
+	ds.Status.DiscoveredVersions[0].LastPollTime = v1.NewTime(time.Now().Add(-time.Minute))
+	assert.NoError(t, r.Status().Update(ctx, ds))
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	ds = getDataSet()
+	assert.Equal(t, "1636444800", ds.Spec.Template.DataItems[0].Version)
+	assert.Equal(t, "/models/ranker/1636444800", ds.Spec.Template.DataItems[0].RemotePath)
+
+	// The version discovered before is kept if the poll fails.
+	store.err = fmt.Errorf("connection refused")
+	ds.Status.DiscoveredVersions[0].LastPollTime = v1.NewTime(time.Now().Add(-time.Minute))
+	assert.NoError(t, r.Status().Update(ctx, ds))
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	ds = getDataSet()
+	assert.Equal(t, "1636444800", ds.Spec.Template.DataItems[0].Version)
+	assert.Equal(t, "1636444800", ds.Status.DiscoveredVersions[0].Version)
+	assert.Equal(t, "connection refused", ds.Status.DiscoveredVersions[0].Message)
+
+	// The version changed in the template, e.g. by rollout undo, is not overridden.
+	store.err = nil
+	ds.Spec.Template.DataItems[0].Version = "1636358400"
+	ds.Spec.Template.DataItems[0].RemotePath = "/models/ranker/1636358400"
+	assert.NoError(t, r.Update(ctx, ds))
+	ds.Status.DiscoveredVersions[0].LastPollTime = v1.NewTime(time.Now().Add(-time.Minute))
+	assert.NoError(t, r.Status().Update(ctx, ds))
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	ds = getDataSet()
+	assert.Equal(t, "1636358400", ds.Spec.Template.DataItems[0].Version)
+	assert.Equal(t, "1636444800", ds.Status.DiscoveredVersions[0].Version)
+	assert.True(t, ds.Status.DiscoveredVersions[0].Overridden)
+
+	// The discovered versions are applied again once it's set to the discovered version.
+	store.files["/models/ranker"] = append(store.files["/models/ranker"], "1636531200")
+	store.files["/models/ranker/1636531200/_SUCCESS"] = nil
+	ds.Spec.Template.DataItems[0].Version = "1636444800"
+	assert.NoError(t, r.Update(ctx, ds))
+	ds.Status.DiscoveredVersions[0].LastPollTime = v1.NewTime(time.Now().Add(-time.Minute))
+	assert.NoError(t, r.Status().Update(ctx, ds))
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	ds = getDataSet()
+	assert.Equal(t, "1636531200", ds.Spec.Template.DataItems[0].Version)
+	assert.False(t, ds.Status.DiscoveredVersions[0].Overridden)
+}
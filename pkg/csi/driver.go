@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+var (
+	log = ctrl.Log.WithName("csi")
+)
+
+// Config defines fields for the CSI node plugin.
+type Config struct {
+	// Name of the driver, which is referenced by the volumes.
+	Name    string
+	Version string
+	// NodeID is the name of the node the plugin runs on.
+	NodeID string
+	// Endpoint is the unix socket the plugin listens on, e.g. unix:///csi/csi.sock.
+	Endpoint string
+	// HostPath and HostCacheIsolation should be the same as the webhook config, the data
+	// is served from the host cache.
+	HostPath           string
+	HostCacheIsolation string
+}
+
+// DefaultConfig returns the config of the kuda CSI driver.
+func DefaultConfig() *Config {
+	return &Config{
+		Name:     datav1alpha1.KudaCSIDriverName,
+		Version:  "v0.1.0",
+		Endpoint: "unix:///csi/csi.sock",
+		HostPath: "/var/lib/kuda",
+	}
+}
+
+// Driver is the CSI node plugin serving the data of datasets cached on the node as
+// volumes. The data is prefetched onto the node by kuda-manager, and the volumes are
+// published once it's downloaded.
+type Driver struct {
+	csi.UnimplementedIdentityServer
+	csi.UnimplementedControllerServer
+	csi.UnimplementedNodeServer
+
+	config  *Config
+	reader  client.Reader
+	mounter Mounter
+}
+
+// NewDriver returns Driver object by the config, the reader should read from the API
+// server directly.
+func NewDriver(config *Config, reader client.Reader, mounter Mounter) *Driver {
+	return &Driver{
+		config:  config,
+		reader:  reader,
+		mounter: mounter,
+	}
+}
+
+// Start serves the CSI services on the endpoint until the context is done.
+func (d *Driver) Start(ctx context.Context) error {
+	u, err := url.Parse(d.config.Endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "unix" {
+		return fmt.Errorf("unsupported endpoint %s, only unix socket is supported", d.config.Endpoint)
+	}
+	path := u.Path
+	if path == "" {
+		path = u.Host
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(logInterceptor))
+	csi.RegisterIdentityServer(server, d)
+	csi.RegisterControllerServer(server, d)
+	csi.RegisterNodeServer(server, d)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	log.Info("serving csi driver", "name", d.config.Name, "endpoint", d.config.Endpoint)
+	return server.Serve(listener)
+}
+
+// logInterceptor logs the failed requests.
+func logInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		log.Error(err, "csi request failed", "method", info.FullMethod)
+	}
+	return resp, err
+}
+
+// GetPluginInfo implements csi.IdentityServer.
+func (d *Driver) GetPluginInfo(ctx context.Context, req *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
+	return &csi.GetPluginInfoResponse{
+		Name:          d.config.Name,
+		VendorVersion: d.config.Version,
+	}, nil
+}
+
+// GetPluginCapabilities implements csi.IdentityServer. There is no controller service,
+// the volumes are neither provisioned nor attached.
+func (d *Driver) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
+	return &csi.GetPluginCapabilitiesResponse{}, nil
+}
+
+// Probe implements csi.IdentityServer.
+func (d *Driver) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+	return &csi.ProbeResponse{Ready: wrapperspb.Bool(true)}, nil
+}
+
+// ControllerGetCapabilities implements csi.ControllerServer, no capability is supported.
+func (d *Driver) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	return &csi.ControllerGetCapabilitiesResponse{}, nil
+}
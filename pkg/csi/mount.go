@@ -0,0 +1,28 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+// Mounter mounts the volumes on the node.
+type Mounter interface {
+	// IsMountPoint returns whether the path is a mount point.
+	IsMountPoint(path string) (bool, error)
+	// Mount mounts the source to the target with the file system type, the source is bind
+	// mounted if the type is empty.
+	Mount(source, target, fstype string, readOnly bool) error
+	// Unmount unmounts the target together with the mounts under it.
+	Unmount(target string) error
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// NewMounter returns the mounter by the mount syscalls.
+func NewMounter() Mounter {
+	return &mounter{}
+}
+
+type mounter struct{}
+
+// IsMountPoint implements Mounter by the mount info of the process.
+func (m *mounter) IsMountPoint(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		return false, err
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The 5th field is the mount point, see proc(5).
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		if unescapeMountPoint(fields[4]) == path {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// Mount implements Mounter.
+func (m *mounter) Mount(source, target, fstype string, readOnly bool) error {
+	if fstype != "" {
+		var flags uintptr = syscall.MS_NOSUID | syscall.MS_NODEV
+		if readOnly {
+			flags |= syscall.MS_RDONLY
+		}
+		return syscall.Mount(source, target, fstype, flags, "mode=0755")
+	}
+
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return err
+	}
+	if !readOnly {
+		return nil
+	}
+	// The read-only flag is ignored by the bind mount, so it's remounted.
+	return syscall.Mount("", target, "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_REC, "")
+}
+
+// Unmount implements Mounter, the target is detached with the mounts under it.
+func (m *mounter) Unmount(target string) error {
+	return syscall.Unmount(target, syscall.MNT_DETACH)
+}
+
+// unescapeMountPoint unescapes the octal sequences in the mount point, e.g. \040 for space.
+func unescapeMountPoint(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
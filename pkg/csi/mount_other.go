@@ -0,0 +1,48 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+import (
+	"errors"
+)
+
+var errUnsupported = errors.New("mount is only supported on linux")
+
+// NewMounter returns the mounter, which is only supported on linux.
+func NewMounter() Mounter {
+	return &mounter{}
+}
+
+type mounter struct{}
+
+// IsMountPoint implements Mounter.
+func (m *mounter) IsMountPoint(path string) (bool, error) {
+	return false, errUnsupported
+}
+
+// Mount implements Mounter.
+func (m *mounter) Mount(source, target, fstype string, readOnly bool) error {
+	return errUnsupported
+}
+
+// Unmount implements Mounter.
+func (m *mounter) Unmount(target string) error {
+	return errUnsupported
+}
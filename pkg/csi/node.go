@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	// volumeAttributePodNamespace is passed by kubelet if podInfoOnMount of the CSIDriver is set.
+	volumeAttributePodNamespace = "csi.storage.k8s.io/pod.namespace"
+)
+
+// itemMount describes a data item mounted into the volume.
+type itemMount struct {
+	// Source is the version directory in the host cache.
+	Source string
+	// Target is the directory in the volume, it's the local path of the data item.
+	Target string
+}
+
+// NodeGetInfo implements csi.NodeServer.
+func (d *Driver) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return &csi.NodeGetInfoResponse{NodeId: d.config.NodeID}, nil
+}
+
+// NodeGetCapabilities implements csi.NodeServer, the volumes are published without staging.
+func (d *Driver) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	return &csi.NodeGetCapabilitiesResponse{}, nil
+}
+
+// NodePublishVolume implements csi.NodeServer. The data items of the dataset are bind
+// mounted read-only from the host cache into the target path, and Unavailable is returned
+// until they are prefetched onto the node, so that kubelet retries the mount.
+func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is missing")
+	}
+	target := req.GetTargetPath()
+	if target == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path is missing")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability is missing")
+	}
+	if req.GetVolumeCapability().GetBlock() != nil {
+		return nil, status.Error(codes.InvalidArgument, "block volume is not supported")
+	}
+
+	attributes := req.GetVolumeContext()
+	name := attributes[datav1alpha1.KudaCSIVolumeDataSet]
+	if name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "volume attribute %s is missing", datav1alpha1.KudaCSIVolumeDataSet)
+	}
+	// The dataset is always looked up in the namespace of the pod passed by kubelet, the
+	// namespace attribute is set by the users and must not reach the datasets of the others.
+	namespace := attributes[volumeAttributePodNamespace]
+	if namespace == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "volume attribute %s is missing", volumeAttributePodNamespace)
+	}
+	if ns := attributes[datav1alpha1.KudaCSIVolumeNamespace]; ns != "" && ns != namespace {
+		return nil, status.Errorf(codes.InvalidArgument, "dataset of namespace %s is not accessible from namespace %s", ns, namespace)
+	}
+
+	mounted, err := d.mounter.IsMountPoint(target)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if mounted {
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
+	mounts, err := d.getItemMounts(ctx, namespace, name, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(target, 0750); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	// The target is a tmpfs holding the mount points of the data items, so that the local
+	// paths of the data items are created without touching the host cache.
+	if err := d.mounter.Mount("tmpfs", target, "tmpfs", false); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	for _, mount := range mounts {
+		err := os.MkdirAll(mount.Target, 0755)
+		if err == nil {
+			err = d.mounter.Mount(mount.Source, mount.Target, "", true)
+		}
+		if err != nil {
+			if err := d.mounter.Unmount(target); err != nil {
+				log.Error(err, "failed to clean up the volume", "target", target)
+			}
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	log.Info("published volume", "volume", req.GetVolumeId(), "dataset", namespace+"/"+name, "target", target)
+
+	return &csi.NodePublishVolumeResponse{}, nil
+}
+
+// NodeUnpublishVolume implements csi.NodeServer.
+func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is missing")
+	}
+	target := req.GetTargetPath()
+	if target == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path is missing")
+	}
+
+	mounted, err := d.mounter.IsMountPoint(target)
+	if os.IsNotExist(err) {
+		return &csi.NodeUnpublishVolumeResponse{}, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if mounted {
+		if err := d.mounter.Unmount(target); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	log.Info("unpublished volume", "volume", req.GetVolumeId(), "target", target)
+
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
+
+// getItemMounts returns the mounts of the data items of the dataset. The data items must
+// be downloaded by the prefetch of the dataset onto the node.
+func (d *Driver) getItemMounts(ctx context.Context, namespace, name, target string) ([]itemMount, error) {
+	dataset := &datav1alpha1.DataSet{}
+	if err := d.reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, dataset); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "dataset %s/%s not found", namespace, name)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	ns := &v1.Namespace{}
+	if err := d.reader.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if ns.Labels[datav1alpha1.KudaKeyHostCache] == datav1alpha1.HostCacheDisabled {
+		return nil, status.Errorf(codes.FailedPrecondition, "host cache is disabled for namespace %s", namespace)
+	}
+
+	dataList := &datav1alpha1.DataList{}
+	if err := d.reader.List(ctx, dataList, client.InNamespace(namespace), client.MatchingLabels{
+		datav1alpha1.KudaKeyPrefetch: name,
+		datav1alpha1.KudaKeyNode:     d.config.NodeID,
+	}); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	downloaded := make(map[string]bool)
+	for _, data := range dataList.Items {
+		for _, item := range data.Status.DataItemsStatus {
+			if item.Phase == datav1alpha1.DataSuccess {
+				downloaded[item.Namespace+"/"+item.Name+"@"+item.Version] = true
+			}
+		}
+	}
+
+	cacheDir := filepath.Join(d.config.HostPath, utils.HostCacheSubPath(d.config.HostCacheIsolation, namespace, name))
+	mounts := make([]itemMount, 0, len(dataset.Spec.Template.DataItems))
+	for _, item := range dataset.Spec.Template.DataItems {
+		if !downloaded[item.Namespace+"/"+item.Name+"@"+item.Version] {
+			return nil, status.Errorf(codes.Unavailable, "data item %s/%s@%s is being prefetched onto node %s",
+				item.Namespace, item.Name, item.Version, d.config.NodeID)
+		}
+		source := filepath.Join(cacheDir, item.Namespace, item.Name, item.Version)
+		if _, err := os.Stat(source); err != nil {
+			return nil, status.Errorf(codes.Unavailable, "data item %s/%s@%s is not in the host cache: %v",
+				item.Namespace, item.Name, item.Version, err)
+		}
+		// The local path is relative to the volume, and never escapes from it.
+		path := filepath.Join(target, filepath.Clean("/"+item.LocalPath))
+		if path == filepath.Clean(target) || !strings.HasPrefix(path, filepath.Clean(target)+string(filepath.Separator)) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid local path %s of data item %s", item.LocalPath, item.Name)
+		}
+		mounts = append(mounts, itemMount{Source: source, Target: path})
+	}
+
+	return mounts, nil
+}
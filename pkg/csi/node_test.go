@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+// fakeMounter records the mounts instead of mounting them.
+type fakeMounter struct {
+	mounts map[string]string
+}
+
+func (m *fakeMounter) IsMountPoint(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		return false, err
+	}
+	_, ok := m.mounts[path]
+	return ok, nil
+}
+
+func (m *fakeMounter) Mount(source, target, fstype string, readOnly bool) error {
+	m.mounts[target] = source
+	return nil
+}
+
+func (m *fakeMounter) Unmount(target string) error {
+	// The mount points of the data items are on the tmpfs, which are gone with it.
+	if m.mounts[target] == "tmpfs" {
+		entries, err := ioutil.ReadDir(target)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(target, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	for path := range m.mounts {
+		if path == target || strings.HasPrefix(path, target+"/") {
+			delete(m.mounts, path)
+		}
+	}
+	return nil
+}
+
+func getTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = datav1alpha1.AddToScheme(scheme)
+	return scheme
+}
+
+func TestDriverNodePublishVolume(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuda-csi")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	hostPath := filepath.Join(root, "cache")
+	target := filepath.Join(root, "pods/uid/volumes/kuda-data/mount")
+
+	dataset := &datav1alpha1.DataSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: "default"},
+		Spec: datav1alpha1.DataSetSpec{
+			Template: datav1alpha1.DataTemplateSpec{
+				DataItems: []datav1alpha1.DataItem{
+					{Name: "model", Namespace: "ns", Version: "v1", LocalPath: "/models/model"},
+					{Name: "dict", Namespace: "ns", Version: "v2", LocalPath: "../dict"},
+				},
+			},
+		},
+	}
+	data := &datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-ds-node-1",
+			Namespace: "default",
+			Labels: map[string]string{
+				datav1alpha1.KudaKeyPrefetch: "test-ds",
+				datav1alpha1.KudaKeyNode:     "node-1",
+			},
+		},
+		Status: datav1alpha1.DataStatus{
+			DataItemsStatus: []datav1alpha1.DataItemStatus{
+				{Name: "model", Namespace: "ns", Version: "v1", Phase: datav1alpha1.DataSuccess},
+				{Name: "dict", Namespace: "ns", Version: "v2", Phase: datav1alpha1.DataDownloading},
+			},
+		},
+	}
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	c := fake.NewClientBuilder().WithScheme(getTestScheme()).WithObjects(dataset, data, ns).Build()
+
+	config := DefaultConfig()
+	config.NodeID = "node-1"
+	config.HostPath = hostPath
+	config.HostCacheIsolation = utils.HostCacheIsolationDataSet
+	mounter := &fakeMounter{mounts: make(map[string]string)}
+	driver := NewDriver(config, c, mounter)
+
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:   "csi-test",
+		TargetPath: target,
+		VolumeCapability: &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY},
+		},
+		Readonly: true,
+		VolumeContext: map[string]string{
+			datav1alpha1.KudaCSIVolumeDataSet: "test-ds",
+			volumeAttributePodNamespace:       "default",
+		},
+	}
+	ctx := context.TODO()
+
+	// The volume is unavailable until all data items are prefetched.
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Empty(t, mounter.mounts)
+
+	data.Status.DataItemsStatus[1].Phase = datav1alpha1.DataSuccess
+	assert.NoError(t, c.Status().Update(ctx, data))
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	cacheDir := filepath.Join(hostPath, "default/test-ds/ns")
+	assert.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "model/v1"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "dict/v2"), 0755))
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		target:                                "tmpfs",
+		filepath.Join(target, "models/model"): filepath.Join(cacheDir, "model/v1"),
+		filepath.Join(target, "dict"):         filepath.Join(cacheDir, "dict/v2"),
+	}, mounter.mounts)
+
+	// Publishing is idempotent.
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.NoError(t, err)
+	assert.Len(t, mounter.mounts, 3)
+
+	unpublish := &csi.NodeUnpublishVolumeRequest{VolumeId: "csi-test", TargetPath: target}
+	_, err = driver.NodeUnpublishVolume(ctx, unpublish)
+	assert.NoError(t, err)
+	assert.Empty(t, mounter.mounts)
+	// Unpublishing is idempotent.
+	_, err = driver.NodeUnpublishVolume(ctx, unpublish)
+	assert.NoError(t, err)
+
+	// The host cache is disabled for the namespace.
+	ns.Labels = map[string]string{datav1alpha1.KudaKeyHostCache: datav1alpha1.HostCacheDisabled}
+	assert.NoError(t, c.Update(ctx, ns))
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The dataset of another namespace is not accessible.
+	req.VolumeContext[datav1alpha1.KudaCSIVolumeNamespace] = "kube-system"
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	req.VolumeContext[datav1alpha1.KudaCSIVolumeNamespace] = "default"
+	delete(req.VolumeContext, volumeAttributePodNamespace)
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	req.VolumeContext[volumeAttributePodNamespace] = "default"
+
+	req.VolumeContext[datav1alpha1.KudaCSIVolumeDataSet] = "not-found"
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	delete(req.VolumeContext, datav1alpha1.KudaCSIVolumeDataSet)
+	_, err = driver.NodePublishVolume(ctx, req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,346 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dedup deduplicates the identical data items in the host cache. It's used by the
+// kuda runtime and the node agent.
+//
+// The content of a data item is downloaded once per node into the content store, keyed by
+// its data source, remote path and version, or by its checksum if declared. The files are
+// then hard linked into the version directories of the data items referencing it, so the
+// number of the links of the files counts the references, and the content no longer linked
+// anywhere is removed by the node agent.
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	// DirName is the directory of the content store in the host cache of a tenant, which
+	// is <hostPath>/<tenant>/.kuda-content. It's in the same file system as the version
+	// directories to be hard linked, and the dedup never crosses the tenants.
+	DirName = ".kuda-content"
+
+	tmpPrefix  = ".tmp-"
+	lockSuffix = ".lock"
+
+	defaultLockTimeout = time.Hour
+	defaultInterval    = time.Second
+
+	dataSourceTypeHdfs    = "hdfs"
+	dataSourceTypeAlluxio = "alluxio"
+)
+
+// Key returns the key of the content of the data item. The data items of the same data
+// source, remote path and version share the content, or the ones of the same checksum
+// whatever else. The archive format and the sync policy are part of the key, since they
+// change the files downloaded.
+func Key(item *datav1alpha1.DataItem, sources *datav1alpha1.DataSources) string {
+	var parts []string
+	if item.Checksum != "" {
+		parts = []string{"checksum", item.Checksum}
+	} else {
+		parts = []string{"source", item.DataSourceType, sourceIdentity(item.DataSourceType, sources), item.RemotePath, item.Version}
+	}
+	parts = append(parts, string(item.Format))
+	if item.Sync != nil {
+		parts = append(parts, string(item.Sync.Mode), strings.Join(item.Sync.Include, ","), strings.Join(item.Sync.Exclude, ","))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// sourceIdentity returns the identity of the data source, the remote paths of which are
+// the same data.
+func sourceIdentity(sourceType string, sources *datav1alpha1.DataSources) string {
+	if sources == nil {
+		return ""
+	}
+	switch {
+	case sourceType == dataSourceTypeHdfs && sources.Hdfs != nil:
+		addresses := append([]string(nil), sources.Hdfs.Addresses...)
+		sort.Strings(addresses)
+		return strings.Join(addresses, ",")
+	case sourceType == dataSourceTypeAlluxio && sources.Alluxio != nil:
+		return fmt.Sprintf("%s:%d", sources.Alluxio.Host, sources.Alluxio.Port)
+	}
+	return ""
+}
+
+// Store is the content store in the host cache of a tenant.
+type Store struct {
+	// Root is the directory of the content store.
+	Root string
+	// LockTimeout is the time after which the lock of a content being downloaded is taken
+	// as stale, e.g. the kuda runtime was killed. Defaults to 1h.
+	LockTimeout time.Duration
+	// Interval of checking the content being downloaded by another kuda runtime. Defaults
+	// to 1s.
+	Interval time.Duration
+}
+
+// Entry is a content in the store.
+type Entry struct {
+	Key  string
+	Path string
+	// Size is the bytes of the regular files of the content.
+	Size int64
+	// ModTime is the time the content was stored.
+	ModTime time.Time
+}
+
+// Path returns the directory of the content.
+func (s *Store) Path(key string) string {
+	return filepath.Join(s.Root, key)
+}
+
+// Populate downloads the content by the function unless it's in the store already, and
+// returns true if it was. The content is downloaded into a temporary directory and moved
+// into the store once complete. Only one of the kuda runtimes on the node downloads the
+// same content at a time, and the others wait for it. The files stored are made read-only,
+// since they're shared by all the data items linked to them.
+func (s *Store) Populate(ctx context.Context, key string, download func(dir string) error) (bool, error) {
+	if err := os.MkdirAll(s.Root, 0755); err != nil {
+		return false, err
+	}
+
+	for {
+		if _, err := os.Stat(s.Path(key)); err == nil {
+			return true, nil
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+
+		locked, err := s.lock(key)
+		if err != nil {
+			return false, err
+		}
+		if locked {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(s.interval()):
+		}
+	}
+	defer os.Remove(s.Path(key) + lockSuffix)
+
+	// The content may be stored while the lock was being taken.
+	if _, err := os.Stat(s.Path(key)); err == nil {
+		return true, nil
+	}
+	tmp, err := ioutil.TempDir(s.Root, tmpPrefix+key+"-")
+	if err != nil {
+		return false, err
+	}
+	err = download(tmp)
+	if err == nil {
+		err = makeReadOnly(tmp)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return false, err
+	}
+	if err := os.Rename(tmp, s.Path(key)); err != nil {
+		os.RemoveAll(tmp)
+		return false, err
+	}
+	return false, nil
+}
+
+// lock takes the lock of the content, the stale lock is broken.
+func (s *Store) lock(key string) (bool, error) {
+	file := s.Path(key) + lockSuffix
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err == nil {
+		return true, f.Close()
+	}
+	if !os.IsExist(err) {
+		return false, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		// The lock was released meanwhile.
+		return false, nil
+	}
+	if time.Since(info.ModTime()) > s.lockTimeout() {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// Link hard links the files of the content into the directory, which is created. The
+// files are copied if they can't be linked, which are not counted as references then.
+func (s *Store) Link(key, dir string) error {
+	src := s.Path(key)
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(dst, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, dst)
+		case info.Mode().IsRegular():
+			if err := os.Link(p, dst); err == nil {
+				return nil
+			}
+			return copyFile(p, dst, info)
+		}
+		return nil
+	})
+}
+
+// Unreferenced returns the contents not linked into any version directory, which were
+// stored before the time. The contents without any regular file are never referenced,
+// they're returned once stored before the time too.
+func (s *Store) Unreferenced(before time.Time) ([]Entry, error) {
+	infos, err := ioutil.ReadDir(s.Root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0)
+	for _, info := range infos {
+		name := info.Name()
+		if !info.IsDir() || strings.HasPrefix(name, ".") || !info.ModTime().Before(before) {
+			continue
+		}
+		entry := Entry{Key: name, Path: filepath.Join(s.Root, name), ModTime: info.ModTime()}
+		referenced := false
+		err := filepath.Walk(entry.Path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				entry.Size += info.Size()
+				if _, _, nlink, _ := FileLinks(info); nlink > 1 {
+					referenced = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !referenced {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Remove removes the content from the store.
+func (s *Store) Remove(key string) error {
+	return os.RemoveAll(s.Path(key))
+}
+
+// RemoveStale removes the temporary directories of the downloads, which started before the
+// time and were interrupted.
+func (s *Store) RemoveStale(before time.Time) error {
+	infos, err := ioutil.ReadDir(s.Root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), tmpPrefix) && info.ModTime().Before(before) {
+			if err := os.RemoveAll(filepath.Join(s.Root, info.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// makeReadOnly removes the write permission of the regular files in the directory.
+func makeReadOnly(dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return os.Chmod(p, info.Mode().Perm()&^0222)
+	})
+}
+
+func (s *Store) lockTimeout() time.Duration {
+	if s.LockTimeout > 0 {
+		return s.LockTimeout
+	}
+	return defaultLockTimeout
+}
+
+func (s *Store) interval() time.Duration {
+	if s.Interval > 0 {
+		return s.Interval
+	}
+	return defaultInterval
+}
+
+// copyFile copies the file with its mode and modification time.
+func copyFile(src, dst string, info os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedup
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestKey(t *testing.T) {
+	sources := &datav1alpha1.DataSources{Hdfs: &datav1alpha1.HdfsDataSource{Addresses: []string{"nn-1:8020", "nn-2:8020"}}}
+	item := &datav1alpha1.DataItem{Name: "ranker", Namespace: "team-a", RemotePath: "/models/ranker/v1", Version: "v1", DataSourceType: "hdfs"}
+
+	// The data items of other names and namespaces share the content.
+	other := item.DeepCopy()
+	other.Name, other.Namespace, other.LocalPath = "model", "team-b", "/model"
+	reordered := sources.DeepCopy()
+	reordered.Hdfs.Addresses = []string{"nn-2:8020", "nn-1:8020"}
+	assert.Equal(t, Key(item, sources), Key(other, reordered))
+
+	for name, mutate := range map[string]func(*datav1alpha1.DataItem){
+		"remote path": func(i *datav1alpha1.DataItem) { i.RemotePath = "/models/ranker/v2" },
+		"version":     func(i *datav1alpha1.DataItem) { i.Version = "v2" },
+		"format":      func(i *datav1alpha1.DataItem) { i.Format = datav1alpha1.DataFormatTarGz },
+		"sync":        func(i *datav1alpha1.DataItem) { i.Sync = &datav1alpha1.SyncPolicy{Exclude: []string{"_SUCCESS"}} },
+	} {
+		changed := item.DeepCopy()
+		mutate(changed)
+		assert.NotEqual(t, Key(item, sources), Key(changed, sources), name)
+	}
+	assert.NotEqual(t, Key(item, sources), Key(item, &datav1alpha1.DataSources{Hdfs: &datav1alpha1.HdfsDataSource{Addresses: []string{"nn-3:8020"}}}))
+
+	// The data items of the same checksum share the content whatever the data sources.
+	item.Checksum = "sha256:0123abcd"
+	other.Checksum = "sha256:0123abcd"
+	other.RemotePath, other.DataSourceType = "/ranker", "alluxio"
+	assert.Equal(t, Key(item, sources), Key(other, nil))
+}
+
+func TestStore(t *testing.T) {
+	root := t.TempDir()
+	store := &Store{Root: filepath.Join(root, DirName), Interval: 10 * time.Millisecond}
+	downloads := new(int32)
+	download := func(dir string) error {
+		atomic.AddInt32(downloads, 1)
+		time.Sleep(50 * time.Millisecond)
+		if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, "sub", "model.bin"), []byte("model"), 0644)
+	}
+
+	// The kuda runtimes on the node download the same content at the same time.
+	var wg sync.WaitGroup
+	reused := new(int32)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := store.Populate(context.Background(), "key", download)
+			assert.NoError(t, err)
+			if ok {
+				atomic.AddInt32(reused, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(downloads))
+	assert.Equal(t, int32(2), atomic.LoadInt32(reused))
+
+	info, err := os.Stat(filepath.Join(store.Path("key"), "sub", "model.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	// The content just stored is not collected before linked.
+	unreferenced, err := store.Unreferenced(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, unreferenced)
+
+	versions := []string{filepath.Join(root, "team-a", "ranker", "v1"), filepath.Join(root, "team-b", "model", "v1")}
+	for _, dir := range versions {
+		assert.NoError(t, store.Link("key", dir))
+		data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "model.bin"))
+		assert.NoError(t, err)
+		assert.Equal(t, "model", string(data))
+	}
+	unreferenced, err = store.Unreferenced(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, unreferenced)
+
+	// The content is unreferenced once all the versions linked to it are removed.
+	assert.NoError(t, os.RemoveAll(versions[0]))
+	unreferenced, err = store.Unreferenced(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, unreferenced)
+	assert.NoError(t, os.RemoveAll(versions[1]))
+	unreferenced, err = store.Unreferenced(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, unreferenced, 1) {
+		assert.Equal(t, "key", unreferenced[0].Key)
+		assert.Equal(t, int64(5), unreferenced[0].Size)
+	}
+	assert.NoError(t, store.Remove("key"))
+	_, err = os.Stat(store.Path("key"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStorePopulateFailed(t *testing.T) {
+	store := &Store{Root: t.TempDir(), Interval: 10 * time.Millisecond}
+
+	_, err := store.Populate(context.Background(), "key", func(dir string) error {
+		return errors.New("connection refused")
+	})
+	assert.Error(t, err)
+	_, err = os.Stat(store.Path("key"))
+	assert.True(t, os.IsNotExist(err))
+
+	// The lock is released, and the next download succeeds.
+	ok, err := store.Populate(context.Background(), "key", func(dir string) error { return nil })
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// The stale lock left by a killed kuda runtime is broken.
+	store.LockTimeout = time.Minute
+	assert.NoError(t, ioutil.WriteFile(store.Path("stale")+lockSuffix, nil, 0644))
+	stale := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(store.Path("stale")+lockSuffix, stale, stale))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ok, err = store.Populate(ctx, "stale", func(dir string) error { return nil })
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// The temporary directories of the interrupted downloads are removed.
+	tmp, err := ioutil.TempDir(store.Root, tmpPrefix)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chtimes(tmp, stale, stale))
+	assert.NoError(t, store.RemoveStale(time.Now().Add(-time.Minute)))
+	_, err = os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package delta computes the file-level delta of a data item between its previous local
+// version and the new remote version by the delta policy, so that only the changed files
+// are fetched into the directory of the new version.
+package delta
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// File describes a regular file of a version.
+type File struct {
+	// Path is the slash separated path relative to the version directory.
+	Path    string
+	Size    int64
+	ModTime time.Time
+	// Checksum is the hex encoded SHA-256 of the file, empty if unknown.
+	Checksum string
+}
+
+// Plan is the delta of the new version against the previous one.
+type Plan struct {
+	// Reuse are the files of the new version unchanged since the previous one.
+	Reuse []File
+	// Fetch are the files of the new version changed or added since the previous one.
+	Fetch []File
+}
+
+// Status returns the status of the delta.
+func (p *Plan) Status() *datav1alpha1.DeltaStatus {
+	status := &datav1alpha1.DeltaStatus{Reused: len(p.Reuse), Fetched: len(p.Fetch)}
+	for _, file := range p.Reuse {
+		status.BytesSaved += file.Size
+	}
+	for _, file := range p.Fetch {
+		status.BytesFetched += file.Size
+	}
+	return status
+}
+
+// ValidateManifestPath returns an error if the manifest path is not a relative path
+// within the version directory.
+func ValidateManifestPath(manifest string) error {
+	clean := path.Clean(manifest)
+	if manifest == "" || path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("must be a relative path within the remote path")
+	}
+	return nil
+}
+
+// ReadManifest parses the checksum manifest in the format of the output of sha256sum, and
+// returns the checksums by the slash separated relative paths.
+func ReadManifest(r io.Reader) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Each line is the checksum and the path, which is prefixed by "*" in binary mode.
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: missing the file path", n)
+		}
+		checksum := strings.ToLower(fields[0])
+		if b, err := hex.DecodeString(checksum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("line %d: invalid sha256 checksum %q", n, fields[0])
+		}
+		name := strings.TrimPrefix(strings.TrimLeft(fields[1], " "), "*")
+		name = path.Clean(strings.TrimPrefix(name, "./"))
+		checksums[name] = checksum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// SetChecksums sets the checksums of the files listed in the manifest.
+func SetChecksums(files []File, checksums map[string]string) {
+	for i := range files {
+		files[i].Checksum = checksums[files[i].Path]
+	}
+}
+
+// ScanDir returns the regular files of the local version directory, sorted by the paths.
+func ScanDir(dir string) ([]File, error) {
+	files := make([]File, 0)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// Compute returns the delta of the remote files of the new version against the local files
+// of the previous version by the policy. With the SizeModTime comparison, a file is reused
+// if its size and modification time are unchanged. With the Checksum comparison, a file is
+// reused if its size and checksum are unchanged, and the files without a checksum on either
+// side are always fetched. The manifest itself is always fetched.
+func Compute(previous, remote []File, policy *datav1alpha1.DeltaPolicy) *Plan {
+	compare := datav1alpha1.DeltaCompareSizeModTime
+	manifest := ""
+	if policy != nil {
+		if policy.Compare != "" {
+			compare = policy.Compare
+		}
+		if policy.Manifest != "" {
+			manifest = path.Clean(policy.Manifest)
+		}
+	}
+
+	local := make(map[string]File, len(previous))
+	for _, file := range previous {
+		local[file.Path] = file
+	}
+
+	plan := &Plan{Reuse: make([]File, 0), Fetch: make([]File, 0)}
+	for _, file := range remote {
+		old, ok := local[file.Path]
+		unchanged := false
+		switch {
+		case !ok || old.Size != file.Size || file.Path == manifest:
+		case compare == datav1alpha1.DeltaCompareChecksum:
+			unchanged = file.Checksum != "" && old.Checksum == file.Checksum
+		default:
+			unchanged = old.ModTime.Equal(file.ModTime)
+		}
+
+		if unchanged {
+			plan.Reuse = append(plan.Reuse, file)
+		} else {
+			plan.Fetch = append(plan.Fetch, file)
+		}
+	}
+	return plan
+}
+
+// Apply makes the reused files of the plan in the directory of the new version from the
+// directory of the previous one. The files are hard linked, or copied if they can't be
+// linked, e.g. across file systems. The files fetched are left to the caller.
+func Apply(previousDir, dir string, plan *Plan) error {
+	for _, file := range plan.Reuse {
+		src := filepath.Join(previousDir, filepath.FromSlash(file.Path))
+		dst := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Link(src, dst); err == nil {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			return fmt.Errorf("failed to reuse %s: %v", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file with its mode and modification time.
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
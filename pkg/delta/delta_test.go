@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	sumA = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	sumB = "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+	sumC = "2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6"
+)
+
+func TestReadManifest(t *testing.T) {
+	checksums, err := ReadManifest(strings.NewReader(`# generated by the pipeline
+` + sumA + `  part-0.parquet
+` + strings.ToUpper(sumB) + ` *./dict/part-1.parquet
+
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"part-0.parquet": sumA, "dict/part-1.parquet": sumB}, checksums)
+
+	_, err = ReadManifest(strings.NewReader("abc  part-0.parquet\n"))
+	assert.Error(t, err)
+	_, err = ReadManifest(strings.NewReader(sumA + "\n"))
+	assert.Error(t, err)
+}
+
+func TestCompute(t *testing.T) {
+	mtime := time.Date(2021, 11, 8, 0, 0, 0, 0, time.UTC)
+	previous := []File{
+		{Path: "SHA256SUMS", Size: 100, ModTime: mtime},
+		{Path: "part-0", Size: 10, ModTime: mtime},
+		{Path: "part-1", Size: 20, ModTime: mtime},
+		{Path: "part-2", Size: 30, ModTime: mtime},
+		{Path: "removed", Size: 40, ModTime: mtime},
+	}
+	remote := []File{
+		{Path: "SHA256SUMS", Size: 100, ModTime: mtime},
+		{Path: "part-0", Size: 10, ModTime: mtime},
+		{Path: "part-1", Size: 20, ModTime: mtime.Add(time.Hour)},
+		{Path: "part-2", Size: 31, ModTime: mtime},
+		{Path: "part-3", Size: 50, ModTime: mtime},
+	}
+	paths := func(files []File) []string {
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.Path)
+		}
+		return names
+	}
+
+	plan := Compute(previous, remote, nil)
+	assert.Equal(t, []string{"SHA256SUMS", "part-0"}, paths(plan.Reuse))
+	assert.Equal(t, []string{"part-1", "part-2", "part-3"}, paths(plan.Fetch))
+	assert.Equal(t, &datav1alpha1.DeltaStatus{Reused: 2, Fetched: 3, BytesSaved: 110, BytesFetched: 101}, plan.Status())
+
+	// The files rewritten with the same content are reused by the checksums.
+	SetChecksums(previous, map[string]string{"part-0": sumA, "part-1": sumB, "part-2": sumC})
+	SetChecksums(remote, map[string]string{"part-0": sumA, "part-1": sumB, "part-2": sumC, "part-3": sumC})
+	remote[0].Checksum = ""
+	plan = Compute(previous, remote, &datav1alpha1.DeltaPolicy{Compare: datav1alpha1.DeltaCompareChecksum, Manifest: "SHA256SUMS"})
+	assert.Equal(t, []string{"part-0", "part-1"}, paths(plan.Reuse))
+	assert.Equal(t, []string{"SHA256SUMS", "part-2", "part-3"}, paths(plan.Fetch))
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	previousDir := filepath.Join(root, "v1")
+	dir := filepath.Join(root, "v2")
+	assert.NoError(t, os.MkdirAll(filepath.Join(previousDir, "dict"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(previousDir, "part-0"), []byte("a"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(previousDir, "dict", "part-1"), []byte("b"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(previousDir, "part-2"), []byte("c"), 0644))
+
+	previous, err := ScanDir(previousDir)
+	assert.NoError(t, err)
+	assert.Len(t, previous, 3)
+	assert.Equal(t, "dict/part-1", previous[0].Path)
+
+	remote := []File{previous[0], previous[1], {Path: "part-2", Size: 2, ModTime: previous[2].ModTime}}
+	plan := Compute(previous, remote, nil)
+	assert.NoError(t, Apply(previousDir, dir, plan))
+
+	current, err := ScanDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, previous[:2], current)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "dict", "part-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(content))
+	_, err = os.Stat(filepath.Join(dir, "part-2"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestValidateManifestPath(t *testing.T) {
+	assert.NoError(t, ValidateManifestPath("SHA256SUMS"))
+	assert.NoError(t, ValidateManifestPath("meta/SHA256SUMS"))
+	for _, manifest := range []string{"", ".", "/SHA256SUMS", "../SHA256SUMS"} {
+		assert.Error(t, ValidateManifestPath(manifest), manifest)
+	}
+}
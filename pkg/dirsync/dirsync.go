@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dirsync syncs the downloaded directory of a data item into its local path by the
+// sync policy.
+package dirsync
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// ValidatePattern returns an error if the glob pattern is malformed.
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("pattern must not be empty")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	return nil
+}
+
+// Match returns true if the file of the slash separated relative path is synced by the
+// policy. A pattern without a slash matches the file name, otherwise the whole path.
+func Match(policy *datav1alpha1.SyncPolicy, rel string) bool {
+	if policy == nil {
+		return true
+	}
+	if matchAny(policy.Exclude, rel) {
+		return false
+	}
+	return len(policy.Include) == 0 || matchAny(policy.Include, rel)
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync syncs the files of the directory src into the directory dst by the policy, and
+// returns the changes of the files in dst. A file is changed if its size or modification
+// time differs from the source. In the Mirror mode, the files not synced are removed from
+// dst, as well as the directories left empty. Only the regular files are synced.
+func Sync(src, dst string, policy *datav1alpha1.SyncPolicy) (*datav1alpha1.FileChanges, error) {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return nil, err
+	}
+	changes := &datav1alpha1.FileChanges{}
+	synced := make(map[string]bool)
+
+	err := filepath.Walk(src, func(srcPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, srcPath)
+		if err != nil {
+			return err
+		}
+		if !Match(policy, filepath.ToSlash(rel)) {
+			return nil
+		}
+		synced[rel] = true
+
+		dstPath := filepath.Join(dst, rel)
+		dstInfo, err := os.Lstat(dstPath)
+		switch {
+		case os.IsNotExist(err):
+			changes.Added += 1
+		case err != nil:
+			return err
+		case dstInfo.Mode().IsRegular() && dstInfo.Size() == info.Size() && dstInfo.ModTime().Equal(info.ModTime()):
+			return nil
+		default:
+			changes.Changed += 1
+		}
+		return copyFile(srcPath, dstPath, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if policy != nil && policy.Mode == datav1alpha1.SyncModeMirror {
+		removed, err := prune(dst, synced)
+		if err != nil {
+			return nil, err
+		}
+		changes.Removed = removed
+	}
+	return changes, nil
+}
+
+// copyFile copies the file into a temporary file next to dst and renames it to dst, so
+// that dst is never seen partially written.
+func copyFile(src, dst string, info os.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := filepath.Join(filepath.Dir(dst), ".kuda-sync-"+filepath.Base(dst))
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if dstInfo, err := os.Lstat(dst); err == nil && dstInfo.IsDir() {
+		if err := os.RemoveAll(dst); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dst)
+}
+
+// prune removes the files of dst not synced and the directories left empty, and returns
+// the number of the files removed.
+func prune(dst string, synced map[string]bool) (int, error) {
+	removed := 0
+	dirs := make([]string, 0)
+	err := filepath.Walk(dst, func(dstPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, dstPath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel != "." {
+				dirs = append(dirs, dstPath)
+			}
+			return nil
+		}
+		if synced[rel] {
+			return nil
+		}
+		if err := os.Remove(dstPath); err != nil {
+			return err
+		}
+		removed += 1
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	// The deeper directories are removed first, and the ones not empty are kept.
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return removed, err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(dir); err != nil {
+				return removed, err
+			}
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dirsync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	files := make(map[string]string)
+	assert.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return err
+	}))
+	return files
+}
+
+func TestMatch(t *testing.T) {
+	policy := &datav1alpha1.SyncPolicy{
+		Include: []string{"*.parquet", "meta/*.json"},
+		Exclude: []string{"_SUCCESS", "*.crc", "tmp-*"},
+	}
+	for rel, want := range map[string]bool{
+		"part-0000.parquet":      true,
+		"day=01/part-0.parquet":  true,
+		"meta/schema.json":       true,
+		"day=01/meta/stats.json": false,
+		"_SUCCESS":               false,
+		"day=01/_SUCCESS":        false,
+		".part-0.parquet.crc":    false,
+		"tmp-0.parquet":          false,
+		"README":                 false,
+	} {
+		assert.Equal(t, want, Match(policy, rel), rel)
+	}
+
+	assert.True(t, Match(nil, "_SUCCESS"))
+	assert.True(t, Match(&datav1alpha1.SyncPolicy{Exclude: []string{"*.crc"}}, "README"))
+}
+
+func TestValidatePattern(t *testing.T) {
+	assert.NoError(t, ValidatePattern("part-*/*.parquet"))
+	assert.Error(t, ValidatePattern("part-[0-9"))
+	assert.Error(t, ValidatePattern(""))
+}
+
+func TestSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuda-dirsync")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+
+	writeFiles(t, src, map[string]string{
+		"day=01/part-0.parquet": "a",
+		"day=01/_SUCCESS":       "",
+		"day=02/part-0.parquet": "b",
+		"day=02/.part-0.crc":    "crc",
+	})
+	policy := &datav1alpha1.SyncPolicy{Exclude: []string{"_SUCCESS", "*.crc"}}
+	changes, err := Sync(src, dst, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, &datav1alpha1.FileChanges{Added: 2}, changes)
+	assert.Equal(t, map[string]string{"day=01/part-0.parquet": "a", "day=02/part-0.parquet": "b"}, readFiles(t, dst))
+
+	// Nothing changes if synced again.
+	changes, err = Sync(src, dst, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, &datav1alpha1.FileChanges{}, changes)
+
+	// The files removed upstream are kept in the Copy mode.
+	assert.NoError(t, os.RemoveAll(filepath.Join(src, "day=01")))
+	writeFiles(t, src, map[string]string{"day=02/part-0.parquet": "bb", "day=03/part-0.parquet": "c"})
+	changes, err = Sync(src, dst, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, &datav1alpha1.FileChanges{Added: 1, Changed: 1}, changes)
+	assert.Equal(t, map[string]string{
+		"day=01/part-0.parquet": "a",
+		"day=02/part-0.parquet": "bb",
+		"day=03/part-0.parquet": "c",
+	}, readFiles(t, dst))
+}
+
+func TestSyncMirror(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuda-dirsync")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+
+	writeFiles(t, src, map[string]string{
+		"day=02/part-0.parquet": "b",
+		"day=02/_SUCCESS":       "",
+	})
+	writeFiles(t, dst, map[string]string{
+		"day=01/part-0.parquet": "a",
+		"day=02/part-0.parquet": "b",
+		"day=02/_SUCCESS":       "",
+	})
+	// The content is the same but the modification time differs.
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dst, "day=02/part-0.parquet"), past, past))
+
+	policy := &datav1alpha1.SyncPolicy{Mode: datav1alpha1.SyncModeMirror, Exclude: []string{"_SUCCESS"}}
+	changes, err := Sync(src, dst, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, &datav1alpha1.FileChanges{Changed: 1, Removed: 2}, changes)
+	assert.Equal(t, map[string]string{"day=02/part-0.parquet": "b"}, readFiles(t, dst))
+
+	// The directories left empty are removed.
+	_, err = os.Stat(filepath.Join(dst, "day=01"))
+	assert.True(t, os.IsNotExist(err))
+}
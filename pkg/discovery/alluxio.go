@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// alluxioProxy lists the directories through the REST API of the Alluxio proxy.
+type alluxioProxy struct {
+	address string
+	client  *http.Client
+}
+
+type alluxioURIStatus struct {
+	Name   string `json:"name"`
+	Folder bool   `json:"folder"`
+}
+
+func (s *alluxioProxy) ListDirs(ctx context.Context, dir string) ([]string, error) {
+	body, err := s.post(ctx, dir, "list-status")
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]alluxioURIStatus, 0)
+	if err := json.Unmarshal(body, &statuses); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		if status.Folder {
+			names = append(names, status.Name)
+		}
+	}
+	return names, nil
+}
+
+func (s *alluxioProxy) Exists(ctx context.Context, file string) (bool, error) {
+	body, err := s.post(ctx, file, "exists")
+	if err != nil {
+		return false, err
+	}
+	exists := false
+	if err := json.Unmarshal(body, &exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// post calls the operation on the path.
+func (s *alluxioProxy) post(ctx context.Context, path, op string) ([]byte, error) {
+	u := url.URL{Scheme: "http", Host: s.address, Path: "/api/v1/paths/" + strings.TrimPrefix(path, "/") + "/" + op}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader("{}"))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s on %s: %s", op, path, s.address, resp.Status)
+	}
+	return body, nil
+}
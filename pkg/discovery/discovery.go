@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package discovery discovers the versions of the data items from the remote stores by
+// their version policies.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/version"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	SourceTypeHdfs    = "hdfs"
+	SourceTypeAlluxio = "alluxio"
+
+	// DefaultInterval is the default interval of polling the remote store.
+	DefaultInterval = 5 * time.Minute
+
+	defaultAlluxioProxyPort = 39999
+)
+
+// Store lists the directories of a remote store.
+type Store interface {
+	// ListDirs returns the names of the sub directories of the directory.
+	ListDirs(ctx context.Context, dir string) ([]string, error)
+	// Exists returns true if the file exists.
+	Exists(ctx context.Context, file string) (bool, error)
+}
+
+// NewStore returns the store of the data source of the type.
+func NewStore(sources *datav1alpha1.DataSources, sourceType string, client *http.Client) (Store, error) {
+	switch {
+	case sources == nil:
+	case sourceType == SourceTypeHdfs && sources.Hdfs != nil:
+		if len(sources.Hdfs.HTTPAddresses) == 0 {
+			return nil, fmt.Errorf("httpAddresses of the hdfs data source must be set to discover versions")
+		}
+		return &webHDFS{addresses: sources.Hdfs.HTTPAddresses, userName: sources.Hdfs.UserName, client: client}, nil
+	case sourceType == SourceTypeAlluxio && sources.Alluxio != nil:
+		port := sources.Alluxio.ProxyPort
+		if port == 0 {
+			port = defaultAlluxioProxyPort
+		}
+		return &alluxioProxy{address: fmt.Sprintf("%s:%d", sources.Alluxio.Host, port), client: client}, nil
+	}
+	return nil, fmt.Errorf("data source %s is not configured", sourceType)
+}
+
+// Interval returns the interval of polling the remote store for the policy.
+func Interval(policy *datav1alpha1.VersionPolicy) time.Duration {
+	if policy.Interval == nil || policy.Interval.Duration <= 0 {
+		return DefaultInterval
+	}
+	return policy.Interval.Duration
+}
+
+// Latest returns the latest version in the parent path by the order, whose marker file
+// exists if required. It's empty if there is no version.
+func Latest(ctx context.Context, store Store, policy *datav1alpha1.VersionPolicy) (string, error) {
+	names, err := store.ListDirs(ctx, policy.ParentPath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range SortVersions(names, policy.Order) {
+		if policy.MarkerFile == "" {
+			return name, nil
+		}
+		exists, err := store.Exists(ctx, path.Join(policy.ParentPath, name, policy.MarkerFile))
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// RemotePath returns the remote path of the version.
+func RemotePath(policy *datav1alpha1.VersionPolicy, version string) string {
+	return path.Join(policy.ParentPath, version)
+}
+
+// SortVersions returns the versions from the latest to the earliest by the order, the
+// names not valid in the order are skipped.
+func SortVersions(names []string, order datav1alpha1.VersionOrder) []string {
+	type parsed struct {
+		name   string
+		number *big.Float
+		semver *version.Version
+	}
+	versions := make([]parsed, 0, len(names))
+	for _, name := range names {
+		v := parsed{name: name}
+		switch order {
+		case datav1alpha1.VersionOrderNumeric:
+			number, ok := new(big.Float).SetString(name)
+			if !ok || number.IsInf() {
+				continue
+			}
+			v.number = number
+		case datav1alpha1.VersionOrderSemVer:
+			semver, err := version.ParseSemantic(name)
+			if err != nil {
+				continue
+			}
+			v.semver = semver
+		}
+		versions = append(versions, v)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := versions[i], versions[j]
+		switch {
+		case a.number != nil:
+			if c := a.number.Cmp(b.number); c != 0 {
+				return c > 0
+			}
+		case a.semver != nil:
+			if a.semver.LessThan(b.semver) || b.semver.LessThan(a.semver) {
+				return b.semver.LessThan(a.semver)
+			}
+		}
+		return a.name > b.name
+	})
+
+	sorted := make([]string, 0, len(versions))
+	for _, v := range versions {
+		sorted = append(sorted, v.name)
+	}
+	return sorted
+}
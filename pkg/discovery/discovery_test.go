@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestSortVersions(t *testing.T) {
+	names := []string{"v1.10.0", "v1.9.0", "10", "9", "20211108", "v1.10.0-rc.1", "latest"}
+
+	assert.Equal(t, []string{"v1.9.0", "v1.10.0-rc.1", "v1.10.0", "latest", "9", "20211108", "10"},
+		SortVersions(names, datav1alpha1.VersionOrderLexical))
+	assert.Equal(t, []string{"20211108", "10", "9"}, SortVersions(names, datav1alpha1.VersionOrderNumeric))
+	assert.Equal(t, []string{"v1.10.0", "v1.10.0-rc.1", "v1.9.0"}, SortVersions(names, datav1alpha1.VersionOrderSemVer))
+}
+
+// fakeStore is a remote store of the directories with the files.
+type fakeStore map[string][]string
+
+func (s fakeStore) ListDirs(ctx context.Context, dir string) ([]string, error) {
+	names, ok := s[dir]
+	if !ok {
+		return nil, fmt.Errorf("directory %s is not found", dir)
+	}
+	return names, nil
+}
+
+func (s fakeStore) Exists(ctx context.Context, file string) (bool, error) {
+	_, ok := s[file]
+	return ok, nil
+}
+
+func TestLatest(t *testing.T) {
+	store := fakeStore{
+		"/models/ranker":                     {"1636358400", "1636444800", "1636531200"},
+		"/models/ranker/1636358400/_SUCCESS": nil,
+		"/models/ranker/1636444800/_SUCCESS": nil,
+	}
+	ctx := context.Background()
+
+	latest, err := Latest(ctx, store, &datav1alpha1.VersionPolicy{ParentPath: "/models/ranker", Order: datav1alpha1.VersionOrderNumeric})
+	assert.NoError(t, err)
+	assert.Equal(t, "1636531200", latest)
+
+	// The version being written has no marker file yet.
+	policy := &datav1alpha1.VersionPolicy{ParentPath: "/models/ranker", MarkerFile: "_SUCCESS"}
+	latest, err = Latest(ctx, store, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "1636444800", latest)
+	assert.Equal(t, "/models/ranker/1636444800", RemotePath(policy, latest))
+
+	latest, err = Latest(ctx, store, &datav1alpha1.VersionPolicy{ParentPath: "/models/ranker", MarkerFile: "_DONE"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", latest)
+
+	_, err = Latest(ctx, store, &datav1alpha1.VersionPolicy{ParentPath: "/models/dict"})
+	assert.Error(t, err)
+}
+
+func TestWebHDFS(t *testing.T) {
+	standby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"RemoteException":{"exception":"StandbyException"}}`, http.StatusForbidden)
+	}))
+	defer standby.Close()
+	active := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "hdfs", r.URL.Query().Get("user.name"))
+		switch r.URL.Path + "?" + r.URL.Query().Get("op") {
+		case "/webhdfs/v1/models/ranker?LISTSTATUS":
+			fmt.Fprint(w, `{"FileStatuses":{"FileStatus":[
+				{"pathSuffix":"20211108","type":"DIRECTORY"},
+				{"pathSuffix":"20211109","type":"DIRECTORY"},
+				{"pathSuffix":"README","type":"FILE"}
+			]}}`)
+		case "/webhdfs/v1/models/ranker/20211108/_SUCCESS?GETFILESTATUS":
+			fmt.Fprint(w, `{"FileStatus":{"type":"FILE"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer active.Close()
+
+	sources := &datav1alpha1.DataSources{Hdfs: &datav1alpha1.HdfsDataSource{
+		UserName:      "hdfs",
+		HTTPAddresses: []string{strings.TrimPrefix(standby.URL, "http://"), strings.TrimPrefix(active.URL, "http://")},
+	}}
+	store, err := NewStore(sources, SourceTypeHdfs, http.DefaultClient)
+	assert.NoError(t, err)
+
+	latest, err := Latest(context.Background(), store, &datav1alpha1.VersionPolicy{ParentPath: "/models/ranker", MarkerFile: "_SUCCESS"})
+	assert.NoError(t, err)
+	assert.Equal(t, "20211108", latest)
+
+	_, err = NewStore(&datav1alpha1.DataSources{Hdfs: &datav1alpha1.HdfsDataSource{}}, SourceTypeHdfs, http.DefaultClient)
+	assert.Error(t, err)
+	_, err = NewStore(sources, SourceTypeAlluxio, http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func TestAlluxioProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		switch r.URL.Path {
+		case "/api/v1/paths/models/ranker/list-status":
+			fmt.Fprint(w, `[{"name":"v1.0.0","folder":true},{"name":"v1.1.0","folder":true},{"name":"v2.0.0.tar","folder":false}]`)
+		case "/api/v1/paths/models/ranker/v1.1.0/_SUCCESS/exists":
+			fmt.Fprint(w, "true")
+		default:
+			fmt.Fprint(w, "false")
+		}
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	assert.NoError(t, err)
+	proxyPort, err := strconv.Atoi(port)
+	assert.NoError(t, err)
+	sources := &datav1alpha1.DataSources{Alluxio: &datav1alpha1.AlluxioDataSource{Host: host, Port: 19998, ProxyPort: proxyPort}}
+	store, err := NewStore(sources, SourceTypeAlluxio, http.DefaultClient)
+	assert.NoError(t, err)
+
+	latest, err := Latest(context.Background(), store, &datav1alpha1.VersionPolicy{
+		ParentPath: "/models/ranker",
+		Order:      datav1alpha1.VersionOrderSemVer,
+		MarkerFile: "_SUCCESS",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", latest)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// webHDFS lists the directories through the WebHDFS REST API of the name nodes. The name
+// nodes are tried in order, so that the standby ones of an HA cluster are skipped.
+type webHDFS struct {
+	addresses []string
+	userName  string
+	client    *http.Client
+}
+
+type webHDFSListStatus struct {
+	FileStatuses struct {
+		FileStatus []struct {
+			PathSuffix string `json:"pathSuffix"`
+			Type       string `json:"type"`
+		} `json:"FileStatus"`
+	} `json:"FileStatuses"`
+}
+
+func (s *webHDFS) ListDirs(ctx context.Context, dir string) ([]string, error) {
+	body, found, err := s.get(ctx, dir, "LISTSTATUS")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("directory %s is not found", dir)
+	}
+
+	status := &webHDFSListStatus{}
+	if err := json.Unmarshal(body, status); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(status.FileStatuses.FileStatus))
+	for _, file := range status.FileStatuses.FileStatus {
+		if file.Type == "DIRECTORY" {
+			names = append(names, file.PathSuffix)
+		}
+	}
+	return names, nil
+}
+
+func (s *webHDFS) Exists(ctx context.Context, file string) (bool, error) {
+	_, found, err := s.get(ctx, file, "GETFILESTATUS")
+	return found, err
+}
+
+// get calls the operation on the path, it returns false if the path is not found.
+func (s *webHDFS) get(ctx context.Context, path, op string) ([]byte, bool, error) {
+	query := url.Values{"op": {op}}
+	if s.userName != "" {
+		query.Set("user.name", s.userName)
+	}
+
+	var lastErr error
+	for _, address := range s.addresses {
+		u := url.URL{Scheme: "http", Host: address, Path: "/webhdfs/v1" + path, RawQuery: query.Encode()}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, false, err
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return body, true, nil
+		case http.StatusNotFound:
+			return nil, false, nil
+		default:
+			// The standby name node responds with a StandbyException.
+			lastErr = fmt.Errorf("%s %s on %s: %s", op, path, address, resp.Status)
+		}
+	}
+	return nil, false, lastErr
+}
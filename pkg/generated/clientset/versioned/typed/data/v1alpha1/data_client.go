@@ -26,7 +26,9 @@ import (
 type DataV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	DatasGetter
+	DataQuotasGetter
 	DataSetsGetter
+	NodeDatasGetter
 }
 
 // DataV1alpha1Client is used to interact with features provided by the data.kuda.io group.
@@ -38,10 +40,18 @@ func (c *DataV1alpha1Client) Datas(namespace string) DataInterface {
 	return newDatas(c, namespace)
 }
 
+func (c *DataV1alpha1Client) DataQuotas(namespace string) DataQuotaInterface {
+	return newDataQuotas(c, namespace)
+}
+
 func (c *DataV1alpha1Client) DataSets(namespace string) DataSetInterface {
 	return newDataSets(c, namespace)
 }
 
+func (c *DataV1alpha1Client) NodeDatas() NodeDataInterface {
+	return newNodeDatas(c)
+}
+
 // NewForConfig creates a new DataV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*DataV1alpha1Client, error) {
 	config := *c
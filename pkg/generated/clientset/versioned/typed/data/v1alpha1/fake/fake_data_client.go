@@ -31,10 +31,18 @@ func (c *FakeDataV1alpha1) Datas(namespace string) v1alpha1.DataInterface {
 	return &FakeDatas{c, namespace}
 }
 
+func (c *FakeDataV1alpha1) DataQuotas(namespace string) v1alpha1.DataQuotaInterface {
+	return &FakeDataQuotas{c, namespace}
+}
+
 func (c *FakeDataV1alpha1) DataSets(namespace string) v1alpha1.DataSetInterface {
 	return &FakeDataSets{c, namespace}
 }
 
+func (c *FakeDataV1alpha1) NodeDatas() v1alpha1.NodeDataInterface {
+	return &FakeNodeDatas{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeDataV1alpha1) RESTClient() rest.Interface {
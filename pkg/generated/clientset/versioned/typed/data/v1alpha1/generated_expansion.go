@@ -19,4 +19,8 @@ package v1alpha1
 
 type DataExpansion interface{}
 
+type DataQuotaExpansion interface{}
+
 type DataSetExpansion interface{}
+
+type NodeDataExpansion interface{}
@@ -25,8 +25,12 @@ import (
 type Interface interface {
 	// Datas returns a DataInformer.
 	Datas() DataInformer
+	// DataQuotas returns a DataQuotaInformer.
+	DataQuotas() DataQuotaInformer
 	// DataSets returns a DataSetInformer.
 	DataSets() DataSetInformer
+	// NodeDatas returns a NodeDataInformer.
+	NodeDatas() NodeDataInformer
 }
 
 type version struct {
@@ -45,7 +49,17 @@ func (v *version) Datas() DataInformer {
 	return &dataInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// DataQuotas returns a DataQuotaInformer.
+func (v *version) DataQuotas() DataQuotaInformer {
+	return &dataQuotaInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // DataSets returns a DataSetInformer.
 func (v *version) DataSets() DataSetInformer {
 	return &dataSetInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// NodeDatas returns a NodeDataInformer.
+func (v *version) NodeDatas() NodeDataInformer {
+	return &nodeDataInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -54,8 +54,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=data.kuda.io, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("datas"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Data().V1alpha1().Datas().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("dataquotas"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Data().V1alpha1().DataQuotas().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("datasets"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Data().V1alpha1().DataSets().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("nodedatas"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Data().V1alpha1().NodeDatas().Informer()}, nil
 
 	}
 
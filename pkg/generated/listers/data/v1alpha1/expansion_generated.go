@@ -25,6 +25,14 @@ type DataListerExpansion interface{}
 // DataNamespaceLister.
 type DataNamespaceListerExpansion interface{}
 
+// DataQuotaListerExpansion allows custom methods to be added to
+// DataQuotaLister.
+type DataQuotaListerExpansion interface{}
+
+// DataQuotaNamespaceListerExpansion allows custom methods to be added to
+// DataQuotaNamespaceLister.
+type DataQuotaNamespaceListerExpansion interface{}
+
 // DataSetListerExpansion allows custom methods to be added to
 // DataSetLister.
 type DataSetListerExpansion interface{}
@@ -32,3 +40,7 @@ type DataSetListerExpansion interface{}
 // DataSetNamespaceListerExpansion allows custom methods to be added to
 // DataSetNamespaceLister.
 type DataSetNamespaceListerExpansion interface{}
+
+// NodeDataListerExpansion allows custom methods to be added to
+// NodeDataLister.
+type NodeDataListerExpansion interface{}
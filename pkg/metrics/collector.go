@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+var (
+	log = ctrl.Log.WithName("metrics")
+
+	dataSetLabels = []string{"namespace", "dataset"}
+
+	descDataItems = prometheus.NewDesc(prometheus.BuildFQName(namespace, "dataset", "data_items"),
+		"Number of data items of all the replicas of the DataSet, partitioned by phase.",
+		append(dataSetLabels, "phase"), nil)
+	descReplicas = prometheus.NewDesc(prometheus.BuildFQName(namespace, "dataset", "replicas"),
+		"Number of replicas with a data resource of the DataSet.",
+		dataSetLabels, nil)
+	descReadyReplicas = prometheus.NewDesc(prometheus.BuildFQName(namespace, "dataset", "ready_replicas"),
+		"Number of replicas with all the data items downloaded successfully.",
+		dataSetLabels, nil)
+	descUpdatedReplicas = prometheus.NewDesc(prometheus.BuildFQName(namespace, "dataset", "updated_replicas"),
+		"Number of replicas whose data resource matches the current template of the DataSet.",
+		dataSetLabels, nil)
+	descUninjectedReplicas = prometheus.NewDesc(prometheus.BuildFQName(namespace, "dataset", "uninjected_replicas"),
+		"Number of pods matching the DataSet but missed the kuda runtime injection.",
+		dataSetLabels, nil)
+	descPodDataItems = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pod", "data_items"),
+		"Number of data items of the pod, partitioned by phase.",
+		append(dataSetLabels, "pod", "phase"), nil)
+)
+
+// DataSetCollector collects the state of DataSets and Data resources at scrape time,
+// so the series of deleted resources disappear without bookkeeping.
+type DataSetCollector struct {
+	reader client.Reader
+	// podLabels enables the per pod metrics, whose cardinality grows with the replicas.
+	podLabels bool
+}
+
+// NewDataSetCollector returns a collector reading the resources from the reader, which
+// is usually the cached client of the manager.
+func NewDataSetCollector(reader client.Reader, podLabels bool) *DataSetCollector {
+	return &DataSetCollector{
+		reader:    reader,
+		podLabels: podLabels,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *DataSetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descDataItems
+	ch <- descReplicas
+	ch <- descReadyReplicas
+	ch <- descUpdatedReplicas
+	ch <- descUninjectedReplicas
+	if c.podLabels {
+		ch <- descPodDataItems
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *DataSetCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+
+	dsList := &datav1alpha1.DataSetList{}
+	if err := c.reader.List(ctx, dsList); err != nil {
+		log.Error(err, "failed to list datasets")
+		return
+	}
+	dataList := &datav1alpha1.DataList{}
+	if err := c.reader.List(ctx, dataList); err != nil {
+		log.Error(err, "failed to list data resources")
+		return
+	}
+
+	dataByDataSet := make(map[string][]*datav1alpha1.Data)
+	for i := range dataList.Items {
+		data := &dataList.Items[i]
+		key := data.Namespace + "/" + data.Labels[datav1alpha1.KudaKeyDataSet]
+		dataByDataSet[key] = append(dataByDataSet[key], data)
+	}
+
+	for _, ds := range dsList.Items {
+		c.collectDataSet(ch, &ds, dataByDataSet[ds.Namespace+"/"+ds.Name])
+	}
+}
+
+func (c *DataSetCollector) collectDataSet(ch chan<- prometheus.Metric, ds *datav1alpha1.DataSet, datas []*datav1alpha1.Data) {
+	labels := []string{ds.Namespace, ds.Name}
+
+	phases := map[datav1alpha1.DataPhase]int{
+		datav1alpha1.DataQueued:      0,
+		datav1alpha1.DataWaiting:     0,
+		datav1alpha1.DataDownloading: 0,
+		datav1alpha1.DataStaged:      0,
+		datav1alpha1.DataSuccess:     0,
+		datav1alpha1.DataFailed:      0,
+	}
+	updated := 0
+	for _, data := range datas {
+		podPhases := countPhases(data)
+		for phase, n := range podPhases {
+			phases[phase] += n
+		}
+		if c.podLabels {
+			for phase, n := range podPhases {
+				ch <- prometheus.MustNewConstMetric(descPodDataItems, prometheus.GaugeValue, float64(n),
+					append(labels, data.Labels[datav1alpha1.KudaKeyPod], string(phase))...)
+			}
+		}
+		if isDataUpdated(ds, data) {
+			updated++
+		}
+	}
+
+	for phase, n := range phases {
+		ch <- prometheus.MustNewConstMetric(descDataItems, prometheus.GaugeValue, float64(n), append(labels, string(phase))...)
+	}
+	ch <- prometheus.MustNewConstMetric(descReplicas, prometheus.GaugeValue, float64(ds.Status.Replicas), labels...)
+	ch <- prometheus.MustNewConstMetric(descReadyReplicas, prometheus.GaugeValue, float64(ds.Status.SuccessReplicas), labels...)
+	ch <- prometheus.MustNewConstMetric(descUpdatedReplicas, prometheus.GaugeValue, float64(updated), labels...)
+	ch <- prometheus.MustNewConstMetric(descUninjectedReplicas, prometheus.GaugeValue, float64(ds.Status.UninjectedReplicas), labels...)
+}
+
+func countPhases(data *datav1alpha1.Data) map[datav1alpha1.DataPhase]int {
+	phases := map[datav1alpha1.DataPhase]int{
+		datav1alpha1.DataQueued:      0,
+		datav1alpha1.DataWaiting:     0,
+		datav1alpha1.DataDownloading: 0,
+		datav1alpha1.DataStaged:      0,
+		datav1alpha1.DataSuccess:     0,
+		datav1alpha1.DataFailed:      0,
+	}
+	for _, item := range data.Status.DataItemsStatus {
+		if _, ok := phases[item.Phase]; ok {
+			phases[item.Phase]++
+		}
+	}
+	return phases
+}
+
+// isDataUpdated returns whether the data is of the current revision of the dataset, the same
+// as the updated replicas counted by the dataset controller.
+func isDataUpdated(ds *datav1alpha1.DataSet, data *datav1alpha1.Data) bool {
+	return ds.Status.CurrentRevision != "" && data.Labels[datav1alpha1.KudaKeyRevision] == ds.Status.CurrentRevision
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestDataSetCollector(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, datav1alpha1.AddToScheme(s))
+
+	items := []datav1alpha1.DataItem{{Name: "test", Namespace: "test", Version: "v1", DataSourceType: "hdfs"}}
+	ds := &datav1alpha1.DataSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: "default"},
+		Spec: datav1alpha1.DataSetSpec{
+			Template: datav1alpha1.DataTemplateSpec{DataItems: items},
+		},
+		Status: datav1alpha1.DataSetStatus{Replicas: 2, SuccessReplicas: 1, CurrentRevision: "test-ds-v2"},
+	}
+	data := &datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-ds-pod",
+			Namespace: "default",
+			Labels: map[string]string{
+				datav1alpha1.KudaKeyDataSet:  "test-ds",
+				datav1alpha1.KudaKeyPod:      "pod",
+				datav1alpha1.KudaKeyRevision: "test-ds-v2",
+			},
+		},
+		Spec: datav1alpha1.DataSpec{DataItems: items},
+		Status: datav1alpha1.DataStatus{
+			DataItemsStatus: datav1alpha1.DataItemsStatus{{Name: "test", Namespace: "test", Phase: datav1alpha1.DataSuccess}},
+		},
+	}
+	// The stale data is of the previous revision, even if its spec equals the template.
+	stale := data.DeepCopy()
+	stale.Name = "test-ds-stale-pod"
+	stale.Labels[datav1alpha1.KudaKeyPod] = "stale-pod"
+	stale.Labels[datav1alpha1.KudaKeyRevision] = "test-ds-v1"
+	cli := fake.NewClientBuilder().WithScheme(s).WithObjects(ds, data, stale).Build()
+
+	tests := []struct {
+		name      string
+		podLabels bool
+		want      map[string]int
+	}{
+		{
+			name: "without pod labels",
+			want: map[string]int{
+				"kuda_dataset_data_items":          6,
+				"kuda_dataset_replicas":            1,
+				"kuda_dataset_ready_replicas":      1,
+				"kuda_dataset_updated_replicas":    1,
+				"kuda_dataset_uninjected_replicas": 1,
+			},
+		},
+		{
+			name:      "with pod labels",
+			podLabels: true,
+			want: map[string]int{
+				"kuda_dataset_data_items":          6,
+				"kuda_dataset_replicas":            1,
+				"kuda_dataset_ready_replicas":      1,
+				"kuda_dataset_updated_replicas":    1,
+				"kuda_dataset_uninjected_replicas": 1,
+				"kuda_pod_data_items":              12,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			assert.NoError(t, reg.Register(NewDataSetCollector(cli, tt.podLabels)))
+
+			mfs, err := reg.Gather()
+			assert.NoError(t, err)
+
+			got := make(map[string]int)
+			for _, mf := range mfs {
+				got[mf.GetName()] = len(mf.GetMetric())
+				if mf.GetName() == "kuda_dataset_updated_replicas" {
+					assert.Equal(t, float64(1), mf.GetMetric()[0].GetGauge().GetValue())
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
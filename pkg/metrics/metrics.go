@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "kuda"
+
+	InjectionResultInjected = "injected"
+	InjectionResultSkipped  = "skipped"
+	InjectionResultError    = "error"
+
+	GCReasonVersions     = "versions"
+	GCReasonSize         = "size"
+	GCReasonUnreferenced = "unreferenced"
+)
+
+var (
+	// DownloadDuration observes the duration from the start of a data item to its success.
+	DownloadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "data_item_download_duration_seconds",
+		Help:      "Duration from the start of downloading a data item to its success.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"namespace", "dataset", "source_type"})
+
+	// InjectionTotal counts the pod admission results of the webhook.
+	InjectionTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_injections_total",
+		Help:      "Number of pods handled by the webhook, partitioned by the injection result.",
+	}, []string{"result"})
+
+	// CacheRemovedVersionsTotal counts the data versions removed from the host cache by the node agent.
+	CacheRemovedVersionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_removed_versions_total",
+		Help:      "Number of data versions removed from the host cache, partitioned by the eviction reason.",
+	}, []string{"reason"})
+
+	// CacheRemovedBytesTotal counts the bytes removed from the host cache by the node agent.
+	CacheRemovedBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_removed_bytes_total",
+		Help:      "Bytes of the data versions removed from the host cache, partitioned by the eviction reason.",
+	}, []string{"reason"})
+
+	// CacheSizeBytes observes the bytes of the host cache after the last garbage collection.
+	CacheSizeBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_size_bytes",
+		Help:      "Bytes of the host cache after the last garbage collection.",
+	})
+
+	// CacheVersions observes the data versions in the host cache after the last garbage collection.
+	CacheVersions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_versions",
+		Help:      "Number of data versions in the host cache after the last garbage collection.",
+	})
+
+	// CacheGCErrorsTotal counts the failed garbage collections of the host cache.
+	CacheGCErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_gc_errors_total",
+		Help:      "Number of failed garbage collections of the host cache.",
+	})
+)
+
+// Register registers the metrics to the global registry of controller-runtime,
+// which is served by the metrics endpoint of the manager.
+func Register(collectors ...prometheus.Collector) {
+	metrics.Registry.MustRegister(collectors...)
+}
+
+func init() {
+	Register(DownloadDuration, InjectionTotal)
+	Register(CacheRemovedVersionsTotal, CacheRemovedBytesTotal, CacheSizeBytes, CacheVersions, CacheGCErrorsTotal)
+}
@@ -0,0 +1,419 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	defaultInterval    = time.Second
+	defaultConcurrency = 4
+)
+
+// Origin downloads a data item from the data source.
+type Origin interface {
+	// Download downloads the data item into the directory.
+	Download(ctx context.Context, dir string) error
+}
+
+// OriginFunc is an Origin by the function.
+type OriginFunc func(ctx context.Context, dir string) error
+
+// Download implements Origin.
+func (f OriginFunc) Download(ctx context.Context, dir string) error {
+	return f(ctx, dir)
+}
+
+// Downloader downloads a data item from the peers, or from the data source if it's a
+// seeder and no peer holds the data item.
+type Downloader struct {
+	// Item is the data item downloaded.
+	Item Item
+	// Dir is the directory the data item is downloaded into.
+	Dir string
+	// PieceSize is the size of the pieces of the manifest built by the seeder.
+	PieceSize int64
+	// Seeder downloads the data item from the Origin while no peer holds it.
+	Seeder bool
+	Origin Origin
+	// Peers discovers the peers of the data item.
+	Peers Peers
+	// Digests provides the digests of the manifests built by the seeders, only the manifests
+	// of which are accepted from the peers. The manifest held by the most peers is accepted
+	// if not set, which is only safe while all the peers are trusted.
+	Digests Digests
+	// Server shares the pieces downloaded to the peers if set, Address is its address
+	// which is excluded from the peers.
+	Server  *Server
+	Address string
+	// Client is the HTTP client of the peers, http.DefaultClient is used if not set.
+	Client *http.Client
+	// Interval between two discoveries of the peers while no peer has the missing pieces,
+	// defaults to 1s.
+	Interval time.Duration
+	// Concurrency is the number of the pieces fetched at the same time, defaults to 4.
+	Concurrency int
+}
+
+// peer is a peer holding the data item.
+type peer struct {
+	address string
+	have    Bitfield
+}
+
+// Download downloads the data item until it's complete or the context is done, and returns
+// the status of the distribution. The pieces failing the verification are fetched from the
+// other peers, and the peer serving them is not used anymore.
+func (d *Downloader) Download(ctx context.Context) (*datav1alpha1.P2PStatus, error) {
+	status := &datav1alpha1.P2PStatus{Address: d.Address}
+	bad := make(map[string]bool)
+
+	var (
+		manifest *Manifest
+		pieces   []Piece
+		have     Bitfield
+		share    *Share
+	)
+	for {
+		peers, candidate, err := d.discover(ctx, manifest, bad)
+		if err != nil {
+			return nil, err
+		}
+
+		if manifest == nil && candidate != nil {
+			manifest, pieces = candidate, candidate.Pieces()
+			status.ManifestDigest = manifest.Digest()
+			have = NewBitfield(len(pieces), false)
+			if err := d.prepare(manifest); err != nil {
+				return nil, err
+			}
+			if d.Server != nil {
+				share = d.Server.Share(d.Item, d.Dir, manifest, false)
+			}
+		}
+
+		// The seeder downloads from the data source only while no peer holds the data item.
+		if manifest == nil && d.Seeder && d.Origin != nil {
+			return d.seed(ctx, status)
+		}
+
+		if manifest != nil {
+			fetched := d.fetchPieces(ctx, peers, manifest, pieces, have, share, bad)
+			status.BytesFromPeers += fetched
+			if countMissing(have, len(pieces)) == 0 {
+				return status, nil
+			}
+			if fetched > 0 {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(d.interval()):
+		}
+	}
+}
+
+// seed downloads the data item from the data source, and shares it.
+func (d *Downloader) seed(ctx context.Context, status *datav1alpha1.P2PStatus) (*datav1alpha1.P2PStatus, error) {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := d.Origin.Download(ctx, d.Dir); err != nil {
+		return nil, err
+	}
+	pieceSize := d.PieceSize
+	if pieceSize <= 0 {
+		pieceSize = DefaultPieceSize
+	}
+	manifest, err := BuildManifest(d.Dir, pieceSize)
+	if err != nil {
+		return nil, err
+	}
+	if d.Server != nil {
+		d.Server.Share(d.Item, d.Dir, manifest, true)
+	}
+	status.Seeder = true
+	status.ManifestDigest = manifest.Digest()
+	status.BytesFromOrigin = manifest.Size()
+	return status, nil
+}
+
+// discover returns the peers holding any piece of the data item by the manifest. If no
+// manifest is chosen yet, the one held by the most peers is chosen among the trusted ones,
+// and the peers serving the others are not used anymore. Without Digests, all manifests
+// are taken as trusted, so that a peer serving a forged manifest is only outvoted.
+func (d *Downloader) discover(ctx context.Context, manifest *Manifest, bad map[string]bool) ([]peer, *Manifest, error) {
+	addresses, err := d.Peers.Peers(ctx, d.Item)
+	if err != nil {
+		return nil, nil, err
+	}
+	var trusted map[string]bool
+	if d.Digests != nil && manifest == nil {
+		digests, err := d.Digests.Digests(ctx, d.Item)
+		if err != nil {
+			return nil, nil, err
+		}
+		trusted = make(map[string]bool, len(digests))
+		for _, digest := range digests {
+			trusted[digest] = true
+		}
+	}
+
+	chosen := manifest
+	availabilities := make(map[string]*Availability, len(addresses))
+	votes := make(map[string]int)
+	for _, address := range addresses {
+		if address == d.Address || bad[address] {
+			continue
+		}
+		availability, err := d.getAvailability(ctx, address)
+		if err != nil {
+			continue
+		}
+		if err := availability.Manifest.Validate(); err != nil {
+			bad[address] = true
+			continue
+		}
+		if trusted != nil && !trusted[availability.Manifest.Digest()] {
+			// The peers are not rejected until any seeder records its digest, since they
+			// may be discovered before.
+			if len(trusted) > 0 {
+				bad[address] = true
+			}
+			continue
+		}
+		availabilities[address] = availability
+		key := manifestKey(availability.Manifest)
+		votes[key]++
+		if manifest == nil && (chosen == nil || votes[key] > votes[manifestKey(chosen)]) {
+			chosen = availability.Manifest
+		}
+	}
+
+	peers := make([]peer, 0, len(availabilities))
+	for _, address := range addresses {
+		availability, ok := availabilities[address]
+		// The peers of another manifest, e.g. re-synced from the data source, are not mixed.
+		if !ok || !reflect.DeepEqual(availability.Manifest, chosen) {
+			continue
+		}
+		peers = append(peers, peer{address: address, have: availability.Have})
+	}
+	return peers, chosen, nil
+}
+
+func manifestKey(manifest *Manifest) string {
+	key, _ := json.Marshal(manifest)
+	return string(key)
+}
+
+// prepare creates the files of the manifest in the directory.
+func (d *Downloader) prepare(manifest *Manifest) error {
+	for _, file := range manifest.Files {
+		p := filepath.Join(d.Dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		if err := f.Truncate(file.Size); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchPieces fetches the missing pieces from the peers holding them, each from a random
+// one to spread the load, and returns the bytes fetched.
+func (d *Downloader) fetchPieces(ctx context.Context, peers []peer, manifest *Manifest, pieces []Piece, have Bitfield, share *Share, bad map[string]bool) int64 {
+	type task struct {
+		index   int
+		address string
+	}
+	queue := make([]task, 0)
+	for _, i := range rand.Perm(len(pieces)) {
+		if have.Has(i) {
+			continue
+		}
+		holders := make([]string, 0, len(peers))
+		for _, p := range peers {
+			if p.have.Has(i) {
+				holders = append(holders, p.address)
+			}
+		}
+		if len(holders) > 0 {
+			queue = append(queue, task{index: i, address: holders[rand.Intn(len(holders))]})
+		}
+	}
+	tasks := make(chan task, len(queue))
+	for _, t := range queue {
+		tasks <- t
+	}
+	close(tasks)
+
+	var (
+		mu      sync.Mutex
+		fetched int64
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < d.concurrency(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				mu.Lock()
+				skip := bad[t.address] || ctx.Err() != nil
+				mu.Unlock()
+				if skip {
+					continue
+				}
+
+				piece := pieces[t.index]
+				data, err := d.getPiece(ctx, t.address, t.index, piece.Length)
+				if err != nil {
+					continue
+				}
+				if err := piece.Verify(data); err != nil {
+					mu.Lock()
+					bad[t.address] = true
+					mu.Unlock()
+					continue
+				}
+				file := filepath.Join(d.Dir, filepath.FromSlash(manifest.Files[piece.File].Path))
+				if err := writeAt(file, piece.Offset, data); err != nil {
+					continue
+				}
+
+				mu.Lock()
+				have.Set(t.index)
+				fetched += piece.Length
+				mu.Unlock()
+				if share != nil {
+					share.Set(t.index)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return fetched
+}
+
+func writeAt(file string, offset int64, data []byte) error {
+	f, err := os.OpenFile(file, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, offset); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (d *Downloader) getAvailability(ctx context.Context, address string) (*Availability, error) {
+	body, err := d.get(ctx, address, itemPath(d.Item, -1), 0)
+	if err != nil {
+		return nil, err
+	}
+	availability := &Availability{}
+	if err := json.Unmarshal(body, availability); err != nil {
+		return nil, err
+	}
+	if availability.Manifest == nil {
+		return nil, fmt.Errorf("peer %s returned no manifest", address)
+	}
+	return availability, nil
+}
+
+func (d *Downloader) getPiece(ctx context.Context, address string, index int, length int64) ([]byte, error) {
+	return d.get(ctx, address, itemPath(d.Item, index), length)
+}
+
+// get returns the body of the path from the peer, which is read up to the limit if it's
+// positive.
+func (d *Downloader) get(ctx context.Context, address, path string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer %s returned %s", address, resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if limit > 0 {
+		// One more byte is read, so that a longer piece fails the verification.
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+	return ioutil.ReadAll(body)
+}
+
+func (d *Downloader) interval() time.Duration {
+	if d.Interval > 0 {
+		return d.Interval
+	}
+	return defaultInterval
+}
+
+func (d *Downloader) concurrency() int {
+	if d.Concurrency > 0 {
+		return d.Concurrency
+	}
+	return defaultConcurrency
+}
+
+func countMissing(have Bitfield, n int) int {
+	missing := 0
+	for i := 0; i < n; i++ {
+		if !have.Has(i) {
+			missing++
+		}
+	}
+	return missing
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package p2p distributes the data items with the p2p policy between the peers. It's used
+// by the kuda runtime and the node agent.
+//
+// A data item is split into pieces described by its manifest, which holds the SHA-256 of
+// each piece. Only a few seeders download a data item from the data source while no peer
+// holds it, they build the manifest and serve the pieces by the Server. The other peers
+// fetch the manifest and the pieces from the peers, verify each piece by its checksum, and
+// serve the pieces they hold to the others meanwhile. The manifest is trusted by its digest
+// recorded by the seeders, so that a peer can't forge the pieces with its own manifest.
+package p2p
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	// DefaultSeeders is the default max number of the seeders of a data item.
+	DefaultSeeders = 3
+	// DefaultPieceSize is the default size of the pieces.
+	DefaultPieceSize = 4 << 20
+	// MinPieceSize and MaxPieceSize are the bounds of the piece size.
+	MinPieceSize = 64 << 10
+	MaxPieceSize = 256 << 20
+)
+
+// Item identifies a version of a data item.
+type Item struct {
+	Namespace string
+	Name      string
+	Version   string
+}
+
+// ItemOf returns the version of the data item.
+func ItemOf(item *datav1alpha1.DataItem) Item {
+	return Item{Namespace: item.Namespace, Name: item.Name, Version: item.Version}
+}
+
+func (i Item) String() string {
+	return fmt.Sprintf("%s/%s@%s", i.Namespace, i.Name, i.Version)
+}
+
+// Seeders returns the max number of the seeders by the policy.
+func Seeders(policy *datav1alpha1.P2PPolicy) int {
+	if policy == nil || policy.Seeders == nil {
+		return DefaultSeeders
+	}
+	return int(*policy.Seeders)
+}
+
+// PieceSize returns the size of the pieces by the policy.
+func PieceSize(policy *datav1alpha1.P2PPolicy) int64 {
+	if policy == nil || policy.PieceSize == nil {
+		return DefaultPieceSize
+	}
+	return policy.PieceSize.Value()
+}
+
+// Manifest describes the regular files of a data item and the checksums of their pieces.
+// The pieces are numbered through the files in order.
+type Manifest struct {
+	PieceSize int64          `json:"pieceSize"`
+	Files     []FileManifest `json:"files"`
+}
+
+// FileManifest describes a regular file of a data item.
+type FileManifest struct {
+	// Path is the slash separated path relative to the directory of the data item.
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Pieces are the hex encoded SHA-256 of the pieces of the file.
+	Pieces []string `json:"pieces"`
+}
+
+// Piece locates a piece in the files of a data item.
+type Piece struct {
+	File     int
+	Offset   int64
+	Length   int64
+	Checksum string
+}
+
+// BuildManifest returns the manifest of the regular files in the directory.
+func BuildManifest(dir string, pieceSize int64) (*Manifest, error) {
+	if pieceSize <= 0 {
+		return nil, fmt.Errorf("invalid piece size %d", pieceSize)
+	}
+
+	manifest := &Manifest{PieceSize: pieceSize, Files: make([]FileManifest, 0)}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		pieces, err := hashPieces(p, pieceSize)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, FileManifest{Path: filepath.ToSlash(rel), Size: info.Size(), Pieces: pieces})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	return manifest, nil
+}
+
+// hashPieces returns the checksums of the pieces of the file.
+func hashPieces(file string, pieceSize int64) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pieces := make([]string, 0)
+	for {
+		h := sha256.New()
+		n, err := io.CopyN(h, f, pieceSize)
+		if n > 0 {
+			pieces = append(pieces, hex.EncodeToString(h.Sum(nil)))
+		}
+		if err == io.EOF {
+			return pieces, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Validate returns an error if the manifest fetched from a peer is malformed, e.g. a path
+// out of the directory of the data item.
+func (m *Manifest) Validate() error {
+	if m.PieceSize < MinPieceSize || m.PieceSize > MaxPieceSize {
+		return fmt.Errorf("invalid piece size %d", m.PieceSize)
+	}
+	seen := make(map[string]bool, len(m.Files))
+	for _, file := range m.Files {
+		clean := path.Clean(file.Path)
+		if file.Path != clean || path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("invalid file path %q", file.Path)
+		}
+		if seen[clean] {
+			return fmt.Errorf("duplicate file path %q", file.Path)
+		}
+		seen[clean] = true
+		if file.Size < 0 || int64(len(file.Pieces)) != (file.Size+m.PieceSize-1)/m.PieceSize {
+			return fmt.Errorf("file %q has %d piece(s) for %d bytes", file.Path, len(file.Pieces), file.Size)
+		}
+	}
+	return nil
+}
+
+// Digest returns the hex encoded SHA-256 of the manifest, by which the manifests fetched
+// from the peers are verified.
+func (m *Manifest) Digest() string {
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Size returns the total bytes of the files.
+func (m *Manifest) Size() int64 {
+	var size int64
+	for _, file := range m.Files {
+		size += file.Size
+	}
+	return size
+}
+
+// Pieces returns the pieces of all the files in order.
+func (m *Manifest) Pieces() []Piece {
+	pieces := make([]Piece, 0)
+	for i, file := range m.Files {
+		for j, checksum := range file.Pieces {
+			offset := int64(j) * m.PieceSize
+			length := m.PieceSize
+			if offset+length > file.Size {
+				length = file.Size - offset
+			}
+			pieces = append(pieces, Piece{File: i, Offset: offset, Length: length, Checksum: checksum})
+		}
+	}
+	return pieces
+}
+
+// Verify returns an error if the data is not the piece.
+func (p *Piece) Verify(data []byte) error {
+	if int64(len(data)) != p.Length {
+		return fmt.Errorf("piece has %d bytes, expected %d", len(data), p.Length)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != p.Checksum {
+		return fmt.Errorf("piece checksum mismatch")
+	}
+	return nil
+}
+
+// Bitfield marks the pieces held.
+type Bitfield []byte
+
+// NewBitfield returns the bitfield of n pieces, all of which are held if full.
+func NewBitfield(n int, full bool) Bitfield {
+	b := make(Bitfield, (n+7)/8)
+	if full {
+		for i := 0; i < n; i++ {
+			b.Set(i)
+		}
+	}
+	return b
+}
+
+// Has returns true if the piece is held.
+func (b Bitfield) Has(i int) bool {
+	return i >= 0 && i/8 < len(b) && b[i/8]&(1<<(uint(i)%8)) != 0
+}
+
+// Set marks the piece held.
+func (b Bitfield) Set(i int) {
+	b[i/8] |= 1 << (uint(i) % 8)
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPieceSize = MinPieceSize
+
+var testFiles = map[string]int{
+	"model.bin":       5*testPieceSize + 100,
+	"vocab/words.txt": 1000,
+	"empty":           0,
+}
+
+// newTestOrigin returns a local HTTP origin serving the test files, and the Origin
+// downloading them which counts the downloads.
+func newTestOrigin(t *testing.T) (string, Origin, *int32) {
+	dir := t.TempDir()
+	for name, size := range testFiles {
+		data := make([]byte, size)
+		rand.Read(data)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(server.Close)
+
+	downloads := new(int32)
+	origin := OriginFunc(func(ctx context.Context, dst string) error {
+		atomic.AddInt32(downloads, 1)
+		for name := range testFiles {
+			resp, err := http.Get(server.URL + "/" + name)
+			if err != nil {
+				return err
+			}
+			p := filepath.Join(dst, name)
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				resp.Body.Close()
+				return err
+			}
+			f, err := os.Create(p)
+			if err != nil {
+				resp.Body.Close()
+				return err
+			}
+			_, err = io.Copy(f, resp.Body)
+			resp.Body.Close()
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return dir, origin, downloads
+}
+
+// newTestPeer returns a server of a kuda runtime listening on a local port, and its address.
+func newTestPeer(t *testing.T, handler http.Handler) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func assertSameFiles(t *testing.T, expected, actual string) {
+	for name := range testFiles {
+		want, err := ioutil.ReadFile(filepath.Join(expected, name))
+		assert.NoError(t, err)
+		got, err := ioutil.ReadFile(filepath.Join(actual, name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir, _, _ := newTestOrigin(t)
+
+	manifest, err := BuildManifest(dir, testPieceSize)
+	assert.NoError(t, err)
+	assert.NoError(t, manifest.Validate())
+	assert.Equal(t, []string{"empty", "model.bin", "vocab/words.txt"},
+		[]string{manifest.Files[0].Path, manifest.Files[1].Path, manifest.Files[2].Path})
+	assert.Len(t, manifest.Files[1].Pieces, 6)
+	assert.Equal(t, int64(5*testPieceSize+1100), manifest.Size())
+
+	pieces := manifest.Pieces()
+	assert.Len(t, pieces, 7)
+	assert.Equal(t, Piece{File: 1, Offset: 5 * testPieceSize, Length: 100, Checksum: manifest.Files[1].Pieces[5]}, pieces[5])
+	data, err := ioutil.ReadFile(filepath.Join(dir, "vocab", "words.txt"))
+	assert.NoError(t, err)
+	assert.NoError(t, pieces[6].Verify(data))
+	data[0]++
+	assert.Error(t, pieces[6].Verify(data))
+	assert.Error(t, pieces[6].Verify(data[1:]))
+
+	for _, path := range []string{"../model.bin", "/model.bin", "vocab/../model.bin", "", "."} {
+		forged := *manifest
+		forged.Files = []FileManifest{{Path: path}}
+		assert.Error(t, forged.Validate(), path)
+	}
+	forged := *manifest
+	forged.Files = []FileManifest{{Path: "model.bin", Size: 2 * testPieceSize, Pieces: []string{"a"}}}
+	assert.Error(t, forged.Validate())
+}
+
+func TestParsePath(t *testing.T) {
+	item := Item{Namespace: "models", Name: "ranker", Version: "2021/11/08"}
+	parsed, piece, err := parsePath(itemPath(item, -1))
+	assert.NoError(t, err)
+	assert.Equal(t, item, parsed)
+	assert.Equal(t, -1, piece)
+
+	parsed, piece, err = parsePath(itemPath(item, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, item, parsed)
+	assert.Equal(t, 3, piece)
+
+	for _, path := range []string{"/p2p/v1/items/models/ranker", PathPrefix + "a/b/c/pieces/-1", PathPrefix + "a/b/c/files/1", "/healthz"} {
+		_, _, err := parsePath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestSelectSeeders(t *testing.T) {
+	item := Item{Namespace: "models", Name: "ranker", Version: "v1"}
+	pods := []string{"pod-a", "pod-b", "pod-c", "pod-d", "pod-e"}
+	seeders := SelectSeeders(item, pods, 2)
+	assert.Len(t, seeders, 2)
+
+	// Every pod selects the same seeders whatever the order of the pods.
+	assert.Equal(t, seeders, SelectSeeders(item, []string{"pod-e", "pod-d", "pod-c", "pod-b", "pod-a", "pod-a"}, 2))
+	// The seeders are kept when the other pods go away.
+	others := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		if pod != seeders[0] && pod != seeders[1] {
+			others = append(others, pod)
+		}
+	}
+	assert.ElementsMatch(t, seeders, SelectSeeders(item, append(append([]string(nil), seeders...), others[1:]...), 2))
+	assert.Len(t, SelectSeeders(item, pods[:1], 3), 1)
+}
+
+func TestDownload(t *testing.T) {
+	originDir, origin, downloads := newTestOrigin(t)
+	item := Item{Namespace: "models", Name: "ranker", Version: "v1"}
+
+	// Several kuda runtimes on one machine, each serving the pieces it holds to the others.
+	const runtimes = 6
+	var (
+		mu        sync.Mutex
+		addresses []string
+	)
+	peers := PeersFunc(func(ctx context.Context, item Item) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), addresses...), nil
+	})
+	downloaders := make([]*Downloader, 0, runtimes)
+	pods := make([]string, 0, runtimes)
+	for i := 0; i < runtimes; i++ {
+		pods = append(pods, fmt.Sprintf("pod-%d", i))
+	}
+	seeders := SelectSeeders(item, pods, 2)
+	for i := 0; i < runtimes; i++ {
+		server := NewServer()
+		address := newTestPeer(t, server)
+		addresses = append(addresses, address)
+		downloaders = append(downloaders, &Downloader{
+			Item:      item,
+			Dir:       t.TempDir(),
+			PieceSize: testPieceSize,
+			Seeder:    pods[i] == seeders[0] || pods[i] == seeders[1],
+			Origin:    origin,
+			Peers:     peers,
+			Server:    server,
+			Address:   address,
+			Interval:  10 * time.Millisecond,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := range downloaders {
+		wg.Add(1)
+		go func(d *Downloader) {
+			defer wg.Done()
+			status, err := d.Download(ctx)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, d.Address, status.Address)
+			if status.Seeder {
+				assert.Equal(t, int64(5*testPieceSize+1100), status.BytesFromOrigin)
+			} else {
+				assert.Equal(t, int64(5*testPieceSize+1100), status.BytesFromPeers)
+			}
+		}(downloaders[i])
+	}
+	wg.Wait()
+
+	// Only the seeders download from the origin, at most once each.
+	assert.LessOrEqual(t, atomic.LoadInt32(downloads), int32(2))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(downloads), int32(1))
+	for _, d := range downloaders {
+		assertSameFiles(t, originDir, d.Dir)
+	}
+
+	// The peers holding the data item already are used instead of the origin.
+	late := &Downloader{Item: item, Dir: t.TempDir(), Seeder: true, Origin: origin, Peers: peers, Interval: 10 * time.Millisecond}
+	before := atomic.LoadInt32(downloads)
+	status, err := late.Download(ctx)
+	assert.NoError(t, err)
+	assert.False(t, status.Seeder)
+	assert.Equal(t, before, atomic.LoadInt32(downloads))
+	assertSameFiles(t, originDir, late.Dir)
+}
+
+func TestDownloadCorruptedPieces(t *testing.T) {
+	originDir, origin, _ := newTestOrigin(t)
+	item := Item{Namespace: "models", Name: "ranker", Version: "v1"}
+
+	seeder := NewServer()
+	seederAddress := newTestPeer(t, seeder)
+	_, err := (&Downloader{Item: item, Dir: t.TempDir(), PieceSize: testPieceSize, Seeder: true, Origin: origin,
+		Peers: PeersFunc(func(context.Context, Item) ([]string, error) { return nil, nil }), Server: seeder}).Download(context.Background())
+	assert.NoError(t, err)
+
+	// The corrupted peer shares the same manifest, but serves the pieces flipped.
+	corrupted := newTestPeer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		seeder.ServeHTTP(rec, r)
+		body := rec.Body.Bytes()
+		if strings.Contains(r.URL.Path, "/pieces/") && len(body) > 0 {
+			body[0]++
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(body)
+	}))
+
+	peers := PeersFunc(func(context.Context, Item) ([]string, error) {
+		return []string{corrupted, seederAddress}, nil
+	})
+	d := &Downloader{Item: item, Dir: t.TempDir(), Peers: peers, Interval: 10 * time.Millisecond, Concurrency: 1}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status, err := d.Download(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5*testPieceSize+1100), status.BytesFromPeers)
+	assertSameFiles(t, originDir, d.Dir)
+}
+
+type testDigests []string
+
+func (d testDigests) Digests(context.Context, Item) ([]string, error) {
+	return d, nil
+}
+
+func TestDownloadForgedManifest(t *testing.T) {
+	originDir, origin, _ := newTestOrigin(t)
+	item := Item{Namespace: "models", Name: "ranker", Version: "v1"}
+
+	seeder := NewServer()
+	seederAddress := newTestPeer(t, seeder)
+	seeded, err := (&Downloader{Item: item, Dir: t.TempDir(), PieceSize: testPieceSize, Seeder: true, Origin: origin,
+		Peers: PeersFunc(func(context.Context, Item) ([]string, error) { return nil, nil }), Server: seeder}).Download(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, seeded.ManifestDigest)
+
+	// The forged peer serves other files by its own manifest, which is valid by itself.
+	forgedDir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(forgedDir, "model.bin"), []byte("forged"), 0644))
+	manifest, err := BuildManifest(forgedDir, testPieceSize)
+	assert.NoError(t, err)
+	forged := NewServer()
+	forged.Share(item, forgedDir, manifest, true)
+	forgedAddress := newTestPeer(t, forged)
+
+	peers := PeersFunc(func(context.Context, Item) ([]string, error) {
+		return []string{forgedAddress, seederAddress}, nil
+	})
+	d := &Downloader{Item: item, Dir: t.TempDir(), Peers: peers, Digests: testDigests{seeded.ManifestDigest}, Interval: 10 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status, err := d.Download(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, seeded.ManifestDigest, status.ManifestDigest)
+	assertSameFiles(t, originDir, d.Dir)
+
+	// No manifest is accepted until any seeder records its digest.
+	d = &Downloader{Item: item, Dir: t.TempDir(), Peers: peers, Digests: testDigests{}, Interval: 10 * time.Millisecond}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = d.Download(ctx)
+	assert.Error(t, err)
+}
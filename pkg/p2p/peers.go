@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package p2p
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// Peers discovers the peers of the data items.
+type Peers interface {
+	// Peers returns the addresses of the peers holding or fetching the data item.
+	Peers(ctx context.Context, item Item) ([]string, error)
+}
+
+// PeersFunc is a Peers by the function.
+type PeersFunc func(ctx context.Context, item Item) ([]string, error)
+
+// Peers implements Peers.
+func (f PeersFunc) Peers(ctx context.Context, item Item) ([]string, error) {
+	return f(ctx, item)
+}
+
+// Digests provides the trusted digests of the manifests of the data items.
+type Digests interface {
+	// Digests returns the digests of the manifests of the data item built by the seeders.
+	Digests(ctx context.Context, item Item) ([]string, error)
+}
+
+// ClusterPeers discovers the peers of the data items of a DataSet from the statuses of
+// its Data, and the node agents sharing the host cache by the node inventory.
+type ClusterPeers struct {
+	Reader    client.Reader
+	Namespace string
+	DataSet   string
+	// NodeData discovers the node agents from the NodeData, the address of which is the
+	// kuda.io/peer-address annotation.
+	NodeData bool
+}
+
+// Peers implements Peers. The pods holding the data item come first, followed by the ones
+// fetching it and then the node agents.
+func (p *ClusterPeers) Peers(ctx context.Context, item Item) ([]string, error) {
+	dataList, err := p.listData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var holding, fetching []string
+	for _, data := range dataList.Items {
+		for _, status := range data.Status.DataItemsStatus {
+			if status.Namespace != item.Namespace || status.Name != item.Name || status.Version != item.Version ||
+				status.P2P == nil || status.P2P.Address == "" {
+				continue
+			}
+			switch status.Phase {
+			case datav1alpha1.DataSuccess, datav1alpha1.DataStaged:
+				holding = append(holding, status.P2P.Address)
+			case datav1alpha1.DataDownloading:
+				fetching = append(fetching, status.P2P.Address)
+			}
+		}
+	}
+	addresses := append(holding, fetching...)
+
+	if p.NodeData {
+		nodeDataList := &datav1alpha1.NodeDataList{}
+		if err := p.Reader.List(ctx, nodeDataList); err != nil {
+			return nil, err
+		}
+		for _, nodeData := range nodeDataList.Items {
+			address := nodeData.Annotations[datav1alpha1.KudaKeyPeerAddress]
+			if address == "" {
+				continue
+			}
+			// Only the shared host cache is served by the nodes, the items cached for the
+			// tenants are not.
+			for _, cached := range nodeData.Status.Items {
+				if cached.Tenant == "" && cached.Namespace == item.Namespace && cached.Name == item.Name && cached.Version == item.Version {
+					addresses = append(addresses, address)
+					break
+				}
+			}
+		}
+	}
+
+	return dedup(addresses), nil
+}
+
+// Digests implements Digests by the manifest digests recorded in the statuses of the data
+// items downloaded by the seeders.
+func (p *ClusterPeers) Digests(ctx context.Context, item Item) ([]string, error) {
+	dataList, err := p.listData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var digests []string
+	for _, data := range dataList.Items {
+		for _, status := range data.Status.DataItemsStatus {
+			if status.Namespace != item.Namespace || status.Name != item.Name || status.Version != item.Version ||
+				status.P2P == nil || !status.P2P.Seeder || status.P2P.ManifestDigest == "" {
+				continue
+			}
+			digests = append(digests, status.P2P.ManifestDigest)
+		}
+	}
+	return dedup(digests), nil
+}
+
+// IsSeeder returns true if the pod is one of the seeders of the data item among the pods
+// of the DataSet.
+func (p *ClusterPeers) IsSeeder(ctx context.Context, item Item, pod string, seeders int) (bool, error) {
+	dataList, err := p.listData(ctx)
+	if err != nil {
+		return false, err
+	}
+	pods := make([]string, 0, len(dataList.Items))
+	for _, data := range dataList.Items {
+		pods = append(pods, data.Labels[datav1alpha1.KudaKeyPod])
+	}
+	for _, seeder := range SelectSeeders(item, pods, seeders) {
+		if seeder == pod {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *ClusterPeers) listData(ctx context.Context) (*datav1alpha1.DataList, error) {
+	dataList := &datav1alpha1.DataList{}
+	if err := p.Reader.List(ctx, dataList, client.InNamespace(p.Namespace),
+		client.MatchingLabels{datav1alpha1.KudaKeyDataSet: p.DataSet}); err != nil {
+		return nil, err
+	}
+	return dataList, nil
+}
+
+// SelectSeeders returns the seeders of the data item among the pods by the rendezvous
+// hashing, so that every pod selects the same seeders without coordination, and a version
+// is seeded by different pods than the others.
+func SelectSeeders(item Item, pods []string, seeders int) []string {
+	type rank struct {
+		pod   string
+		score uint64
+	}
+	ranks := make([]rank, 0, len(pods))
+	for _, pod := range dedup(pods) {
+		sum := sha256.Sum256([]byte(item.String() + "/" + pod))
+		ranks = append(ranks, rank{pod: pod, score: binary.BigEndian.Uint64(sum[:8])})
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].score != ranks[j].score {
+			return ranks[i].score > ranks[j].score
+		}
+		return ranks[i].pod < ranks[j].pod
+	})
+
+	selected := make([]string, 0, seeders)
+	for i := 0; i < len(ranks) && i < seeders; i++ {
+		selected = append(selected, ranks[i].pod)
+	}
+	return selected
+}
+
+// dedup removes the duplicates and the empty ones, the order is kept.
+func dedup(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PathPrefix is the path prefix of the HTTP API served to the peers:
+//
+//	GET /p2p/v1/items/<namespace>/<name>/<version>             the Availability
+//	GET /p2p/v1/items/<namespace>/<name>/<version>/pieces/<n>  the bytes of the piece
+//
+// The path elements are escaped.
+const PathPrefix = "/p2p/v1/items/"
+
+// Availability is the manifest of a data item and the pieces held by a peer.
+type Availability struct {
+	Manifest *Manifest `json:"manifest"`
+	Have     Bitfield  `json:"have"`
+}
+
+// Server serves the pieces of the data items shared to the peers.
+type Server struct {
+	mu    sync.RWMutex
+	items map[Item]*Share
+}
+
+// Share is a data item shared by the server.
+type Share struct {
+	dir      string
+	manifest *Manifest
+	pieces   []Piece
+
+	mu   sync.RWMutex
+	have Bitfield
+}
+
+// NewServer returns a Server sharing nothing.
+func NewServer() *Server {
+	return &Server{items: make(map[Item]*Share)}
+}
+
+// Share shares the data item in the directory by the manifest. The pieces are marked held
+// by the returned Share, all of them are held already if full.
+func (s *Server) Share(item Item, dir string, manifest *Manifest, full bool) *Share {
+	pieces := manifest.Pieces()
+	share := &Share{dir: dir, manifest: manifest, pieces: pieces, have: NewBitfield(len(pieces), full)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[item] = share
+	return share
+}
+
+// Unshare stops sharing the data item.
+func (s *Server) Unshare(item Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, item)
+}
+
+// Items returns the data items shared.
+func (s *Server) Items() []Item {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items := make([]Item, 0, len(s.items))
+	for item := range s.items {
+		items = append(items, item)
+	}
+	return items
+}
+
+// Set marks the piece held.
+func (s *Share) Set(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.have.Set(i)
+}
+
+func (s *Share) has(i int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.have.Has(i)
+}
+
+func (s *Share) availability() *Availability {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &Availability{Manifest: s.manifest, Have: append(Bitfield(nil), s.have...)}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	item, piece, err := parsePath(r.URL.EscapedPath())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	share, ok := s.items[item]
+	s.mu.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("data item %s is not shared", item), http.StatusNotFound)
+		return
+	}
+
+	if piece < 0 {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(share.availability())
+		return
+	}
+	if piece >= len(share.pieces) || !share.has(piece) {
+		http.Error(w, fmt.Sprintf("piece %d is not held", piece), http.StatusNotFound)
+		return
+	}
+	if err := share.writePiece(w, piece); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writePiece writes the bytes of the piece.
+func (s *Share) writePiece(w http.ResponseWriter, i int) error {
+	piece := s.pieces[i]
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(s.manifest.Files[piece.File].Path)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(piece.Length, 10))
+	_, err = io.Copy(w, io.NewSectionReader(f, piece.Offset, piece.Length))
+	return err
+}
+
+// itemPath returns the path of the data item, or the piece of it if the piece is not
+// negative.
+func itemPath(item Item, piece int) string {
+	p := PathPrefix + url.PathEscape(item.Namespace) + "/" + url.PathEscape(item.Name) + "/" + url.PathEscape(item.Version)
+	if piece >= 0 {
+		p += "/pieces/" + strconv.Itoa(piece)
+	}
+	return p
+}
+
+// parsePath returns the data item and the piece of the escaped path, the piece is -1 if
+// the path is the data item.
+func parsePath(escaped string) (Item, int, error) {
+	if !strings.HasPrefix(escaped, PathPrefix) {
+		return Item{}, 0, fmt.Errorf("invalid path %s", escaped)
+	}
+	elems := strings.Split(strings.TrimPrefix(escaped, PathPrefix), "/")
+	if len(elems) != 3 && (len(elems) != 5 || elems[3] != "pieces") {
+		return Item{}, 0, fmt.Errorf("invalid path %s", escaped)
+	}
+	for i := 0; i < 3; i++ {
+		elem, err := url.PathUnescape(elems[i])
+		if err != nil || elem == "" {
+			return Item{}, 0, fmt.Errorf("invalid path %s", escaped)
+		}
+		elems[i] = elem
+	}
+
+	item := Item{Namespace: elems[0], Name: elems[1], Version: elems[2]}
+	if len(elems) == 3 {
+		return item, -1, nil
+	}
+	piece, err := strconv.Atoi(elems[4])
+	if err != nil || piece < 0 {
+		return Item{}, 0, fmt.Errorf("invalid piece %s", elems[4])
+	}
+	return item, piece, nil
+}
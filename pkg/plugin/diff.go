@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	changeAdded     = "added"
+	changeRemoved   = "removed"
+	changeModified  = "modified"
+	changeLifecycle = "lifecycle"
+)
+
+// itemChange describes the change of a data item of a pod.
+type itemChange struct {
+	Pod    string
+	Item   string
+	Change string
+	Detail string
+}
+
+// redownload returns true if the data item will be downloaded again by the change.
+func (c itemChange) redownload() bool {
+	return c.Change == changeAdded || c.Change == changeModified
+}
+
+// RunDiff previews the pods and data items affected by the edited dataset in the file.
+func (o *Options) RunDiff(ctx context.Context, name string) error {
+	if o.Filename == "" {
+		return fmt.Errorf("the edited dataset must be specified by --filename")
+	}
+	b, err := ioutil.ReadFile(o.Filename)
+	if err != nil {
+		return err
+	}
+	edited := &datav1alpha1.DataSet{}
+	if err := yaml.Unmarshal(b, edited); err != nil {
+		return err
+	}
+
+	dataList, err := o.listDataForDataSet(ctx, name)
+	if err != nil {
+		return err
+	}
+	podList, err := o.KubeClient.CoreV1().Pods(o.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(edited.Spec.WorkloadSelector).String(),
+	})
+	if err != nil {
+		return err
+	}
+	pods := make([]string, 0, len(podList.Items))
+	for _, pod := range podList.Items {
+		pods = append(pods, pod.Name)
+	}
+
+	changes := diffDataSet(&edited.Spec.Template, dataList.Items, pods)
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "POD\tITEM\tCHANGE\tDETAIL")
+	affected := make(map[string]bool)
+	downloads := 0
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Pod, c.Item, c.Change, c.Detail)
+		affected[c.Pod] = true
+		if c.redownload() {
+			downloads++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "\n%d pod(s) affected, %d data item download(s)\n", len(affected), downloads)
+	return nil
+}
+
+// diffDataSet compares the data resources of the pods with the edited template. Pods
+// newly matching the edited dataset download all the data items, and data resources of
+// the pods no longer matching it are removed.
+func diffDataSet(template *datav1alpha1.DataTemplateSpec, datas []datav1alpha1.Data, pods []string) []itemChange {
+	changes := make([]itemChange, 0)
+
+	matched := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		matched[pod] = true
+	}
+
+	existing := make(map[string]bool, len(datas))
+	for _, data := range datas {
+		pod := data.Labels[datav1alpha1.KudaKeyPod]
+		existing[pod] = true
+		if !matched[pod] {
+			changes = append(changes, itemChange{Pod: pod, Item: "*", Change: changeRemoved, Detail: "pod no longer matches the workload selector"})
+			continue
+		}
+		changes = append(changes, diffDataItems(pod, &data.Spec, template)...)
+	}
+
+	for _, pod := range pods {
+		if existing[pod] {
+			continue
+		}
+		for _, item := range template.DataItems {
+			changes = append(changes, itemChange{Pod: pod, Item: itemKey(item), Change: changeAdded, Detail: "pod newly matches the workload selector"})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Pod < changes[j].Pod })
+	return changes
+}
+
+// diffDataItems compares the data items of a data resource with the edited template.
+func diffDataItems(pod string, spec *datav1alpha1.DataSpec, template *datav1alpha1.DataTemplateSpec) []itemChange {
+	changes := make([]itemChange, 0)
+
+	oldItems := make(map[string]datav1alpha1.DataItem, len(spec.DataItems))
+	for _, item := range spec.DataItems {
+		oldItems[itemKey(item)] = item
+	}
+
+	for _, item := range template.DataItems {
+		key := itemKey(item)
+		old, ok := oldItems[key]
+		delete(oldItems, key)
+		if !ok {
+			changes = append(changes, itemChange{Pod: pod, Item: key, Change: changeAdded, Detail: "version " + item.Version})
+			continue
+		}
+
+		fields := make([]string, 0)
+		if old.Version != item.Version {
+			fields = append(fields, fmt.Sprintf("version %s -> %s", old.Version, item.Version))
+		}
+		if old.RemotePath != item.RemotePath {
+			fields = append(fields, fmt.Sprintf("remotePath %s -> %s", old.RemotePath, item.RemotePath))
+		}
+		if old.LocalPath != item.LocalPath {
+			fields = append(fields, fmt.Sprintf("localPath %s -> %s", old.LocalPath, item.LocalPath))
+		}
+		if old.DataSourceType != item.DataSourceType {
+			fields = append(fields, fmt.Sprintf("dataSourceType %s -> %s", old.DataSourceType, item.DataSourceType))
+		} else if !reflect.DeepEqual(getDataSource(spec.DataSources, item.DataSourceType), getDataSource(template.DataSources, item.DataSourceType)) {
+			fields = append(fields, fmt.Sprintf("dataSources.%s changed", item.DataSourceType))
+		}
+		if old.Format != item.Format {
+			fields = append(fields, fmt.Sprintf("format %q -> %q", old.Format, item.Format))
+		}
+		if !reflect.DeepEqual(old.Sync, item.Sync) {
+			fields = append(fields, "sync changed")
+		}
+		if !reflect.DeepEqual(old.Delta, item.Delta) {
+			fields = append(fields, "delta changed")
+		}
+		if !reflect.DeepEqual(old.Versioning, item.Versioning) {
+			fields = append(fields, "versioning changed")
+		}
+		if old.Schedule != item.Schedule {
+			fields = append(fields, fmt.Sprintf("schedule %q -> %q", old.Schedule, item.Schedule))
+		}
+		if !reflect.DeepEqual(old.P2P, item.P2P) {
+			fields = append(fields, "p2p changed")
+		}
+		if old.Checksum != item.Checksum {
+			fields = append(fields, fmt.Sprintf("checksum %q -> %q", old.Checksum, item.Checksum))
+		}
+		if len(fields) > 0 {
+			changes = append(changes, itemChange{Pod: pod, Item: key, Change: changeModified, Detail: strings.Join(fields, ", ")})
+			continue
+		}
+
+		if !reflect.DeepEqual(old.Lifecycle, item.Lifecycle) {
+			changes = append(changes, itemChange{Pod: pod, Item: key, Change: changeLifecycle, Detail: "lifecycle changed, no download"})
+		}
+	}
+
+	for key := range oldItems {
+		changes = append(changes, itemChange{Pod: pod, Item: key, Change: changeRemoved})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Item < changes[j].Item })
+	return changes
+}
+
+// getDataSource returns the config of the data source by type.
+func getDataSource(sources *datav1alpha1.DataSources, sourceType string) interface{} {
+	if sources == nil {
+		return nil
+	}
+	switch sourceType {
+	case "hdfs":
+		return sources.Hdfs
+	case "alluxio":
+		return sources.Alluxio
+	}
+	return nil
+}
+
+func itemKey(item datav1alpha1.DataItem) string {
+	return item.Namespace + "/" + item.Name
+}
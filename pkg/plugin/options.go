@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kuda-io/kuda/pkg/generated/clientset/versioned"
+)
+
+const usage = `kubectl kuda inspects DataSets and operates their rollouts.
+
+Usage:
+  kubectl kuda status <dataset>                  Show the data items of each pod of the DataSet
+  kubectl kuda rollout status <dataset>          Wait for the rollout of the DataSet to finish
+  kubectl kuda rollout pause <dataset>           Pause the rollout of the DataSet
+  kubectl kuda rollout resume <dataset>          Resume the paused rollout of the DataSet
+  kubectl kuda rollout history <dataset>         Show the revisions of the DataSet
+  kubectl kuda rollout undo <dataset>            Roll back the DataSet to the previous revision
+  kubectl kuda which <pod>                       Show the DataSets and data items affecting the pod
+  kubectl kuda diff <dataset> -f <file>          Preview the pods and data items re-downloaded by an edit
+
+Flags:
+`
+
+// Options holds the clients and flags shared by the commands.
+type Options struct {
+	Namespace  string
+	KubeClient kubernetes.Interface
+	KudaClient versioned.Interface
+	Out        io.Writer
+
+	// Timeout is the max duration to wait for the rollout.
+	Timeout time.Duration
+	// ToRevision is the revision to roll back to, 0 means the previous one.
+	ToRevision int64
+	// Filename is the file containing the edited DataSet.
+	Filename string
+}
+
+// Run parses the arguments and runs the command.
+func Run(args []string, out io.Writer) error {
+	o := &Options{Out: out}
+
+	fs := pflag.NewFlagSet("kubectl-kuda", pflag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprint(out, usage)
+		fs.PrintDefaults()
+	}
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	fs.StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVarP(&o.Namespace, "namespace", "n", "", "Namespace of the resources.")
+	fs.DurationVar(&o.Timeout, "timeout", 0, "The length of time to wait for the rollout, zero means never timeout.")
+	fs.Int64Var(&o.ToRevision, "to-revision", 0, "The revision to roll back to. Defaults to the previous revision.")
+	fs.StringVarP(&o.Filename, "filename", "f", "", "File containing the edited DataSet for diff.")
+	if err := fs.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+
+	cmd := fs.Args()
+	if len(cmd) == 0 {
+		fs.Usage()
+		return nil
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	if o.Namespace == "" {
+		ns, _, err := clientConfig.Namespace()
+		if err != nil {
+			return err
+		}
+		o.Namespace = ns
+	}
+	cfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	if o.KubeClient, err = kubernetes.NewForConfig(cfg); err != nil {
+		return err
+	}
+	if o.KudaClient, err = versioned.NewForConfig(cfg); err != nil {
+		return err
+	}
+
+	return o.run(context.Background(), cmd)
+}
+
+func (o *Options) run(ctx context.Context, cmd []string) error {
+	switch {
+	case len(cmd) == 2 && cmd[0] == "status":
+		return o.RunStatus(ctx, cmd[1])
+	case len(cmd) == 3 && cmd[0] == "rollout":
+		switch cmd[1] {
+		case "status":
+			return o.RunRolloutStatus(ctx, cmd[2])
+		case "pause":
+			return o.RunRolloutPause(ctx, cmd[2], true)
+		case "resume":
+			return o.RunRolloutPause(ctx, cmd[2], false)
+		case "history":
+			return o.RunRolloutHistory(ctx, cmd[2])
+		case "undo":
+			return o.RunRolloutUndo(ctx, cmd[2])
+		}
+	case len(cmd) == 2 && cmd[0] == "which":
+		return o.RunWhich(ctx, cmd[1])
+	case len(cmd) == 2 && cmd[0] == "diff":
+		return o.RunDiff(ctx, cmd[1])
+	}
+
+	return fmt.Errorf("unknown command %q, run 'kubectl kuda --help' for usage", cmd)
+}
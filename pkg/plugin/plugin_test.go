@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestRolloutStatus(t *testing.T) {
+	ds := &datav1alpha1.DataSet{ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Generation: 2}}
+	ds.Status.ObservedGeneration = 1
+
+	msg, done := rolloutStatus(ds)
+	assert.False(t, done)
+	assert.Contains(t, msg, "spec update to be observed")
+
+	ds.Status.ObservedGeneration = 2
+	ds.Status.Replicas = 3
+	ds.Status.UpdatedReplicas = 2
+	msg, done = rolloutStatus(ds)
+	assert.False(t, done)
+	assert.Contains(t, msg, "2 of 3 replicas are updated")
+
+	ds.Spec.Paused = true
+	msg, done = rolloutStatus(ds)
+	assert.False(t, done)
+	assert.Contains(t, msg, "paused")
+
+	ds.Spec.Paused = false
+	ds.Status.Conditions = []metav1.Condition{{
+		Type:    datav1alpha1.DataSetProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  "OutsideUpdateWindow",
+		Message: "updates of 1 data resource(s) are held until the update window opens at 2021-11-09T01:00:00Z",
+	}}
+	msg, done = rolloutStatus(ds)
+	assert.False(t, done)
+	assert.Contains(t, msg, "is held: updates of 1 data resource(s)")
+
+	ds.Status.Conditions = nil
+	ds.Status.UpdatedReplicas = 3
+	ds.Status.Activation = &datav1alpha1.ActivationStatus{Generation: 2, ActiveGeneration: 1, Staged: 2}
+	msg, done = rolloutStatus(ds)
+	assert.False(t, done)
+	assert.Contains(t, msg, "2 of 3 updated replicas are staged")
+
+	ds.Status.Activation.ActiveGeneration = 2
+	ds.Status.SuccessReplicas = 3
+	_, done = rolloutStatus(ds)
+	assert.True(t, done)
+}
+
+func TestFindUndoRevision(t *testing.T) {
+	revisions := []appsv1.ControllerRevision{
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-ds-a"}, Revision: 1},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-ds-b"}, Revision: 2},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-ds-c"}, Revision: 3},
+	}
+
+	cr, err := findUndoRevision(revisions, "test-ds-c", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-ds-b", cr.Name)
+
+	cr, err = findUndoRevision(revisions, "test-ds-c", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-ds-a", cr.Name)
+
+	_, err = findUndoRevision(revisions, "test-ds-c", 5)
+	assert.Error(t, err)
+
+	_, err = findUndoRevision(revisions[2:], "test-ds-c", 0)
+	assert.Error(t, err)
+}
+
+func TestMatchDataSets(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-pod",
+		Labels:      map[string]string{"app": "test"},
+		Annotations: map[string]string{datav1alpha1.KudaKeyDataSet: "injected"},
+	}}
+	datasets := []datav1alpha1.DataSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "injected"}, Spec: datav1alpha1.DataSetSpec{WorkloadSelector: map[string]string{"app": "old"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "matched"}, Spec: datav1alpha1.DataSetSpec{WorkloadSelector: map[string]string{"app": "test"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Spec: datav1alpha1.DataSetSpec{WorkloadSelector: map[string]string{"app": "other"}}},
+	}
+
+	matched := matchDataSets(pod, datasets)
+	assert.Len(t, matched, 2)
+	assert.Equal(t, "injected", matched[0].Name)
+	assert.Equal(t, "matched", matched[1].Name)
+}
+
+func TestDiffDataSet(t *testing.T) {
+	items := []datav1alpha1.DataItem{
+		{Name: "model", Namespace: "ns", Version: "v1", RemotePath: "/model", LocalPath: "/data/model", DataSourceType: "hdfs"},
+		{Name: "dict", Namespace: "ns", Version: "v1", RemotePath: "/dict", LocalPath: "/data/dict", DataSourceType: "hdfs"},
+	}
+	data := datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{datav1alpha1.KudaKeyPod: "pod-a"}},
+		Spec:       datav1alpha1.DataSpec{DataItems: items},
+	}
+	removedData := datav1alpha1.Data{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{datav1alpha1.KudaKeyPod: "pod-c"}},
+		Spec:       datav1alpha1.DataSpec{DataItems: items},
+	}
+
+	template := &datav1alpha1.DataTemplateSpec{
+		DataItems: []datav1alpha1.DataItem{
+			{Name: "model", Namespace: "ns", Version: "v2", RemotePath: "/model", LocalPath: "/data/model", DataSourceType: "hdfs",
+				Sync: &datav1alpha1.SyncPolicy{Mode: datav1alpha1.SyncModeMirror}},
+			{Name: "index", Namespace: "ns", Version: "v1", RemotePath: "/index", LocalPath: "/data/index", DataSourceType: "hdfs"},
+		},
+	}
+
+	changes := diffDataSet(template, []datav1alpha1.Data{data, removedData}, []string{"pod-a", "pod-b"})
+	assert.Equal(t, []itemChange{
+		{Pod: "pod-a", Item: "ns/dict", Change: changeRemoved},
+		{Pod: "pod-a", Item: "ns/index", Change: changeAdded, Detail: "version v1"},
+		{Pod: "pod-a", Item: "ns/model", Change: changeModified, Detail: "version v1 -> v2, sync changed"},
+		{Pod: "pod-b", Item: "ns/model", Change: changeAdded, Detail: "pod newly matches the workload selector"},
+		{Pod: "pod-b", Item: "ns/index", Change: changeAdded, Detail: "pod newly matches the workload selector"},
+		{Pod: "pod-c", Item: "*", Change: changeRemoved, Detail: "pod no longer matches the workload selector"},
+	}, changes)
+}
+
+func TestPrintDataSetStatus(t *testing.T) {
+	ds := &datav1alpha1.DataSet{ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: "default"}}
+	ds.Status.CurrentRevision = "test-ds-1234567890"
+	datas := []datav1alpha1.Data{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-pod",
+				Labels: map[string]string{
+					datav1alpha1.KudaKeyPod:      "test-pod",
+					datav1alpha1.KudaKeyRevision: "test-ds-1234567890",
+				},
+			},
+			Status: datav1alpha1.DataStatus{
+				DataItemsStatus: []datav1alpha1.DataItemStatus{
+					{Name: "model", Namespace: "ns", Version: "v1", Phase: datav1alpha1.DataFailed, Message: "file not found"},
+				},
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	printDataSetStatus(out, ds, datas, map[string]string{"test-pod": "node-1"})
+	assert.Contains(t, out.String(), "default/test-ds")
+	assert.Regexp(t, `test-pod\s+node-1\s+test-ds-1234567890\s+ns/model\s+v1\s+failed\s+file not found`, out.String())
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const rolloutPollInterval = 2 * time.Second
+
+// RunRolloutStatus waits until all the replicas of the dataset are updated and ready.
+func (o *Options) RunRolloutStatus(ctx context.Context, name string) error {
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	lastMsg := ""
+	return wait.PollImmediateUntil(rolloutPollInterval, func() (bool, error) {
+		ds, err := o.KudaClient.DataV1alpha1().DataSets(o.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		msg, done := rolloutStatus(ds)
+		if msg != lastMsg {
+			fmt.Fprintln(o.Out, msg)
+			lastMsg = msg
+		}
+		return done, nil
+	}, ctx.Done())
+}
+
+// rolloutStatus returns the message of the rollout progress and whether it is finished.
+func rolloutStatus(ds *datav1alpha1.DataSet) (string, bool) {
+	if ds.Status.ObservedGeneration < ds.Generation {
+		return fmt.Sprintf("Waiting for dataset %q spec update to be observed...", ds.Name), false
+	}
+	if ds.Spec.Paused {
+		return fmt.Sprintf("Rollout of dataset %q is paused: %d of %d replicas are updated", ds.Name,
+			ds.Status.UpdatedReplicas, ds.Status.Replicas), false
+	}
+	if condition := meta.FindStatusCondition(ds.Status.Conditions, datav1alpha1.DataSetProgressing); condition != nil && condition.Status == metav1.ConditionFalse {
+		return fmt.Sprintf("Rollout of dataset %q is held: %s", ds.Name, condition.Message), false
+	}
+	if ds.Status.UpdatedReplicas < ds.Status.Replicas {
+		return fmt.Sprintf("Waiting for dataset %q rollout to finish: %d of %d replicas are updated...", ds.Name,
+			ds.Status.UpdatedReplicas, ds.Status.Replicas), false
+	}
+	if activation := ds.Status.Activation; activation != nil && activation.ActiveGeneration < activation.Generation {
+		return fmt.Sprintf("Waiting for dataset %q rollout to be activated: %d of %d updated replicas are staged...", ds.Name,
+			activation.Staged, ds.Status.Replicas), false
+	}
+	if ds.Status.SuccessReplicas < ds.Status.Replicas {
+		return fmt.Sprintf("Waiting for dataset %q rollout to finish: %d of %d updated replicas are ready...", ds.Name,
+			ds.Status.SuccessReplicas, ds.Status.Replicas), false
+	}
+	return fmt.Sprintf("dataset %q successfully rolled out", ds.Name), true
+}
+
+// RunRolloutPause pauses or resumes the rollout of the dataset.
+func (o *Options) RunRolloutPause(ctx context.Context, name string, paused bool) error {
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"paused": paused}})
+	if err != nil {
+		return err
+	}
+	if _, err := o.KudaClient.DataV1alpha1().DataSets(o.Namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+
+	if paused {
+		fmt.Fprintf(o.Out, "dataset.data.kuda.io/%s paused\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "dataset.data.kuda.io/%s resumed\n", name)
+	}
+	return nil
+}
+
+// RunRolloutHistory prints the revisions of the dataset.
+func (o *Options) RunRolloutHistory(ctx context.Context, name string) error {
+	revisions, err := o.listRevisions(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tNAME\tDATA ITEMS")
+	for _, cr := range revisions {
+		template := &datav1alpha1.DataTemplateSpec{}
+		if err := json.Unmarshal(cr.Data.Raw, template); err != nil {
+			return err
+		}
+		items := make([]string, 0, len(template.DataItems))
+		for _, item := range template.DataItems {
+			items = append(items, fmt.Sprintf("%s/%s@%s", item.Namespace, item.Name, item.Version))
+		}
+		fmt.Fprintf(w, "%d\t%s\t%v\n", cr.Revision, cr.Name, items)
+	}
+	return w.Flush()
+}
+
+// RunRolloutUndo rolls back the template of the dataset to the previous or the specified revision.
+func (o *Options) RunRolloutUndo(ctx context.Context, name string) error {
+	ds, err := o.KudaClient.DataV1alpha1().DataSets(o.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	revisions, err := o.listRevisions(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	target, err := findUndoRevision(revisions, ds.Status.CurrentRevision, o.ToRevision)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": json.RawMessage(target.Data.Raw)},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := o.KudaClient.DataV1alpha1().DataSets(o.Namespace).Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.Out, "dataset.data.kuda.io/%s rolled back to revision %d\n", name, target.Revision)
+	return nil
+}
+
+// listRevisions returns the controller revisions of the dataset sorted by the revision number.
+func (o *Options) listRevisions(ctx context.Context, name string) ([]appsv1.ControllerRevision, error) {
+	revisionList, err := o.KubeClient.AppsV1().ControllerRevisions(o.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{datav1alpha1.KudaKeyDataSet: name}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := revisionList.Items
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	return revisions, nil
+}
+
+// findUndoRevision returns the revision to roll back to. The sorted revisions are required.
+func findUndoRevision(revisions []appsv1.ControllerRevision, current string, toRevision int64) (*appsv1.ControllerRevision, error) {
+	if toRevision > 0 {
+		for i := range revisions {
+			if revisions[i].Revision == toRevision {
+				return &revisions[i], nil
+			}
+		}
+		return nil, fmt.Errorf("unable to find the specified revision %d", toRevision)
+	}
+
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].Name != current {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no rollout history found")
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// RunStatus prints the status of each data item of each pod of the dataset.
+func (o *Options) RunStatus(ctx context.Context, name string) error {
+	ds, err := o.KudaClient.DataV1alpha1().DataSets(o.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	dataList, err := o.listDataForDataSet(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	podList, err := o.KubeClient.CoreV1().Pods(o.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(ds.Spec.WorkloadSelector).String(),
+	})
+	if err != nil {
+		return err
+	}
+	nodes := make(map[string]string, len(podList.Items))
+	for _, pod := range podList.Items {
+		nodes[pod.Name] = pod.Spec.NodeName
+	}
+
+	printDataSetStatus(o.Out, ds, dataList.Items, nodes)
+	return nil
+}
+
+func (o *Options) listDataForDataSet(ctx context.Context, name string) (*datav1alpha1.DataList, error) {
+	return o.KudaClient.DataV1alpha1().Datas(o.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{datav1alpha1.KudaKeyDataSet: name}).String(),
+	})
+}
+
+// printDataSetStatus prints the summary of the dataset and a table of the data items per pod.
+func printDataSetStatus(out io.Writer, ds *datav1alpha1.DataSet, datas []datav1alpha1.Data, nodes map[string]string) {
+	fmt.Fprintf(out, "DataSet:    %s/%s\n", ds.Namespace, ds.Name)
+	fmt.Fprintf(out, "Revision:   %s\n", ds.Status.CurrentRevision)
+	fmt.Fprintf(out, "Paused:     %t\n", ds.Spec.Paused)
+	fmt.Fprintf(out, "Replicas:   %d total, %d ready, %d updated, %d uninjected\n\n",
+		ds.Status.Replicas, ds.Status.SuccessReplicas, ds.Status.UpdatedReplicas, ds.Status.UninjectedReplicas)
+
+	sort.Slice(datas, func(i, j int) bool { return datas[i].Name < datas[j].Name })
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "POD\tNODE\tREVISION\tITEM\tVERSION\tPHASE\tMESSAGE")
+	for _, data := range datas {
+		pod := data.Labels[datav1alpha1.KudaKeyPod]
+		revision := data.Labels[datav1alpha1.KudaKeyRevision]
+		if len(data.Status.DataItemsStatus) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t<none>\t\t\t\n", pod, nodes[pod], revision)
+			continue
+		}
+		for _, item := range data.Status.DataItemsStatus {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s/%s\t%s\t%s\t%s\n", pod, nodes[pod], revision,
+				item.Namespace, item.Name, item.Version, item.Phase, item.Message)
+		}
+	}
+	w.Flush()
+}
+
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+// RunWhich prints the datasets and data items affecting the pod.
+func (o *Options) RunWhich(ctx context.Context, name string) error {
+	pod, err := o.KubeClient.CoreV1().Pods(o.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	dsList, err := o.KudaClient.DataV1alpha1().DataSets(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	dataList, err := o.KudaClient.DataV1alpha1().Datas(o.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{datav1alpha1.KudaKeyPod: name}).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.Out, "Pod:        %s/%s\n", pod.Namespace, pod.Name)
+	fmt.Fprintf(o.Out, "Node:       %s\n", pod.Spec.NodeName)
+	if injected, ok := pod.Annotations[datav1alpha1.KudaKeyDataSet]; ok {
+		fmt.Fprintf(o.Out, "Injected:   %s\n\n", injected)
+	} else {
+		fmt.Fprintf(o.Out, "Injected:   <none>\n\n")
+	}
+
+	dataByDataSet := make(map[string]*datav1alpha1.Data, len(dataList.Items))
+	for i := range dataList.Items {
+		dataByDataSet[dataList.Items[i].Labels[datav1alpha1.KudaKeyDataSet]] = &dataList.Items[i]
+	}
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "DATASET\tITEM\tVERSION\tLOCAL PATH\tPHASE")
+	for _, ds := range matchDataSets(pod, dsList.Items) {
+		data, ok := dataByDataSet[ds.Name]
+		if !ok {
+			fmt.Fprintf(w, "%s\t<none>\t\t\t\n", ds.Name)
+			continue
+		}
+		phases := make(map[string]datav1alpha1.DataPhase, len(data.Status.DataItemsStatus))
+		for _, item := range data.Status.DataItemsStatus {
+			phases[item.Namespace+"/"+item.Name] = item.Phase
+		}
+		for _, item := range data.Spec.DataItems {
+			key := item.Namespace + "/" + item.Name
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ds.Name, key, item.Version, item.LocalPath, phases[key])
+		}
+	}
+	return w.Flush()
+}
+
+// matchDataSets returns the datasets applied to the pod, either injected by the webhook
+// or matching the labels of the pod.
+func matchDataSets(pod *v1.Pod, datasets []datav1alpha1.DataSet) []datav1alpha1.DataSet {
+	matched := make([]datav1alpha1.DataSet, 0)
+	for _, ds := range datasets {
+		if pod.Annotations[datav1alpha1.KudaKeyDataSet] == ds.Name ||
+			utils.ContainsAll(pod.GetLabels(), ds.Spec.WorkloadSelector) {
+			matched = append(matched, ds)
+		}
+	}
+	return matched
+}
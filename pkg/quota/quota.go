@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota computes the usage of the data pulled through kuda in a namespace and
+// checks it against the DataQuotas, it's shared by the webhook admitting the DataSets and
+// the controllers delivering their data.
+package quota
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// DataSetUsage returns the usage of the dataset, the data items without a declared size
+// are not counted in the size.
+func DataSetUsage(ds *datav1alpha1.DataSet) datav1alpha1.DataQuotaUsage {
+	usage := datav1alpha1.DataQuotaUsage{
+		DataSets: 1,
+		Size:     resource.Quantity{Format: resource.BinarySI},
+		Items:    len(ds.Spec.Template.DataItems),
+	}
+	for _, item := range ds.Spec.Template.DataItems {
+		if item.Size != nil {
+			usage.Size.Add(*item.Size)
+		}
+	}
+	return usage
+}
+
+// Add returns the sum of the usages.
+func Add(a, b datav1alpha1.DataQuotaUsage) datav1alpha1.DataQuotaUsage {
+	sum := datav1alpha1.DataQuotaUsage{
+		DataSets:  a.DataSets + b.DataSets,
+		Size:      a.Size.DeepCopy(),
+		Items:     a.Items + b.Items,
+		Downloads: a.Downloads + b.Downloads,
+	}
+	sum.Size.Add(b.Size)
+	return sum
+}
+
+// Check returns the messages of the size and items limits of the quota exceeded by the
+// usage, the concurrent downloads are throttled rather than checked.
+func Check(quota *datav1alpha1.DataQuota, used datav1alpha1.DataQuotaUsage) []string {
+	msgs := make([]string, 0)
+	if max := quota.Spec.MaxSize; max != nil && used.Size.Cmp(*max) > 0 {
+		msgs = append(msgs, fmt.Sprintf("the total size %s exceeds the max size %s", used.Size.String(), max.String()))
+	}
+	if max := quota.Spec.MaxItems; max != nil && used.Items > int(*max) {
+		msgs = append(msgs, fmt.Sprintf("the total number %d of data items exceeds the max items %d", used.Items, *max))
+	}
+	return msgs
+}
+
+// IsLimited returns true if the quota limits the size or items of the DataSets.
+func IsLimited(quota *datav1alpha1.DataQuota) bool {
+	return quota.Spec.MaxSize != nil || quota.Spec.MaxItems != nil
+}
+
+// Usage returns the usage of the datasets, the datasets being deleted are skipped.
+func Usage(dataSets []datav1alpha1.DataSet) datav1alpha1.DataQuotaUsage {
+	used := datav1alpha1.DataQuotaUsage{Size: resource.Quantity{Format: resource.BinarySI}}
+	for i := range dataSets {
+		if dataSets[i].GetDeletionTimestamp() != nil {
+			continue
+		}
+		used = Add(used, DataSetUsage(&dataSets[i]))
+	}
+	return used
+}
+
+// GetExceededDataSets returns the datasets exceeding the quota with the messages. The
+// datasets created earlier take the quota first, and the exceeded ones take none of it,
+// so that the result is stable however the quota is lowered.
+func GetExceededDataSets(quota *datav1alpha1.DataQuota, dataSets []datav1alpha1.DataSet) map[string]string {
+	exceeded := make(map[string]string)
+	if !IsLimited(quota) {
+		return exceeded
+	}
+
+	sorted := make([]*datav1alpha1.DataSet, 0, len(dataSets))
+	for i := range dataSets {
+		if dataSets[i].GetDeletionTimestamp() == nil {
+			sorted = append(sorted, &dataSets[i])
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp, sorted[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	used := datav1alpha1.DataQuotaUsage{Size: resource.Quantity{Format: resource.BinarySI}}
+	for _, ds := range sorted {
+		next := Add(used, DataSetUsage(ds))
+		if msgs := Check(quota, next); len(msgs) > 0 {
+			exceeded[ds.Name] = msgs[0]
+			continue
+		}
+		used = next
+	}
+	return exceeded
+}
+
+// IsDownloading returns true if any data item of the data is neither downloaded nor failed
+// at the version of the spec. The data queued for the download lease is counted too, since
+// it's going to download once the lease is handed out. The staged data items waiting for
+// the activation are downloaded already, so that they never hold the downloads of the
+// other replicas which the activation waits for.
+func IsDownloading(data *datav1alpha1.Data) bool {
+	phases := make(map[string]datav1alpha1.DataPhase, len(data.Status.DataItemsStatus))
+	for _, item := range data.Status.DataItemsStatus {
+		phases[item.Namespace+"/"+item.Name+"/"+item.Version] = item.Phase
+	}
+	for _, item := range data.Spec.DataItems {
+		switch phases[item.Namespace+"/"+item.Name+"/"+item.Version] {
+		case datav1alpha1.DataSuccess, datav1alpha1.DataStaged, datav1alpha1.DataFailed:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Downloads returns the number of the data downloading.
+func Downloads(dataList []datav1alpha1.Data) int {
+	downloads := 0
+	for i := range dataList {
+		if IsDownloading(&dataList[i]) {
+			downloads += 1
+		}
+	}
+	return downloads
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func newDataSet(name string, created time.Time, sizes ...string) datav1alpha1.DataSet {
+	ds := datav1alpha1.DataSet{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)}}
+	for _, size := range sizes {
+		item := datav1alpha1.DataItem{Name: name}
+		if size != "" {
+			quantity := resource.MustParse(size)
+			item.Size = &quantity
+		}
+		ds.Spec.Template.DataItems = append(ds.Spec.Template.DataItems, item)
+	}
+	return ds
+}
+
+func TestUsage(t *testing.T) {
+	now := time.Now()
+	deleting := newDataSet("deleting", now, "1Gi")
+	deleting.DeletionTimestamp = &metav1.Time{Time: now}
+
+	used := Usage([]datav1alpha1.DataSet{
+		newDataSet("model", now, "100Gi", "512Mi"),
+		newDataSet("dict", now, ""),
+		deleting,
+	})
+	assert.Equal(t, 2, used.DataSets)
+	assert.Equal(t, 3, used.Items)
+	assert.Equal(t, "102912Mi", used.Size.String())
+}
+
+func TestCheck(t *testing.T) {
+	maxSize := resource.MustParse("10Gi")
+	quota := &datav1alpha1.DataQuota{Spec: datav1alpha1.DataQuotaSpec{MaxSize: &maxSize, MaxItems: pointer.Int32Ptr(2)}}
+
+	ds := newDataSet("model", time.Now(), "6Gi", "4Gi")
+	assert.Empty(t, Check(quota, DataSetUsage(&ds)))
+
+	ds = newDataSet("model", time.Now(), "6Gi", "4Gi", "1Mi")
+	assert.Equal(t, []string{
+		"the total size 10241Mi exceeds the max size 10Gi",
+		"the total number 3 of data items exceeds the max items 2",
+	}, Check(quota, DataSetUsage(&ds)))
+
+	// Nothing is checked without the limits.
+	assert.False(t, IsLimited(&datav1alpha1.DataQuota{}))
+	assert.Empty(t, Check(&datav1alpha1.DataQuota{}, DataSetUsage(&ds)))
+}
+
+func TestGetExceededDataSets(t *testing.T) {
+	maxSize := resource.MustParse("10Gi")
+	quota := &datav1alpha1.DataQuota{Spec: datav1alpha1.DataQuotaSpec{MaxSize: &maxSize}}
+
+	now := time.Now().Truncate(time.Second)
+	dataSets := []datav1alpha1.DataSet{
+		newDataSet("c", now.Add(time.Minute), "2Gi"),
+		newDataSet("b", now, "6Gi"),
+		newDataSet("a", now, "3Gi"),
+		newDataSet("d", now.Add(2*time.Minute), "1Gi"),
+	}
+
+	// The datasets created earlier take the quota first, and the exceeded one takes none.
+	assert.Equal(t, map[string]string{
+		"c": "the total size 11Gi exceeds the max size 10Gi",
+	}, GetExceededDataSets(quota, dataSets))
+
+	assert.Empty(t, GetExceededDataSets(&datav1alpha1.DataQuota{}, dataSets))
+}
+
+func TestIsDownloading(t *testing.T) {
+	data := &datav1alpha1.Data{
+		Spec: datav1alpha1.DataSpec{DataItems: []datav1alpha1.DataItem{
+			{Name: "model", Namespace: "ns", Version: "v2"},
+			{Name: "dict", Namespace: "ns", Version: "v1"},
+		}},
+	}
+	assert.True(t, IsDownloading(data))
+
+	data.Status.DataItemsStatus = datav1alpha1.DataItemsStatus{
+		{Name: "model", Namespace: "ns", Version: "v1", Phase: datav1alpha1.DataSuccess},
+		{Name: "dict", Namespace: "ns", Version: "v1", Phase: datav1alpha1.DataFailed},
+	}
+	assert.True(t, IsDownloading(data))
+
+	data.Status.DataItemsStatus[0].Version = "v2"
+	assert.False(t, IsDownloading(data))
+	assert.Equal(t, 1, Downloads([]datav1alpha1.Data{*data, {Spec: data.Spec}}))
+
+	// The data staged for the activation is downloaded already.
+	data.Status.DataItemsStatus[0].Phase = datav1alpha1.DataStaged
+	assert.False(t, IsDownloading(data))
+
+	// The data queued for the download lease is going to download.
+	data.Spec.Queued = true
+	data.Status.DataItemsStatus = nil
+	assert.True(t, IsDownloading(data))
+}
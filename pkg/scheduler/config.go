@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+// Args defines the args of the plugin, which are set by the pluginConfig of the
+// KubeSchedulerConfiguration.
+type Args struct {
+	// BytesWeight is the weight of the cached bytes of the required data items in the score.
+	BytesWeight int64 `json:"bytesWeight"`
+	// ItemsWeight is the weight of the ratio of the required data items cached in the score.
+	ItemsWeight int64 `json:"itemsWeight"`
+	// MinCachedRatio scores zero the nodes caching less than the ratio of the required data
+	// items. It's ignored if no node satisfies it, and zero disables it.
+	MinCachedRatio float64 `json:"minCachedRatio"`
+	// HostCacheIsolation is the isolation of the host cache, which should be the same as
+	// the webhook config. Only the data items cached for the tenant of the pod are counted.
+	HostCacheIsolation string `json:"hostCacheIsolation,omitempty"`
+	// KubeConfigPath is the kubeconfig to read the kuda resources, the in-cluster config is
+	// used if empty.
+	KubeConfigPath string `json:"kubeConfigPath,omitempty"`
+}
+
+// DefaultArgs returns the args scoring nodes by the cached bytes only.
+func DefaultArgs() *Args {
+	return &Args{BytesWeight: 1, HostCacheIsolation: utils.HostCacheIsolationShared}
+}
+
+// Validate validates the args.
+func (a *Args) Validate() error {
+	if a.BytesWeight < 0 || a.ItemsWeight < 0 {
+		return fmt.Errorf("weights must be greater than or equal to 0")
+	}
+	if a.BytesWeight+a.ItemsWeight == 0 {
+		return fmt.Errorf("at least one of bytesWeight and itemsWeight must be positive")
+	}
+	if !utils.IsValidHostCacheIsolation(a.HostCacheIsolation) {
+		return fmt.Errorf("invalid hostCacheIsolation %q", a.HostCacheIsolation)
+	}
+	if a.MinCachedRatio < 0 || a.MinCachedRatio > 1 {
+		return fmt.Errorf("minCachedRatio must be between 0 and 1")
+	}
+	return nil
+}
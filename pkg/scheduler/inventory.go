@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+// CachedItem describes a data item cached on a node.
+type CachedItem struct {
+	// Tenant is the sub path of the host cache holding the item, it's empty for the
+	// shared cache.
+	Tenant    string
+	Namespace string
+	Name      string
+	Version   string
+	Size      int64
+}
+
+// Inventory provides the data items cached on each node.
+type Inventory interface {
+	// NodeItems returns the cached data items by node name.
+	NodeItems(ctx context.Context) (map[string][]CachedItem, error)
+}
+
+// nodeDataInventory reads the cached data items of nodes from the NodeData resources
+// maintained by the kuda manager.
+type nodeDataInventory struct {
+	client client.Reader
+}
+
+// NewNodeDataInventory returns the inventory read from the NodeData resources.
+func NewNodeDataInventory(reader client.Reader) Inventory {
+	return &nodeDataInventory{client: reader}
+}
+
+// NodeItems implements Inventory.
+func (i *nodeDataInventory) NodeItems(ctx context.Context) (map[string][]CachedItem, error) {
+	nodeDataList := &datav1alpha1.NodeDataList{}
+	if err := i.client.List(ctx, nodeDataList); err != nil {
+		return nil, err
+	}
+
+	nodeItems := make(map[string][]CachedItem, len(nodeDataList.Items))
+	for _, nodeData := range nodeDataList.Items {
+		for _, item := range nodeData.Status.Items {
+			nodeItems[nodeData.Name] = append(nodeItems[nodeData.Name], CachedItem{
+				Tenant:    item.Tenant,
+				Namespace: item.Namespace,
+				Name:      item.Name,
+				Version:   item.Version,
+				Size:      item.Size,
+			})
+		}
+	}
+
+	return nodeItems, nil
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	// Name is the name of the plugin in the KubeSchedulerConfiguration.
+	Name = "KudaDataLocality"
+
+	preScoreStateKey framework.StateKey = "PreScore" + Name
+
+	// cacheSyncTimeout is the timeout waiting for the cache of the kuda resources to sync.
+	cacheSyncTimeout = time.Minute
+)
+
+var (
+	log    = ctrl.Log.WithName("scheduler")
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(datav1alpha1.AddToScheme(scheme))
+}
+
+// DataLocality is a scheduler framework plugin scoring nodes by the data items of pods
+// already cached on them. It never filters nodes, so that the data locality is only a
+// preference and pods are never made unschedulable by the cache.
+type DataLocality struct {
+	args      *Args
+	client    client.Reader
+	inventory Inventory
+}
+
+var _ framework.PreScorePlugin = &DataLocality{}
+var _ framework.ScorePlugin = &DataLocality{}
+
+// New is the factory of the plugin registered into the kuda scheduler. The DataSets and
+// NodeData are read from the cache of the kuda resources, which is started and synced here,
+// so that no node is scored by an empty cache. The cache is stopped if it fails to sync,
+// otherwise it runs until the scheduler exits, since plugins are never closed.
+func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	args := DefaultArgs()
+	if err := frameworkruntime.DecodeInto(obj, args); err != nil {
+		return nil, err
+	}
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", args.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	c, err := cluster.New(config, func(o *cluster.Options) { o.Scheme = scheme })
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// The informers are added before the cache is started, which are synced by it then.
+	for _, obj := range []client.Object{&datav1alpha1.DataSet{}, &datav1alpha1.NodeData{}} {
+		if _, err := c.GetCache().GetInformer(ctx, obj); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	go func() {
+		// Waiting for the cache to sync is aborted if it fails to start.
+		defer cancel()
+		if err := c.Start(ctx); err != nil {
+			log.Error(err, "failed to start the cache of the kuda resources")
+		}
+	}()
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer syncCancel()
+	if !c.GetCache().WaitForCacheSync(syncCtx) {
+		cancel()
+		return nil, fmt.Errorf("failed to sync the cache of the kuda resources")
+	}
+
+	return NewDataLocality(args, c.GetClient(), NewNodeDataInventory(c.GetClient())), nil
+}
+
+// NewDataLocality returns DataLocality object by the args, client and inventory.
+func NewDataLocality(args *Args, reader client.Reader, inventory Inventory) *DataLocality {
+	return &DataLocality{
+		args:      args,
+		client:    reader,
+		inventory: inventory,
+	}
+}
+
+// Name implements framework.Plugin.
+func (p *DataLocality) Name() string {
+	return Name
+}
+
+// nodeCache describes the required data items of the pod cached on a node.
+type nodeCache struct {
+	items int
+	bytes int64
+}
+
+// preScoreState is the required data items of the pod cached on each node, it's computed
+// once per scheduling cycle and read-only afterwards.
+type preScoreState struct {
+	required int
+	caches   map[string]nodeCache
+	// maxBytes is the max cached bytes of the nodes to be scored.
+	maxBytes int64
+	// ratioMet is true if any node caches MinCachedRatio of the required data items, then
+	// the nodes caching less are scored zero.
+	ratioMet bool
+}
+
+// Clone implements framework.StateData.
+func (s *preScoreState) Clone() framework.StateData {
+	return s
+}
+
+// PreScore computes the required data items of the pod cached on each node, and the max
+// cached bytes of the nodes, by which the cached bytes of each node are normalized. If the
+// cache can't be read, all nodes are scored zero instead of failing the pod.
+func (p *DataLocality) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
+	s, err := p.nodeCaches(ctx, pod)
+	if err != nil {
+		log.Error(err, "failed to read the cached data items, scoring nodes zero", "pod", pod.Namespace+"/"+pod.Name)
+		s = &preScoreState{}
+	}
+	for _, node := range nodes {
+		cache := s.caches[node.Name]
+		if s.required > 0 && p.args.MinCachedRatio > 0 && float64(cache.items)/float64(s.required) >= p.args.MinCachedRatio {
+			s.ratioMet = true
+		}
+		if cache.bytes > s.maxBytes {
+			s.maxBytes = cache.bytes
+		}
+	}
+	state.Write(preScoreStateKey, s)
+	return nil
+}
+
+// Score scores the node by the required data items of the pod cached on it. The cached
+// bytes are normalized by the max cached bytes of the nodes, and the cached items by the
+// number of required items, then they are weighted into the range of node scores. The
+// nodes caching less than MinCachedRatio of the required data items are scored zero, if
+// any node satisfies the ratio.
+func (p *DataLocality) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	data, err := state.Read(preScoreStateKey)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	s, ok := data.(*preScoreState)
+	if !ok {
+		return 0, framework.AsStatus(fmt.Errorf("%+v convert to preScoreState error", data))
+	}
+
+	cache := s.caches[nodeName]
+	if s.ratioMet && float64(cache.items)/float64(s.required) < p.args.MinCachedRatio {
+		return 0, nil
+	}
+	var score float64
+	if s.maxBytes > 0 {
+		score += float64(p.args.BytesWeight) * float64(cache.bytes) / float64(s.maxBytes)
+	}
+	if s.required > 0 {
+		score += float64(p.args.ItemsWeight) * float64(cache.items) / float64(s.required)
+	}
+	totalWeight := p.args.BytesWeight + p.args.ItemsWeight
+	return int64(score * float64(framework.MaxNodeScore) / float64(totalWeight)), nil
+}
+
+// ScoreExtensions implements framework.ScorePlugin, the scores are normalized already.
+func (p *DataLocality) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+// nodeCaches returns the required data items of the pod cached on each node. Only the data
+// items cached for the tenant of the pod are counted, since the pod can't read the others
+// under the isolation of the host cache.
+func (p *DataLocality) nodeCaches(ctx context.Context, pod *v1.Pod) (*preScoreState, error) {
+	required, err := p.requiredItems(ctx, pod)
+	if err != nil || len(required) == 0 {
+		return &preScoreState{}, err
+	}
+
+	nodeItems, err := p.inventory.NodeItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := utils.HostCacheSubPath(p.args.HostCacheIsolation, pod.Namespace, pod.Annotations[datav1alpha1.KudaKeyDataSet])
+	s := &preScoreState{required: len(required), caches: make(map[string]nodeCache, len(nodeItems))}
+	for node, items := range nodeItems {
+		seen := make(map[string]bool, len(items))
+		cache := nodeCache{}
+		for _, item := range items {
+			if item.Tenant != tenant {
+				continue
+			}
+			key := itemKey(item.Namespace, item.Name, item.Version)
+			if !required[key] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			cache.items++
+			cache.bytes += item.Size
+		}
+		s.caches[node] = cache
+	}
+
+	return s, nil
+}
+
+// requiredItems returns the data items of the dataset injected into the pod.
+func (p *DataLocality) requiredItems(ctx context.Context, pod *v1.Pod) (map[string]bool, error) {
+	name, ok := pod.Annotations[datav1alpha1.KudaKeyDataSet]
+	if !ok {
+		return nil, nil
+	}
+
+	ds := &datav1alpha1.DataSet{}
+	if err := p.client.Get(ctx, types.NamespacedName{Name: name, Namespace: pod.Namespace}, ds); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	required := make(map[string]bool, len(ds.Spec.Template.DataItems))
+	for _, item := range ds.Spec.Template.DataItems {
+		required[itemKey(item.Namespace, item.Name, item.Version)] = true
+	}
+	return required, nil
+}
+
+func itemKey(namespace, name, version string) string {
+	return fmt.Sprintf("%s/%s@%s", namespace, name, version)
+}
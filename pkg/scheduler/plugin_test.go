@@ -0,0 +1,248 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+type staticInventory map[string][]CachedItem
+
+func (i staticInventory) NodeItems(ctx context.Context) (map[string][]CachedItem, error) {
+	return i, nil
+}
+
+type failedInventory struct{}
+
+func (i failedInventory) NodeItems(ctx context.Context) (map[string][]CachedItem, error) {
+	return nil, fmt.Errorf("failed to list NodeData")
+}
+
+func getTestDataSet() *datav1alpha1.DataSet {
+	return &datav1alpha1.DataSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: "default"},
+		Spec: datav1alpha1.DataSetSpec{
+			Template: datav1alpha1.DataTemplateSpec{
+				DataItems: []datav1alpha1.DataItem{
+					{Name: "model", Namespace: "ns", Version: "v1"},
+					{Name: "dict", Namespace: "ns", Version: "v1"},
+				},
+			},
+		},
+	}
+}
+
+func getTestPod() *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-pod",
+		Namespace:   "default",
+		Annotations: map[string]string{datav1alpha1.KudaKeyDataSet: "test-ds"},
+	}}
+}
+
+// getTestFramework returns the scheduler framework running the plugin in process.
+func getTestFramework(t *testing.T, args *Args) framework.Framework {
+	return getTestFrameworkWithInventory(t, args, getTestInventory())
+}
+
+func getTestInventory() Inventory {
+	return staticInventory{
+		"node-a": {
+			{Namespace: "ns", Name: "model", Version: "v1", Size: 900},
+			{Namespace: "ns", Name: "dict", Version: "v1", Size: 100},
+		},
+		"node-b": {
+			{Namespace: "ns", Name: "model", Version: "v1", Size: 900},
+			{Namespace: "ns", Name: "dict", Version: "v0", Size: 100},
+		},
+		"node-c": {
+			{Namespace: "ns", Name: "dict", Version: "v1", Size: 100},
+		},
+		"node-d": {
+			{Tenant: "default/test-ds", Namespace: "ns", Name: "model", Version: "v1", Size: 900},
+		},
+	}
+}
+
+func getTestFrameworkWithInventory(t *testing.T, args *Args, inventory Inventory) framework.Framework {
+	newDataSet := getTestDataSet()
+	newDataSet.Name = "new-ds"
+	newDataSet.Spec.Template.DataItems[0].Version = "v2"
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(getTestDataSet(), newDataSet).Build()
+	factory := func(_ runtime.Object, _ framework.Handle) (framework.Plugin, error) {
+		return NewDataLocality(args, c, inventory), nil
+	}
+
+	fwk, err := st.NewFramework([]st.RegisterPluginFunc{
+		st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+		st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		st.RegisterPluginAsExtensions(Name, factory, "PreScore", "Score"),
+	}, "kuda-scheduler")
+	assert.NoError(t, err)
+	return fwk
+}
+
+func getTestNodes(names ...string) []*v1.Node {
+	nodes := make([]*v1.Node, 0, len(names))
+	for _, name := range names {
+		nodes = append(nodes, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	return nodes
+}
+
+// runScore returns the scores of the nodes by the score plugins.
+func runScore(t *testing.T, fwk framework.Framework, pod *v1.Pod, nodes []*v1.Node) map[string]int64 {
+	ctx := context.Background()
+	state := framework.NewCycleState()
+	assert.True(t, fwk.RunPreScorePlugins(ctx, state, pod, nodes).IsSuccess())
+
+	pluginScores, status := fwk.RunScorePlugins(ctx, state, pod, nodes)
+	assert.True(t, status.IsSuccess())
+	scores := make(map[string]int64, len(nodes))
+	for _, score := range pluginScores[Name] {
+		scores[score.Name] = score.Score
+	}
+	return scores
+}
+
+func TestScore(t *testing.T) {
+	nodes := getTestNodes("node-a", "node-b", "node-c", "node-d")
+
+	t.Run("score by the cached bytes", func(t *testing.T) {
+		fwk := getTestFramework(t, DefaultArgs())
+		assert.Equal(t, map[string]int64{
+			"node-a": 100,
+			"node-b": 90,
+			"node-c": 10,
+			"node-d": 0,
+		}, runScore(t, fwk, getTestPod(), nodes))
+	})
+
+	t.Run("score by the weighted cached bytes and items", func(t *testing.T) {
+		fwk := getTestFramework(t, &Args{BytesWeight: 1, ItemsWeight: 1})
+		assert.Equal(t, map[string]int64{
+			"node-a": 100,
+			"node-b": 70,
+			"node-c": 30,
+			"node-d": 0,
+		}, runScore(t, fwk, getTestPod(), nodes))
+	})
+
+	t.Run("normalize the cached bytes by the nodes scored", func(t *testing.T) {
+		fwk := getTestFramework(t, DefaultArgs())
+		assert.Equal(t, map[string]int64{
+			"node-c": 100,
+			"node-d": 0,
+		}, runScore(t, fwk, getTestPod(), getTestNodes("node-c", "node-d")))
+	})
+
+	t.Run("score by the items cached for the tenant of the pod", func(t *testing.T) {
+		fwk := getTestFramework(t, &Args{BytesWeight: 1, HostCacheIsolation: utils.HostCacheIsolationDataSet})
+		assert.Equal(t, map[string]int64{
+			"node-a": 0,
+			"node-b": 0,
+			"node-c": 0,
+			"node-d": 100,
+		}, runScore(t, fwk, getTestPod(), nodes))
+	})
+
+	t.Run("score zero the nodes caching less than the ratio", func(t *testing.T) {
+		fwk := getTestFramework(t, &Args{BytesWeight: 1, MinCachedRatio: 1})
+		assert.Equal(t, map[string]int64{
+			"node-a": 100,
+			"node-b": 0,
+			"node-c": 0,
+			"node-d": 0,
+		}, runScore(t, fwk, getTestPod(), nodes))
+	})
+
+	t.Run("ignore the ratio if no node satisfies it", func(t *testing.T) {
+		fwk := getTestFramework(t, &Args{BytesWeight: 1, MinCachedRatio: 1})
+		// The new version of the model is cached nowhere.
+		pod := getTestPod()
+		pod.Annotations[datav1alpha1.KudaKeyDataSet] = "new-ds"
+		assert.Equal(t, map[string]int64{
+			"node-a": 100,
+			"node-b": 0,
+			"node-c": 100,
+			"node-d": 0,
+		}, runScore(t, fwk, pod, nodes))
+	})
+
+	t.Run("score zero if the cache can't be read", func(t *testing.T) {
+		fwk := getTestFrameworkWithInventory(t, DefaultArgs(), failedInventory{})
+		for _, score := range runScore(t, fwk, getTestPod(), nodes) {
+			assert.Equal(t, int64(0), score)
+		}
+	})
+
+	t.Run("score zero for pods without dataset", func(t *testing.T) {
+		fwk := getTestFramework(t, DefaultArgs())
+		for _, score := range runScore(t, fwk, &v1.Pod{}, nodes) {
+			assert.Equal(t, int64(0), score)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&runtime.Unknown{Raw: []byte(`{"bytesWeight": 0, "itemsWeight": 0}`)}, nil)
+	assert.Error(t, err)
+	_, err = New(&runtime.Unknown{Raw: []byte(`{"minCachedRatio": 2}`)}, nil)
+	assert.Error(t, err)
+	_, err = New(&runtime.Unknown{Raw: []byte(`{"hostCacheIsolation": "Pod"}`)}, nil)
+	assert.Error(t, err)
+}
+
+func TestNodeDataInventory(t *testing.T) {
+	nodeData := &datav1alpha1.NodeData{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status: datav1alpha1.NodeDataStatus{
+			Items: []datav1alpha1.CachedDataItem{
+				{Name: "model", Namespace: "ns", Version: "v1", Size: 900},
+				{Name: "dict", Namespace: "ns", Version: "v1", Size: 100, Tenant: "default"},
+			},
+			ItemsNum: 2,
+			Size:     1000,
+		},
+	}
+	empty := &datav1alpha1.NodeData{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nodeData, empty).Build()
+	nodeItems, err := NewNodeDataInventory(c).NodeItems(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]CachedItem{
+		"node-a": {
+			{Namespace: "ns", Name: "model", Version: "v1", Size: 900},
+			{Tenant: "default", Namespace: "ns", Name: "dict", Version: "v1", Size: 100},
+		},
+	}, nodeItems)
+}
@@ -20,6 +20,16 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+)
+
+const (
+	// HostCacheIsolationShared shares the host cache among all the pods on the node.
+	HostCacheIsolationShared = "Shared"
+	// HostCacheIsolationNamespace isolates the host cache by the namespace of the pods.
+	HostCacheIsolationNamespace = "Namespace"
+	// HostCacheIsolationDataSet isolates the host cache by the dataset of the pods.
+	HostCacheIsolationDataSet = "DataSet"
 )
 
 // ContainsAll means mapA contains all key/value in mapB, or mapB is the subset of mapA
@@ -40,3 +50,26 @@ func MD5(obj interface{}) (string, error) {
 
 	return fmt.Sprintf("%x", md5.Sum(b)), nil
 }
+
+// HostCacheSubPath returns the sub directory of the host cache the pods of the dataset are
+// entitled to by the isolation, it's empty if the host cache is shared.
+func HostCacheSubPath(isolation, namespace, dataset string) string {
+	switch isolation {
+	case HostCacheIsolationNamespace:
+		return namespace
+	case HostCacheIsolationDataSet:
+		return filepath.Join(namespace, dataset)
+	default:
+		return ""
+	}
+}
+
+// IsValidHostCacheIsolation returns whether the isolation is supported.
+func IsValidHostCacheIsolation(isolation string) bool {
+	switch isolation {
+	case HostCacheIsolationShared, HostCacheIsolationNamespace, HostCacheIsolationDataSet:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package versiondir keeps the versions of a data item in their own directories under the
+// data path prefix, and switches the local path between them atomically.
+//
+// The layout of a data item is:
+//
+//	<prefix>/.kuda/versions/<namespace>/<name>/<version>/  the files of a version
+//	<prefix>/.kuda/current/<namespace>/<name>              symlink to the current version
+//	<prefix>/<localPath>                                   symlink to the current symlink
+//
+// A version is downloaded into a staging directory, which is renamed to the version
+// directory once the whole data item succeeded, and the current symlink is then replaced by
+// a rename, so the local path never shows a partially written version.
+package versiondir
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+const (
+	// DefaultKeepPrevious is the default number of the previous versions kept locally.
+	DefaultKeepPrevious = 1
+
+	stagingPrefix = ".staging-"
+	oldPrefix     = ".old-"
+	tmpLinkPrefix = ".tmp-"
+)
+
+// Item is the versioned directories of a data item.
+type Item struct {
+	// dir is the directory of the versions.
+	dir string
+	// current is the symlink to the current version.
+	current string
+	// localPath is the local path of the data item under the prefix.
+	localPath string
+}
+
+// New returns the versioned directories of the data item under the data path prefix.
+func New(prefix string, item *datav1alpha1.DataItem) *Item {
+	return &Item{
+		dir:       filepath.Join(prefix, ".kuda", "versions", item.Namespace, item.Name),
+		current:   filepath.Join(prefix, ".kuda", "current", item.Namespace, item.Name),
+		localPath: filepath.Join(prefix, item.LocalPath),
+	}
+}
+
+// KeepPrevious returns the number of the previous versions kept by the policy.
+func KeepPrevious(policy *datav1alpha1.VersioningPolicy) int {
+	if policy == nil || policy.KeepPrevious == nil {
+		return DefaultKeepPrevious
+	}
+	return int(*policy.KeepPrevious)
+}
+
+// versionName returns the directory name of the version, which is escaped so that it is a
+// single path element not hidden.
+func versionName(version string) string {
+	name := url.PathEscape(version)
+	if strings.HasPrefix(name, ".") {
+		name = "%2E" + name[1:]
+	}
+	return name
+}
+
+// Dir returns the directory of the version.
+func (i *Item) Dir(version string) string {
+	return filepath.Join(i.dir, versionName(version))
+}
+
+// Current returns the current version, or empty if none is switched to yet.
+func (i *Item) Current() (string, error) {
+	target, err := os.Readlink(i.current)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return url.PathUnescape(filepath.Base(target))
+}
+
+// Exists returns true if the version is kept locally, so that switching to it requires no
+// download.
+func (i *Item) Exists(version string) (bool, error) {
+	info, err := os.Stat(i.Dir(version))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// Stage returns an empty staging directory for downloading the version, the one left by a
+// failed download is cleaned up.
+func (i *Item) Stage(version string) (string, error) {
+	staging := filepath.Join(i.dir, stagingPrefix+versionName(version))
+	if err := os.RemoveAll(staging); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return "", err
+	}
+	return staging, nil
+}
+
+// Commit makes the staging directory of the version the version directory, and switches
+// the local path to it. The version directory downloaded before, e.g. by a scheduled
+// re-sync, is moved aside by a rename before it's replaced.
+func (i *Item) Commit(version string) error {
+	staging := filepath.Join(i.dir, stagingPrefix+versionName(version))
+	old := filepath.Join(i.dir, oldPrefix+versionName(version))
+	if err := os.RemoveAll(old); err != nil {
+		return err
+	}
+	if err := os.Rename(i.Dir(version), old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(staging, i.Dir(version)); err != nil {
+		return err
+	}
+	if err := i.Switch(version); err != nil {
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// Switch switches the local path to the version kept locally, e.g. on rollback.
+func (i *Item) Switch(version string) error {
+	exists, err := i.Exists(version)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("version %s is not kept locally", version)
+	}
+
+	// The switched version is the latest used one when pruning.
+	now := time.Now()
+	if err := os.Chtimes(i.Dir(version), now, now); err != nil {
+		return err
+	}
+	target, err := filepath.Rel(filepath.Dir(i.current), i.Dir(version))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(i.current), 0755); err != nil {
+		return err
+	}
+	if err := replaceSymlink(target, i.current); err != nil {
+		return err
+	}
+	return i.linkLocalPath()
+}
+
+// linkLocalPath makes the local path a relative symlink to the current symlink, the local
+// path written by the kuda runtime without versioning is not replaced.
+func (i *Item) linkLocalPath() error {
+	target, err := filepath.Rel(filepath.Dir(i.localPath), i.current)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(i.localPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink == 0:
+		return fmt.Errorf("local path %s exists and is not a symlink", i.localPath)
+	default:
+		if current, err := os.Readlink(i.localPath); err == nil && current == target {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.localPath), 0755); err != nil {
+		return err
+	}
+	return replaceSymlink(target, i.localPath)
+}
+
+// Versions returns the versions kept locally, from the most recently used to the least.
+func (i *Item) Versions() ([]string, error) {
+	entries, err := os.ReadDir(i.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type version struct {
+		name    string
+		modTime time.Time
+	}
+	versions := make([]version, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		name, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version{name: name, modTime: info.ModTime()})
+	}
+	sort.SliceStable(versions, func(a, b int) bool {
+		if !versions[a].modTime.Equal(versions[b].modTime) {
+			return versions[a].modTime.After(versions[b].modTime)
+		}
+		return versions[a].name > versions[b].name
+	})
+
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		names = append(names, v.name)
+	}
+	return names, nil
+}
+
+// Prune removes the versions except the current one and the most recently used previous
+// ones up to the number kept, and returns the versions kept from the current one.
+func (i *Item) Prune(keepPrevious int) ([]string, error) {
+	current, err := i.Current()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := i.Versions()
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]string, 0, keepPrevious+1)
+	if current != "" {
+		kept = append(kept, current)
+	}
+	previous := 0
+	for _, version := range versions {
+		if version == current {
+			continue
+		}
+		if previous < keepPrevious {
+			kept = append(kept, version)
+			previous++
+			continue
+		}
+		if err := os.RemoveAll(i.Dir(version)); err != nil {
+			return kept, err
+		}
+	}
+	return kept, nil
+}
+
+// replaceSymlink atomically replaces the file at the path by a symlink to the target.
+func replaceSymlink(target, path string) error {
+	tmp := filepath.Join(filepath.Dir(path), tmpLinkPrefix+filepath.Base(path))
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package versiondir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestVersionSwitch(t *testing.T) {
+	prefix := t.TempDir()
+	item := New(prefix, &datav1alpha1.DataItem{Name: "ranker", Namespace: "models", LocalPath: "/models/ranker"})
+	localPath := filepath.Join(prefix, "models", "ranker")
+	readModel := func() string {
+		content, err := ioutil.ReadFile(filepath.Join(localPath, "model.bin"))
+		assert.NoError(t, err)
+		return string(content)
+	}
+	download := func(version string) {
+		staging, err := item.Stage(version)
+		assert.NoError(t, err)
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(staging, "model.bin"), []byte(version), 0644))
+		assert.NoError(t, item.Commit(version))
+	}
+
+	current, err := item.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "", current)
+
+	download("v1")
+	assert.Equal(t, "v1", readModel())
+
+	// The local path keeps the current version while the next one is being downloaded.
+	staging, err := item.Stage("v2")
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(staging, "model.bin"), []byte("v2"), 0644))
+	assert.Equal(t, "v1", readModel())
+	assert.NoError(t, item.Commit("v2"))
+	assert.Equal(t, "v2", readModel())
+	current, err = item.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", current)
+
+	// The symlinks are relative, so that the prefix can be mounted anywhere.
+	target, err := os.Readlink(localPath)
+	assert.NoError(t, err)
+	assert.False(t, filepath.IsAbs(target))
+
+	// Rolling back switches to the version kept locally.
+	exists, err := item.Exists("v1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, item.Switch("v1"))
+	assert.Equal(t, "v1", readModel())
+	assert.Error(t, item.Switch("v0"))
+
+	// Re-syncing the same version replaces its directory.
+	download("v1")
+	assert.Equal(t, "v1", readModel())
+
+	// Only the most recently used previous versions are kept.
+	future := time.Now().Add(time.Hour)
+	download("v3")
+	assert.NoError(t, os.Chtimes(item.Dir("v1"), future, future))
+	kept, err := item.Prune(KeepPrevious(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v3", "v1"}, kept)
+	versions, err := item.Versions()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v3", "v1"}, versions)
+
+	kept, err = item.Prune(KeepPrevious(&datav1alpha1.VersioningPolicy{KeepPrevious: pointer.Int32Ptr(0)}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v3"}, kept)
+}
+
+func TestVersionName(t *testing.T) {
+	prefix := t.TempDir()
+	item := New(prefix, &datav1alpha1.DataItem{Name: "dict", Namespace: "default", LocalPath: "/dict"})
+	for _, version := range []string{"2021/11/08", "..", ".hidden"} {
+		dir := item.Dir(version)
+		assert.Equal(t, filepath.Join(prefix, ".kuda", "versions", "default", "dict"), filepath.Dir(dir), version)
+
+		_, err := item.Stage(version)
+		assert.NoError(t, err)
+		assert.NoError(t, item.Commit(version))
+		current, err := item.Current()
+		assert.NoError(t, err)
+		assert.Equal(t, version, current)
+	}
+
+	// The local path written without versioning is not replaced.
+	item = New(prefix, &datav1alpha1.DataItem{Name: "conf", Namespace: "default", LocalPath: "/conf"})
+	assert.NoError(t, os.MkdirAll(filepath.Join(prefix, "conf"), 0755))
+	_, err := item.Stage("v1")
+	assert.NoError(t, err)
+	assert.Error(t, item.Commit("v1"))
+}
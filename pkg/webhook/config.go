@@ -17,9 +17,22 @@ limitations under the License.
 package webhook
 
 import (
+	"fmt"
 	"io/ioutil"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+const (
+	defaultHostCacheInitImage = "busybox:1.33"
+
+	// DeliveryModeSidecar delivers the data by the kuda runtime sidecar and the host path.
+	DeliveryModeSidecar = "Sidecar"
+	// DeliveryModeCSI delivers the data by the inline volume of the kuda CSI driver.
+	DeliveryModeCSI = "CSI"
 )
 
 // Config defines fields for webhook.
@@ -29,6 +42,46 @@ type Config struct {
 	DataPathPrefix    string `yaml:"dataPathPrefix"`
 	EnableAffinity    bool   `yaml:"enableAffinity"`
 	RuntimeServerPort uint   `yaml:"runtimeServerPort"`
+	// HostCache defines the isolation of the host cache among pods.
+	HostCache HostCacheConfig `yaml:"hostCache"`
+	// DeliveryMode is one of Sidecar and CSI, defaults to Sidecar. The pods get neither the
+	// sidecar nor the host path in CSI mode, which requires the kuda CSI driver installed.
+	DeliveryMode string `yaml:"deliveryMode"`
+	// DataSize defines the storage of the pods sized by the declared sizes of the data items.
+	DataSize DataSizeConfig `yaml:"dataSize"`
+}
+
+// DataSizeConfig defines fields for sizing the pods by the total size of the data items.
+// The kuda runtime requests the ephemeral storage of the data, and the emptyDir volumes
+// holding the data are limited to it.
+type DataSizeConfig struct {
+	// SetLimits sets the ephemeral storage limits of the kuda runtime besides the requests,
+	// the pods are evicted if the data exceeds the declared size.
+	SetLimits bool `yaml:"setLimits"`
+	// Max is the max total size of the data items of a DataSet, e.g. 100Gi.
+	Max *resource.Quantity `yaml:"max"`
+	// RejectOverMax rejects the DataSets exceeding the max size, which are only warned by default.
+	RejectOverMax bool `yaml:"rejectOverMax"`
+}
+
+// HostCacheConfig defines fields for the host cache mounted into the pods. The host cache
+// is mounted read-only into the app containers, and only the kuda runtime writes it.
+type HostCacheConfig struct {
+	// Isolation is one of Shared, Namespace and DataSet. The pods mount the sub directory
+	// of the host path named after their namespace or dataset unless it's Shared.
+	Isolation string `yaml:"isolation"`
+	// RunAsUser and RunAsGroup are the owner of the sub directory, which the kuda runtime
+	// runs as. The group is added to the supplemental groups of the pods to read the cache.
+	RunAsUser  *int64 `yaml:"runAsUser"`
+	RunAsGroup *int64 `yaml:"runAsGroup"`
+	// Mode is the permission bits of the sub directory, e.g. 0750.
+	Mode *int32 `yaml:"mode"`
+	// InitImage is the image of the init container enforcing the owner and mode.
+	InitImage string `yaml:"initImage"`
+	// Dedup downloads the identical data items once per node into the content store of the
+	// host cache, and hard links them into the version directories. The data items are
+	// deduplicated across the DataSets sharing the host cache, see the dedup package.
+	Dedup bool `yaml:"dedup"`
 }
 
 // LoadConfig returns config from the file.
@@ -38,10 +91,37 @@ func LoadConfig(file string) (*Config, error) {
 		return nil, err
 	}
 
+	return ParseConfig(b)
+}
+
+// ParseConfig returns config from the content of the config file.
+func ParseConfig(b []byte) (*Config, error) {
 	var cfg Config
 	if err := yaml.Unmarshal(b, &cfg); err != nil {
 		return nil, err
 	}
 
+	if cfg.HostCache.Isolation == "" {
+		cfg.HostCache.Isolation = utils.HostCacheIsolationShared
+	}
+	if !utils.IsValidHostCacheIsolation(cfg.HostCache.Isolation) {
+		return nil, fmt.Errorf("unsupported host cache isolation %s", cfg.HostCache.Isolation)
+	}
+	if cfg.HostCache.Mode != nil && (*cfg.HostCache.Mode < 0 || *cfg.HostCache.Mode > 0777) {
+		return nil, fmt.Errorf("host cache mode must be in the range of 0 to 0777")
+	}
+	if cfg.HostCache.InitImage == "" {
+		cfg.HostCache.InitImage = defaultHostCacheInitImage
+	}
+	if cfg.DeliveryMode == "" {
+		cfg.DeliveryMode = DeliveryModeSidecar
+	}
+	if cfg.DeliveryMode != DeliveryModeSidecar && cfg.DeliveryMode != DeliveryModeCSI {
+		return nil, fmt.Errorf("unsupported delivery mode %s", cfg.DeliveryMode)
+	}
+	if cfg.DataSize.Max != nil && cfg.DataSize.Max.Sign() <= 0 {
+		return nil, fmt.Errorf("max data size must be greater than 0")
+	}
+
 	return &cfg, nil
 }
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kuda-io/kuda/pkg/utils"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte("hostPath: /var/lib/kuda\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, utils.HostCacheIsolationShared, cfg.HostCache.Isolation)
+	assert.Equal(t, defaultHostCacheInitImage, cfg.HostCache.InitImage)
+	assert.Equal(t, DeliveryModeSidecar, cfg.DeliveryMode)
+
+	cfg, err = ParseConfig([]byte("hostCache:\n  isolation: DataSet\n  runAsGroup: 2000\n  mode: 0750\n  dedup: true\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, utils.HostCacheIsolationDataSet, cfg.HostCache.Isolation)
+	assert.True(t, cfg.HostCache.Dedup)
+	assert.Equal(t, int64(2000), *cfg.HostCache.RunAsGroup)
+	assert.Equal(t, int32(0750), *cfg.HostCache.Mode)
+
+	_, err = ParseConfig([]byte("hostCache:\n  isolation: Pod\n"))
+	assert.Error(t, err)
+
+	cfg, err = ParseConfig([]byte("deliveryMode: CSI\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, DeliveryModeCSI, cfg.DeliveryMode)
+
+	_, err = ParseConfig([]byte("deliveryMode: HostPath\n"))
+	assert.Error(t, err)
+
+	cfg, err = ParseConfig([]byte("dataSize:\n  setLimits: true\n  max: 100Gi\n"))
+	assert.NoError(t, err)
+	assert.True(t, cfg.DataSize.SetLimits)
+	assert.Equal(t, "100Gi", cfg.DataSize.Max.String())
+
+	_, err = ParseConfig([]byte("dataSize:\n  max: 0\n"))
+	assert.Error(t, err)
+}
@@ -21,25 +21,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/dedup"
+	"github.com/kuda-io/kuda/pkg/metrics"
 	"github.com/kuda-io/kuda/pkg/utils"
+	"github.com/kuda-io/kuda/pkg/versiondir"
 )
 
 const (
 	affinityTopologyKey = "kubernetes.io/hostname"
+	affinityWeight      = 1
 
-	sidecarContainerName = "kuda-runtime"
+	sidecarContainerName   = datav1alpha1.KudaRuntimeContainerName
+	hostCacheContainerName = "kuda-host-cache-init"
 
 	volumeNameShareData = "share-data"
 	volumeNameHostData  = "host-data"
 	volumeNamePodData   = "pod-data"
+	volumeNameCSIData   = "kuda-data"
 )
 
 var (
@@ -72,38 +83,67 @@ func (p *PodInjector) Handle(ctx context.Context, req admission.Request) admissi
 		pod.Annotations = make(map[string]string, 0)
 	}
 
+	result := metrics.InjectionResultSkipped
 	if _, ok := pod.Annotations[datav1alpha1.KudaKeyDataSet]; !ok {
 		ds, err := p.findDataSetForPod(ctx, pod)
 		if err != nil {
 			log.Error(err, "failed to find dataset for pod", "pod.Name", pod.Name)
+			metrics.InjectionTotal.WithLabelValues(metrics.InjectionResultError).Inc()
 			return admission.Errored(http.StatusInternalServerError, err)
 		}
 
 		if ds != nil {
-			p.mutatePod(pod, ds)
+			p.MutatePod(ctx, pod, ds)
+			result = metrics.InjectionResultInjected
 		}
 	}
 
 	marshaledPod, err := json.Marshal(pod)
 	if err != nil {
 		log.Error(err, "marshal pod error", "pod.Name", pod.Name)
+		metrics.InjectionTotal.WithLabelValues(metrics.InjectionResultError).Inc()
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
+	metrics.InjectionTotal.WithLabelValues(result).Inc()
 
 	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
 }
 
-// mutatePod add config for the pod.
-func (p *PodInjector) mutatePod(pod *corev1.Pod, dataset *datav1alpha1.DataSet) {
-	p.patchSidecar(pod)
+// MutatePod add config for the pod.
+func (p *PodInjector) MutatePod(ctx context.Context, pod *corev1.Pod, dataset *datav1alpha1.DataSet) {
+	hostCacheDisabled := p.isHostCacheDisabled(ctx, dataset.Namespace)
 
-	p.patchVolumes(pod)
+	// The CSI driver serves the data from the host cache, so the pods fall back to the
+	// sidecar if the host cache is disabled for the namespace.
+	if p.config.DeliveryMode == DeliveryModeCSI && !hostCacheDisabled {
+		p.patchCSIVolume(pod, dataset)
+	} else {
+		p.patchSidecar(pod)
 
-	p.patchAffinity(pod, dataset.Spec.WorkloadSelector)
+		p.patchVolumes(pod, dataset, hostCacheDisabled)
+
+		p.patchDataSize(pod, dataset)
+	}
+
+	p.patchAffinity(ctx, pod, dataset)
 
 	p.patchAnnotations(pod, dataset.Name)
 }
 
+// MutatePodTemplate add config for the pod template of workloads, it's used to inject
+// manifests offline. The template already injected is skipped, and false is returned.
+func (p *PodInjector) MutatePodTemplate(ctx context.Context, template *corev1.PodTemplateSpec, dataset *datav1alpha1.DataSet) bool {
+	if _, ok := template.Annotations[datav1alpha1.KudaKeyDataSet]; ok {
+		return false
+	}
+
+	pod := &corev1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec}
+	p.MutatePod(ctx, pod, dataset)
+	template.ObjectMeta, template.Spec = pod.ObjectMeta, pod.Spec
+
+	return true
+}
+
 // patch kuda runtime container as sidecar for the app.
 func (p *PodInjector) patchSidecar(pod *corev1.Pod) {
 	sidecar := &corev1.Container{
@@ -145,6 +185,14 @@ func (p *PodInjector) patchSidecar(pod *corev1.Pod) {
 					},
 				},
 			},
+			{
+				Name: datav1alpha1.KudaRuntimeEnvPodIP,
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "status.podIP",
+					},
+				},
+			},
 			{
 				Name:  datav1alpha1.KudaRuntimeEnvMainContainerName,
 				Value: pod.Spec.Containers[0].Name,
@@ -152,21 +200,36 @@ func (p *PodInjector) patchSidecar(pod *corev1.Pod) {
 		},
 	}
 
+	// The content store is in the host cache mounted, so that the content can be hard linked
+	// into the version directories.
+	if p.config.HostCache.Dedup {
+		sidecar.Args = append(sidecar.Args, fmt.Sprintf("--content-dir=%s", filepath.Join(p.config.HostPath, dedup.DirName)))
+	}
+
 	pod.Spec.Containers = append(pod.Spec.Containers, *sidecar)
 }
 
-// patch volumes for the pod.
-func (p *PodInjector) patchVolumes(pod *corev1.Pod) {
+// patch volumes for the pod. The pod mounts the sub directory of the host cache it's
+// entitled to, which is read-only for the app containers.
+func (p *PodInjector) patchVolumes(pod *corev1.Pod, dataset *datav1alpha1.DataSet, hostCacheDisabled bool) {
 	dirOrCreate := corev1.HostPathDirectoryOrCreate
 
+	// The data is downloaded into the pod itself if the host cache is disabled.
+	hostData := corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	if !hostCacheDisabled {
+		path := filepath.Join(p.config.HostPath, utils.HostCacheSubPath(p.config.HostCache.Isolation, dataset.Namespace, dataset.Name))
+		hostData = corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: path, Type: &dirOrCreate}}
+		p.patchHostCacheOwner(pod)
+	}
+
 	volumes := []corev1.Volume{
 		{
 			Name:         volumeNameShareData,
-			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			VolumeSource: newShareDataVolumeSource(dataset.Spec.Volume),
 		},
 		{
 			Name:         volumeNameHostData,
-			VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: p.config.HostPath, Type: &dirOrCreate}},
+			VolumeSource: hostData,
 		},
 		{
 			Name: volumeNamePodData,
@@ -186,36 +249,352 @@ func (p *PodInjector) patchVolumes(pod *corev1.Pod) {
 			{
 				Name:      volumeNameHostData,
 				MountPath: p.config.HostPath,
+				ReadOnly:  pod.Spec.Containers[i].Name != sidecarContainerName,
 			},
 		}...)
 	}
 }
 
-// patch affinity for the pod.
-func (p *PodInjector) patchAffinity(pod *corev1.Pod, workloadSelector map[string]string) {
-	if !p.config.EnableAffinity {
+// patch the ephemeral storage for the pod by the declared sizes of the data items. The
+// emptyDir volumes holding the data are limited to the size, and the kuda runtime requests
+// the ephemeral storage of the ones on the disk of the node.
+func (p *PodInjector) patchDataSize(pod *corev1.Pod, dataset *datav1alpha1.DataSet) {
+	size := getDataSize(dataset.Spec.Template.DataItems)
+	if size == nil {
 		return
 	}
 
-	wpat := corev1.WeightedPodAffinityTerm{
-		Weight: 1,
-		PodAffinityTerm: corev1.PodAffinityTerm{
-			LabelSelector: &v1.LabelSelector{
-				MatchLabels: workloadSelector,
-			},
-			TopologyKey: affinityTopologyKey,
-		},
+	storage := resource.Quantity{Format: resource.BinarySI}
+	for i := range pod.Spec.Volumes {
+		volume := &pod.Spec.Volumes[i]
+		if (volume.Name != volumeNameShareData && volume.Name != volumeNameHostData) || volume.EmptyDir == nil {
+			continue
+		}
+		if volume.EmptyDir.SizeLimit == nil {
+			limit := size.DeepCopy()
+			volume.EmptyDir.SizeLimit = &limit
+		}
+		if volume.EmptyDir.Medium != corev1.StorageMediumMemory {
+			storage.Add(*volume.EmptyDir.SizeLimit)
+		}
+	}
+	if storage.IsZero() {
+		return
+	}
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != sidecarContainerName {
+			continue
+		}
+		if c.Resources.Requests == nil {
+			c.Resources.Requests = corev1.ResourceList{}
+		}
+		c.Resources.Requests[corev1.ResourceEphemeralStorage] = storage
+		if p.config.DataSize.SetLimits {
+			if c.Resources.Limits == nil {
+				c.Resources.Limits = corev1.ResourceList{}
+			}
+			c.Resources.Limits[corev1.ResourceEphemeralStorage] = storage
+		}
+	}
+}
+
+// getDataSize returns the total size of the data items, or nil if none of them declares
+// its size. The versioned data items take the space of the versions kept locally and the
+// one being downloaded.
+func getDataSize(items []datav1alpha1.DataItem) *resource.Quantity {
+	var size *resource.Quantity
+	for _, item := range items {
+		if item.Size == nil {
+			continue
+		}
+		if size == nil {
+			size = &resource.Quantity{Format: resource.BinarySI}
+		}
+		copies := 1
+		if item.Versioning != nil {
+			copies = versiondir.KeepPrevious(item.Versioning) + 2
+		}
+		for i := 0; i < copies; i++ {
+			size.Add(*item.Size)
+		}
+	}
+	return size
+}
+
+// newShareDataVolumeSource returns the source of the volume the data is delivered into by
+// the volume spec of the dataset, which defaults to an emptyDir.
+func newShareDataVolumeSource(volume *datav1alpha1.DataVolumeSource) corev1.VolumeSource {
+	switch {
+	case volume == nil:
+	case volume.EmptyDir != nil:
+		return corev1.VolumeSource{EmptyDir: volume.EmptyDir.DeepCopy()}
+	case volume.Ephemeral != nil:
+		return corev1.VolumeSource{Ephemeral: volume.Ephemeral.DeepCopy()}
+	case volume.PersistentVolumeClaim != nil:
+		return corev1.VolumeSource{PersistentVolumeClaim: volume.PersistentVolumeClaim.DeepCopy()}
+	}
+	return corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+}
+
+// patch the inline volume of the kuda CSI driver for the pod, which is mounted read-only at
+// the data path in all the containers. Kubelet waits for the data to be prefetched onto the
+// node before starting the containers.
+func (p *PodInjector) patchCSIVolume(pod *corev1.Pod, dataset *datav1alpha1.DataSet) {
+	readOnly := true
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: volumeNameCSIData,
+		VolumeSource: corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{
+			Driver:           datav1alpha1.KudaCSIDriverName,
+			ReadOnly:         &readOnly,
+			VolumeAttributes: map[string]string{datav1alpha1.KudaCSIVolumeDataSet: dataset.Name},
+		}},
+	})
+
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      volumeNameCSIData,
+			MountPath: p.config.DataPathPrefix,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// patch the owner and mode of the host cache. An init container enforces them on the sub
+// directory, the kuda runtime runs as the owner, and the pod joins the group to read it.
+func (p *PodInjector) patchHostCacheOwner(pod *corev1.Pod) {
+	cfg := p.config.HostCache
+	if cfg.RunAsUser == nil && cfg.RunAsGroup == nil && cfg.Mode == nil {
+		return
+	}
+
+	commands := make([]string, 0, 2)
+	if cfg.RunAsUser != nil || cfg.RunAsGroup != nil {
+		owner := ""
+		if cfg.RunAsUser != nil {
+			owner = fmt.Sprintf("%d", *cfg.RunAsUser)
+		}
+		if cfg.RunAsGroup != nil {
+			owner = fmt.Sprintf("%s:%d", owner, *cfg.RunAsGroup)
+		}
+		commands = append(commands, fmt.Sprintf("chown %s %s", owner, p.config.HostPath))
+	}
+	if cfg.Mode != nil {
+		commands = append(commands, fmt.Sprintf("chmod %o %s", *cfg.Mode, p.config.HostPath))
+	}
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+		Name:         hostCacheContainerName,
+		Image:        cfg.InitImage,
+		Command:      []string{"sh", "-c", strings.Join(commands, " && ")},
+		VolumeMounts: []corev1.VolumeMount{{Name: volumeNameHostData, MountPath: p.config.HostPath}},
+	})
+
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name != sidecarContainerName {
+			continue
+		}
+		if pod.Spec.Containers[i].SecurityContext == nil {
+			pod.Spec.Containers[i].SecurityContext = &corev1.SecurityContext{}
+		}
+		pod.Spec.Containers[i].SecurityContext.RunAsUser = cfg.RunAsUser
+		pod.Spec.Containers[i].SecurityContext.RunAsGroup = cfg.RunAsGroup
+	}
+
+	if cfg.RunAsGroup != nil {
+		if pod.Spec.SecurityContext == nil {
+			pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		pod.Spec.SecurityContext.SupplementalGroups = append(pod.Spec.SecurityContext.SupplementalGroups, *cfg.RunAsGroup)
+	}
+}
+
+// isHostCacheDisabled returns whether the host cache is disabled for the namespace by its
+// label. It's regarded as disabled if the namespace is unavailable, so that the data of
+// sensitive namespaces never goes into the host cache.
+func (p *PodInjector) isHostCacheDisabled(ctx context.Context, namespace string) bool {
+	if p.client == nil || namespace == "" {
+		return false
+	}
+
+	ns := &corev1.Namespace{}
+	if err := p.client.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		log.Error(err, "failed to get namespace, host cache is disabled", "namespace", namespace)
+		return true
+	}
+	return ns.Labels[datav1alpha1.KudaKeyHostCache] == datav1alpha1.HostCacheDisabled
+}
+
+// patch affinity for the pod. The affinity policy of the dataset takes precedence over
+// the EnableAffinity of the config, which enables the preferred affinity to the peer pods.
+func (p *PodInjector) patchAffinity(ctx context.Context, pod *corev1.Pod, dataset *datav1alpha1.DataSet) {
+	policy := getAffinityPolicy(dataset.Spec.Affinity)
+	if (dataset.Spec.Affinity == nil && !p.config.EnableAffinity) || policy.Mode == datav1alpha1.AffinityModeNone {
+		return
 	}
 
 	if pod.Spec.Affinity == nil {
 		pod.Spec.Affinity = &corev1.Affinity{}
 	}
 
+	if policy.Target == datav1alpha1.AffinityTargetNodes {
+		p.patchNodeAffinity(ctx, pod, dataset, policy)
+		return
+	}
+
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &v1.LabelSelector{
+			MatchLabels: dataset.Spec.WorkloadSelector,
+		},
+		TopologyKey: policy.TopologyKey,
+	}
+
+	if policy.Mode == datav1alpha1.AffinityModeAntiAffinity {
+		if pod.Spec.Affinity.PodAntiAffinity == nil {
+			pod.Spec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+		}
+		antiAffinity := pod.Spec.Affinity.PodAntiAffinity
+		if policy.Required {
+			antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+		} else {
+			antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+				corev1.WeightedPodAffinityTerm{Weight: policy.Weight, PodAffinityTerm: term})
+		}
+		return
+	}
+
 	if pod.Spec.Affinity.PodAffinity == nil {
 		pod.Spec.Affinity.PodAffinity = &corev1.PodAffinity{}
 	}
+	affinity := pod.Spec.Affinity.PodAffinity
+	if policy.Required {
+		affinity.RequiredDuringSchedulingIgnoredDuringExecution = append(affinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+	} else {
+		affinity.PreferredDuringSchedulingIgnoredDuringExecution = append(affinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			corev1.WeightedPodAffinityTerm{Weight: policy.Weight, PodAffinityTerm: term})
+	}
+}
 
-	pod.Spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(pod.Spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution, wpat)
+// patch node affinity to the topology domains of the nodes caching all the data items of
+// the dataset. Nothing is patched if no node caches them or the inventory is unavailable,
+// so that pods are never made unschedulable by the cache.
+func (p *PodInjector) patchNodeAffinity(ctx context.Context, pod *corev1.Pod, dataset *datav1alpha1.DataSet, policy *datav1alpha1.AffinityPolicy) {
+	domains, err := p.getCacheDomains(ctx, dataset, policy.TopologyKey)
+	if err != nil {
+		log.Error(err, "failed to get nodes caching the data", "dataset", dataset.Name)
+		return
+	}
+	if len(domains) == 0 {
+		return
+	}
+
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      policy.TopologyKey,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   domains,
+	}
+
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	affinity := pod.Spec.Affinity.NodeAffinity
+	if !policy.Required {
+		affinity.PreferredDuringSchedulingIgnoredDuringExecution = append(affinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			corev1.PreferredSchedulingTerm{
+				Weight:     policy.Weight,
+				Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{requirement}},
+			})
+		return
+	}
+
+	// The node selector terms are ORed, so the requirement is added to each of the existing
+	// terms to be satisfied together with them.
+	if affinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		affinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	selector := affinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range selector.NodeSelectorTerms {
+		selector.NodeSelectorTerms[i].MatchExpressions = append(selector.NodeSelectorTerms[i].MatchExpressions, requirement)
+	}
+}
+
+// get the sorted values of the topology key of the nodes caching all the data items of the
+// dataset from the NodeData resources. Only the data items in the host cache of the tenant
+// of the dataset are counted, the pods can't read the ones of the other tenants.
+func (p *PodInjector) getCacheDomains(ctx context.Context, dataset *datav1alpha1.DataSet, topologyKey string) ([]string, error) {
+	if p.client == nil || len(dataset.Spec.Template.DataItems) == 0 {
+		return nil, nil
+	}
+
+	nodeDataList := &datav1alpha1.NodeDataList{}
+	if err := p.client.List(ctx, nodeDataList); err != nil {
+		return nil, err
+	}
+
+	required := make(map[string]bool, len(dataset.Spec.Template.DataItems))
+	for _, item := range dataset.Spec.Template.DataItems {
+		required[fmt.Sprintf("%s/%s@%s", item.Namespace, item.Name, item.Version)] = true
+	}
+
+	tenant := utils.HostCacheSubPath(p.config.HostCache.Isolation, dataset.Namespace, dataset.Name)
+	domains := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, nodeData := range nodeDataList.Items {
+		cached := make(map[string]bool, len(required))
+		for _, item := range nodeData.Status.Items {
+			if item.Tenant != tenant {
+				continue
+			}
+			key := fmt.Sprintf("%s/%s@%s", item.Namespace, item.Name, item.Version)
+			if required[key] {
+				cached[key] = true
+			}
+		}
+		if len(cached) < len(required) {
+			continue
+		}
+
+		node := &corev1.Node{}
+		if err := p.client.Get(ctx, types.NamespacedName{Name: nodeData.Name}, node); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return nil, err
+			}
+			continue
+		}
+		domain, ok := node.Labels[topologyKey]
+		if !ok || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	return domains, nil
+}
+
+// getAffinityPolicy returns the affinity policy with defaults.
+func getAffinityPolicy(policy *datav1alpha1.AffinityPolicy) *datav1alpha1.AffinityPolicy {
+	result := &datav1alpha1.AffinityPolicy{}
+	if policy != nil {
+		result = policy.DeepCopy()
+	}
+	if result.Mode == "" {
+		result.Mode = datav1alpha1.AffinityModeAffinity
+	}
+	if result.Target == "" {
+		result.Target = datav1alpha1.AffinityTargetPods
+	}
+	if result.TopologyKey == "" {
+		result.TopologyKey = affinityTopologyKey
+	}
+	if result.Weight == 0 {
+		result.Weight = affinityWeight
+	}
+	return result
 }
 
 // patch annotations for the pod.
@@ -17,19 +17,25 @@ limitations under the License.
 package webhook
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/utils"
 )
 
-func TestPodInjector_mutatePod(t *testing.T) {
+func TestPodInjector_MutatePod(t *testing.T) {
 	type fields struct {
 		config  *Config
 		client  client.Client
@@ -105,6 +111,7 @@ func TestPodInjector_mutatePod(t *testing.T) {
 								{
 									Name:      volumeNameHostData,
 									MountPath: hostPath,
+									ReadOnly:  true,
 								},
 							},
 						},
@@ -155,6 +162,14 @@ func TestPodInjector_mutatePod(t *testing.T) {
 										},
 									},
 								},
+								{
+									Name: datav1alpha1.KudaRuntimeEnvPodIP,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "status.podIP",
+										},
+									},
+								},
 								{
 									Name:  datav1alpha1.KudaRuntimeEnvMainContainerName,
 									Value: "test",
@@ -204,8 +219,417 @@ func TestPodInjector_mutatePod(t *testing.T) {
 				client:  tt.fields.client,
 				decoder: tt.fields.decoder,
 			}
-			p.mutatePod(tt.args.pod, tt.args.dataset)
+			p.MutatePod(context.Background(), tt.args.pod, tt.args.dataset)
 			assert.Equal(t, tt.want, tt.args.pod)
 		})
 	}
 }
+
+func TestPodInjector_PatchAffinity(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = datav1alpha1.AddToScheme(scheme)
+
+	workloadSelector := map[string]string{"app": "test"}
+	newNode := func(name, zone string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{affinityTopologyKey: name, "topology.kubernetes.io/zone": zone},
+		}}
+	}
+	newNodeData := func(name, tenant string, versions ...string) *datav1alpha1.NodeData {
+		nodeData := &datav1alpha1.NodeData{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for _, version := range versions {
+			nodeData.Status.Items = append(nodeData.Status.Items, datav1alpha1.CachedDataItem{Name: "conf", Namespace: "kuda-io", Version: version, Tenant: tenant})
+		}
+		return nodeData
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newNode("node-a", "zone-a"), newNode("node-b", "zone-b"), newNode("node-c", "zone-b"), newNode("node-d", "zone-a"),
+		newNodeData("node-a", "", "v1"), newNodeData("node-b", "", "v0"), newNodeData("node-c", "", "v0", "v1"),
+		newNodeData("node-d", "default", "v1"),
+	).Build()
+
+	podTerm := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: workloadSelector},
+		TopologyKey:   affinityTopologyKey,
+	}
+	zoneTerm := *podTerm.DeepCopy()
+	zoneTerm.TopologyKey = "topology.kubernetes.io/zone"
+
+	tests := []struct {
+		name           string
+		enableAffinity bool
+		isolation      string
+		policy         *datav1alpha1.AffinityPolicy
+		affinity       *corev1.Affinity
+		want           *corev1.Affinity
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:           "enabled by config",
+			enableAffinity: true,
+			want: &corev1.Affinity{PodAffinity: &corev1.PodAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{Weight: 1, PodAffinityTerm: podTerm}},
+			}},
+		},
+		{
+			name:           "disabled by policy",
+			enableAffinity: true,
+			policy:         &datav1alpha1.AffinityPolicy{Mode: datav1alpha1.AffinityModeNone},
+		},
+		{
+			name:   "required pod affinity",
+			policy: &datav1alpha1.AffinityPolicy{Required: true},
+			want: &corev1.Affinity{PodAffinity: &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{podTerm},
+			}},
+		},
+		{
+			name: "preferred pod anti-affinity by zone",
+			policy: &datav1alpha1.AffinityPolicy{
+				Mode:        datav1alpha1.AffinityModeAntiAffinity,
+				TopologyKey: "topology.kubernetes.io/zone",
+				Weight:      50,
+			},
+			want: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{Weight: 50, PodAffinityTerm: zoneTerm}},
+			}},
+		},
+		{
+			name:   "preferred node affinity",
+			policy: &datav1alpha1.AffinityPolicy{Target: datav1alpha1.AffinityTargetNodes, Weight: 10},
+			want: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+					Weight: 10,
+					Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: affinityTopologyKey, Operator: corev1.NodeSelectorOpIn, Values: []string{"node-a", "node-c"}},
+					}},
+				}},
+			}},
+		},
+		{
+			name:      "preferred node affinity by the cache of the tenant",
+			isolation: utils.HostCacheIsolationNamespace,
+			policy:    &datav1alpha1.AffinityPolicy{Target: datav1alpha1.AffinityTargetNodes, Weight: 10},
+			want: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+					Weight: 10,
+					Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: affinityTopologyKey, Operator: corev1.NodeSelectorOpIn, Values: []string{"node-d"}},
+					}},
+				}},
+			}},
+		},
+		{
+			name: "required node affinity by zone",
+			policy: &datav1alpha1.AffinityPolicy{
+				Target:      datav1alpha1.AffinityTargetNodes,
+				TopologyKey: "topology.kubernetes.io/zone",
+				Required:    true,
+			},
+			affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "gpu", Operator: corev1.NodeSelectorOpExists}}},
+				}},
+			}},
+			want: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "gpu", Operator: corev1.NodeSelectorOpExists},
+						{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"zone-a", "zone-b"}},
+					}},
+				}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPodInjector(&Config{EnableAffinity: tt.enableAffinity, HostCache: HostCacheConfig{Isolation: tt.isolation}}, cli)
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Affinity: tt.affinity}}
+			dataset := &datav1alpha1.DataSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: "default"},
+				Spec: datav1alpha1.DataSetSpec{
+					Template: datav1alpha1.DataTemplateSpec{
+						DataItems: []datav1alpha1.DataItem{{Name: "conf", Namespace: "kuda-io", Version: "v1"}},
+					},
+					WorkloadSelector: workloadSelector,
+					Affinity:         tt.policy,
+				},
+			}
+			p.patchAffinity(context.Background(), pod, dataset)
+			assert.Equal(t, tt.want, pod.Spec.Affinity)
+		})
+	}
+}
+
+func TestPodInjector_PatchVolumes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = datav1alpha1.AddToScheme(scheme)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "tenant-b",
+			Labels: map[string]string{datav1alpha1.KudaKeyHostCache: datav1alpha1.HostCacheDisabled},
+		}},
+	).Build()
+	uid, gid, mode := int64(1000), int64(2000), int32(0750)
+
+	tests := []struct {
+		name       string
+		namespace  string
+		hostCache  HostCacheConfig
+		hostPath   string
+		initCmd    string
+		supplement []int64
+	}{
+		{
+			name:      "shared",
+			namespace: "tenant-a",
+			hostCache: HostCacheConfig{Isolation: utils.HostCacheIsolationShared},
+			hostPath:  "/var/lib/kuda",
+		},
+		{
+			name:      "shared with dedup",
+			namespace: "tenant-a",
+			hostCache: HostCacheConfig{Isolation: utils.HostCacheIsolationShared, Dedup: true},
+			hostPath:  "/var/lib/kuda",
+		},
+		{
+			name:       "isolated by dataset with owner",
+			namespace:  "tenant-a",
+			hostCache:  HostCacheConfig{Isolation: utils.HostCacheIsolationDataSet, RunAsUser: &uid, RunAsGroup: &gid, Mode: &mode, InitImage: "busybox"},
+			hostPath:   "/var/lib/kuda/tenant-a/test-ds",
+			initCmd:    "chown 1000:2000 /var/lib/kuda && chmod 750 /var/lib/kuda",
+			supplement: []int64{gid},
+		},
+		{
+			name:      "isolated by namespace with mode",
+			namespace: "tenant-a",
+			hostCache: HostCacheConfig{Isolation: utils.HostCacheIsolationNamespace, Mode: &mode, InitImage: "busybox"},
+			hostPath:  "/var/lib/kuda/tenant-a",
+			initCmd:   "chmod 750 /var/lib/kuda",
+		},
+		{
+			name:      "disabled",
+			namespace: "tenant-b",
+			hostCache: HostCacheConfig{Isolation: utils.HostCacheIsolationNamespace, Mode: &mode, InitImage: "busybox"},
+		},
+		{
+			name:      "namespace not found",
+			namespace: "tenant-c",
+			hostCache: HostCacheConfig{Isolation: utils.HostCacheIsolationNamespace},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPodInjector(&Config{HostPath: "/var/lib/kuda", DataPathPrefix: "/kuda/data", HostCache: tt.hostCache}, cli)
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+			p.patchSidecar(pod)
+			dataset := &datav1alpha1.DataSet{ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: tt.namespace}}
+			p.patchVolumes(pod, dataset, p.isHostCacheDisabled(context.Background(), tt.namespace))
+
+			hostData := pod.Spec.Volumes[1]
+			assert.Equal(t, volumeNameHostData, hostData.Name)
+			if tt.hostPath == "" {
+				assert.Nil(t, hostData.HostPath)
+				assert.NotNil(t, hostData.EmptyDir)
+			} else {
+				assert.Equal(t, tt.hostPath, hostData.HostPath.Path)
+			}
+			assert.True(t, pod.Spec.Containers[0].VolumeMounts[1].ReadOnly)
+			assert.False(t, pod.Spec.Containers[1].VolumeMounts[2].ReadOnly)
+			if tt.hostCache.Dedup {
+				assert.Contains(t, pod.Spec.Containers[1].Args, "--content-dir=/var/lib/kuda/.kuda-content")
+			} else {
+				assert.Len(t, pod.Spec.Containers[1].Args, 3)
+			}
+
+			if tt.initCmd == "" {
+				assert.Empty(t, pod.Spec.InitContainers)
+				assert.Nil(t, pod.Spec.Containers[1].SecurityContext)
+			} else {
+				assert.Equal(t, []string{"sh", "-c", tt.initCmd}, pod.Spec.InitContainers[0].Command)
+				assert.Equal(t, tt.hostCache.RunAsUser, pod.Spec.Containers[1].SecurityContext.RunAsUser)
+			}
+			if tt.supplement == nil {
+				assert.Nil(t, pod.Spec.SecurityContext)
+			} else {
+				assert.Equal(t, tt.supplement, pod.Spec.SecurityContext.SupplementalGroups)
+			}
+		})
+	}
+}
+
+func TestPodInjector_MutatePodByCSI(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = datav1alpha1.AddToScheme(scheme)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "tenant-b",
+			Labels: map[string]string{datav1alpha1.KudaKeyHostCache: datav1alpha1.HostCacheDisabled},
+		}},
+	).Build()
+	p := NewPodInjector(&Config{HostPath: "/var/lib/kuda", DataPathPrefix: "/kuda/data", DeliveryMode: DeliveryModeCSI}, cli)
+
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "proxy"}}}}
+	dataset := &datav1alpha1.DataSet{ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: "tenant-a"}}
+	p.MutatePod(context.Background(), pod, dataset)
+
+	readOnly := true
+	assert.Equal(t, []corev1.Volume{{
+		Name: volumeNameCSIData,
+		VolumeSource: corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{
+			Driver:           datav1alpha1.KudaCSIDriverName,
+			ReadOnly:         &readOnly,
+			VolumeAttributes: map[string]string{datav1alpha1.KudaCSIVolumeDataSet: "test-ds"},
+		}},
+	}}, pod.Spec.Volumes)
+	assert.Len(t, pod.Spec.Containers, 2)
+	for _, c := range pod.Spec.Containers {
+		assert.Equal(t, []corev1.VolumeMount{{Name: volumeNameCSIData, MountPath: "/kuda/data", ReadOnly: true}}, c.VolumeMounts)
+	}
+	assert.Equal(t, "test-ds", pod.Annotations[datav1alpha1.KudaKeyDataSet])
+
+	// The sidecar is injected if the host cache is disabled for the namespace.
+	pod = &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	dataset.Namespace = "tenant-b"
+	p.MutatePod(context.Background(), pod, dataset)
+	assert.Len(t, pod.Spec.Containers, 2)
+	assert.Equal(t, sidecarContainerName, pod.Spec.Containers[1].Name)
+}
+
+func TestNewShareDataVolumeSource(t *testing.T) {
+	sizeLimit := resource.MustParse("10Gi")
+	storageClass := "local-nvme"
+	tests := []struct {
+		name   string
+		volume *datav1alpha1.DataVolumeSource
+		want   corev1.VolumeSource
+	}{
+		{
+			name: "default",
+			want: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+		{
+			name:   "memory",
+			volume: &datav1alpha1.DataVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &sizeLimit}},
+			want:   corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &sizeLimit}},
+		},
+		{
+			name: "ephemeral",
+			volume: &datav1alpha1.DataVolumeSource{Ephemeral: &corev1.EphemeralVolumeSource{
+				VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &storageClass}},
+			}},
+			want: corev1.VolumeSource{Ephemeral: &corev1.EphemeralVolumeSource{
+				VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &storageClass}},
+			}},
+		},
+		{
+			name:   "existing claim",
+			volume: &datav1alpha1.DataVolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "models"}},
+			want:   corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "models"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, newShareDataVolumeSource(tt.volume))
+		})
+	}
+}
+
+func TestPodInjector_PatchDataSize(t *testing.T) {
+	newDataSet := func(sizes ...string) *datav1alpha1.DataSet {
+		ds := &datav1alpha1.DataSet{ObjectMeta: metav1.ObjectMeta{Name: "test-ds", Namespace: "default"}}
+		for i, size := range sizes {
+			item := datav1alpha1.DataItem{Name: fmt.Sprintf("item-%d", i)}
+			if size != "" {
+				quantity := resource.MustParse(size)
+				item.Size = &quantity
+			}
+			ds.Spec.Template.DataItems = append(ds.Spec.Template.DataItems, item)
+		}
+		return ds
+	}
+	memory := &datav1alpha1.DataVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}}
+
+	tests := []struct {
+		name      string
+		dataset   *datav1alpha1.DataSet
+		setLimits bool
+		sizeLimit string
+		request   string
+	}{
+		{
+			name:    "no size declared",
+			dataset: newDataSet("", ""),
+		},
+		{
+			name:      "partial sizes declared",
+			dataset:   newDataSet("1Gi", "", "512Mi"),
+			sizeLimit: "1536Mi",
+			request:   "1536Mi",
+		},
+		{
+			name:      "limits",
+			dataset:   newDataSet("2Gi"),
+			setLimits: true,
+			sizeLimit: "2Gi",
+			request:   "2Gi",
+		},
+		{
+			name: "versioned data items",
+			dataset: func() *datav1alpha1.DataSet {
+				ds := newDataSet("1Gi", "512Mi")
+				ds.Spec.Template.DataItems[0].Versioning = &datav1alpha1.VersioningPolicy{}
+				return ds
+			}(),
+			sizeLimit: "3584Mi",
+			request:   "3584Mi",
+		},
+		{
+			name: "memory volume",
+			dataset: func() *datav1alpha1.DataSet {
+				ds := newDataSet("2Gi")
+				ds.Spec.Volume = memory
+				return ds
+			}(),
+			sizeLimit: "2Gi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPodInjector(&Config{HostPath: "/var/lib/kuda", DataPathPrefix: "/kuda/data", DataSize: DataSizeConfig{SetLimits: tt.setLimits}}, nil)
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+			p.MutatePod(context.Background(), pod, tt.dataset)
+
+			shareData := pod.Spec.Volumes[0]
+			assert.Equal(t, volumeNameShareData, shareData.Name)
+			if tt.sizeLimit == "" {
+				assert.Nil(t, shareData.EmptyDir.SizeLimit)
+			} else {
+				assert.Equal(t, tt.sizeLimit, shareData.EmptyDir.SizeLimit.String())
+			}
+
+			sidecar := pod.Spec.Containers[1]
+			if tt.request == "" {
+				assert.Nil(t, sidecar.Resources.Requests)
+			} else {
+				request := sidecar.Resources.Requests[corev1.ResourceEphemeralStorage]
+				assert.Equal(t, tt.request, request.String())
+			}
+			if tt.setLimits {
+				assert.Equal(t, sidecar.Resources.Requests, sidecar.Resources.Limits)
+			} else {
+				assert.Nil(t, sidecar.Resources.Limits)
+			}
+		})
+	}
+}
@@ -0,0 +1,673 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+	"github.com/kuda-io/kuda/pkg/delta"
+	"github.com/kuda-io/kuda/pkg/dirsync"
+	"github.com/kuda-io/kuda/pkg/p2p"
+	"github.com/kuda-io/kuda/pkg/quota"
+)
+
+const (
+	dataSourceTypeHdfs    = "hdfs"
+	dataSourceTypeAlluxio = "alluxio"
+
+	// minDiscoveryInterval is the minimum interval of polling the remote store for versions.
+	minDiscoveryInterval = 10 * time.Second
+)
+
+// checksumRegexp is the format of the checksums of the data items, which is the same as
+// the CRD validation.
+var checksumRegexp = regexp.MustCompile(`^[a-z0-9]+:[0-9a-f]+$`)
+
+// DataSetValidator validates the dataset on creation and update.
+type DataSetValidator struct {
+	config  *Config
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// NewDataSetValidator returns DataSetValidator object by the config and client.
+func NewDataSetValidator(config *Config, client client.Client) *DataSetValidator {
+	return &DataSetValidator{
+		config: config,
+		client: client,
+	}
+}
+
+// Handle handles an dataset creation or update request, and denies it if the dataset is invalid.
+func (v *DataSetValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ds := &datav1alpha1.DataSet{}
+	if err := v.decoder.Decode(req, ds); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var old *datav1alpha1.DataSet
+	if req.Operation == admissionv1.Update {
+		old = &datav1alpha1.DataSet{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	if errs := ValidateDataSet(ds, old); len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+
+	msg, err := v.checkQuota(ctx, ds, old)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if msg != "" {
+		return admission.Denied(msg)
+	}
+
+	if msg := v.checkDataSize(ds); msg != "" {
+		if v.config.DataSize.RejectOverMax {
+			return admission.Denied(msg)
+		}
+		return admission.Allowed("").WithWarnings(msg)
+	}
+
+	return admission.Allowed("")
+}
+
+// checkDataSize returns the message if the total size of the data items exceeds the max.
+func (v *DataSetValidator) checkDataSize(ds *datav1alpha1.DataSet) string {
+	if v.config == nil || v.config.DataSize.Max == nil {
+		return ""
+	}
+	size := getDataSize(ds.Spec.Template.DataItems)
+	if size == nil || size.Cmp(*v.config.DataSize.Max) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("the total size %s of the data items exceeds the max size %s", size.String(), v.config.DataSize.Max.String())
+}
+
+// checkQuota returns the message if the dataset makes the namespace exceed any of its data
+// quotas. The updates not increasing the usage of the dataset are always allowed, so that
+// the datasets can be shrunk after the quotas are lowered.
+func (v *DataSetValidator) checkQuota(ctx context.Context, ds, old *datav1alpha1.DataSet) (string, error) {
+	usage := quota.DataSetUsage(ds)
+	if old != nil {
+		oldUsage := quota.DataSetUsage(old)
+		if usage.Size.Cmp(oldUsage.Size) <= 0 && usage.Items <= oldUsage.Items {
+			return "", nil
+		}
+	}
+
+	quotaList := &datav1alpha1.DataQuotaList{}
+	if err := v.client.List(ctx, quotaList, client.InNamespace(ds.Namespace)); err != nil {
+		return "", err
+	}
+	quotas := make([]*datav1alpha1.DataQuota, 0, len(quotaList.Items))
+	for i := range quotaList.Items {
+		if quota.IsLimited(&quotaList.Items[i]) {
+			quotas = append(quotas, &quotaList.Items[i])
+		}
+	}
+	if len(quotas) == 0 {
+		return "", nil
+	}
+
+	dsList := &datav1alpha1.DataSetList{}
+	if err := v.client.List(ctx, dsList, client.InNamespace(ds.Namespace)); err != nil {
+		return "", err
+	}
+	others := make([]datav1alpha1.DataSet, 0, len(dsList.Items))
+	for _, item := range dsList.Items {
+		if item.Namespace == ds.Namespace && item.Name != ds.Name {
+			others = append(others, item)
+		}
+	}
+	used := quota.Add(quota.Usage(others), usage)
+
+	for _, q := range quotas {
+		if msgs := quota.Check(q, used); len(msgs) > 0 {
+			return fmt.Sprintf("exceeded DataQuota %s: %s", q.Name, strings.Join(msgs, ", ")), nil
+		}
+	}
+	return "", nil
+}
+
+// InjectDecoder injects the decoder.
+func (v *DataSetValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// ValidateDataSet validates the dataset. It checks the required fields and enums of the
+// CRD schema, and the rules the schema can not express. The old dataset is nil on creation;
+// on update, the rules added after the dataset was created, i.e. the required workload
+// selector and the absolute local paths, are only checked if the fields are changed, so
+// that the existing datasets can still be updated.
+func ValidateDataSet(ds, old *datav1alpha1.DataSet) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	namePath := field.NewPath("metadata", "name")
+	if ds.Name == "" {
+		allErrs = append(allErrs, field.Required(namePath, ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(ds.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, ds.Name, msg))
+		}
+	}
+
+	specPath := field.NewPath("spec")
+	var oldTemplate *datav1alpha1.DataTemplateSpec
+	if old != nil {
+		oldTemplate = &old.Spec.Template
+	}
+	allErrs = append(allErrs, validateTemplate(&ds.Spec.Template, oldTemplate, specPath.Child("template"))...)
+
+	if old == nil || !reflect.DeepEqual(ds.Spec.WorkloadSelector, old.Spec.WorkloadSelector) {
+		selectorPath := specPath.Child("workloadSelector")
+		if len(ds.Spec.WorkloadSelector) == 0 {
+			allErrs = append(allErrs, field.Required(selectorPath, "the dataset would match no workload"))
+		}
+		allErrs = append(allErrs, validateLabels(ds.Spec.WorkloadSelector, selectorPath)...)
+	}
+
+	switch ds.Spec.MissingInjectionPolicy {
+	case "", datav1alpha1.MissingInjectionIgnore, datav1alpha1.MissingInjectionRestart:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("missingInjectionPolicy"), ds.Spec.MissingInjectionPolicy,
+			[]string{string(datav1alpha1.MissingInjectionIgnore), string(datav1alpha1.MissingInjectionRestart)}))
+	}
+
+	if ds.Spec.RevisionHistoryLimit != nil && *ds.Spec.RevisionHistoryLimit < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("revisionHistoryLimit"), *ds.Spec.RevisionHistoryLimit, "must be greater than or equal to 0"))
+	}
+
+	allErrs = append(allErrs, validateAffinityPolicy(ds.Spec.Affinity, specPath.Child("affinity"))...)
+
+	if ds.Spec.Prefetch != nil {
+		allErrs = append(allErrs, validateLabels(ds.Spec.Prefetch.NodeSelector, specPath.Child("prefetch", "nodeSelector"))...)
+	}
+
+	allErrs = append(allErrs, validateVolume(ds.Spec.Volume, specPath.Child("volume"))...)
+
+	if download := ds.Spec.Download; download != nil {
+		downloadPath := specPath.Child("download")
+		if download.MaxConcurrent != nil && *download.MaxConcurrent < 0 {
+			allErrs = append(allErrs, field.Invalid(downloadPath.Child("maxConcurrent"), *download.MaxConcurrent, "must be greater than or equal to 0"))
+		}
+		if download.PriorityClassName != "" {
+			for _, msg := range validation.IsDNS1123Subdomain(download.PriorityClassName) {
+				allErrs = append(allErrs, field.Invalid(downloadPath.Child("priorityClassName"), download.PriorityClassName, msg))
+			}
+		}
+	}
+
+	for i, window := range ds.Spec.UpdateWindows {
+		windowPath := specPath.Child("updateWindows").Index(i)
+		allErrs = append(allErrs, validateSchedule(window.Schedule, windowPath.Child("schedule"))...)
+		if window.Duration.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("duration"), window.Duration.Duration.String(), "must be greater than 0"))
+		}
+	}
+
+	if ds.Spec.Activation != nil {
+		allErrs = append(allErrs, validateQuorum(ds.Spec.Activation.Quorum, specPath.Child("activation", "quorum"))...)
+	}
+
+	return allErrs
+}
+
+func validateQuorum(quorum *intstr.IntOrString, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if quorum == nil {
+		return allErrs
+	}
+	if quorum.Type == intstr.Int {
+		if quorum.IntVal < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath, quorum.IntVal, "must be greater than or equal to 0"))
+		}
+		return allErrs
+	}
+	for _, msg := range validation.IsValidPercent(quorum.StrVal) {
+		allErrs = append(allErrs, field.Invalid(fldPath, quorum.StrVal, msg))
+	}
+	if len(allErrs) == 0 {
+		if percent, _ := strconv.Atoi(strings.TrimSuffix(quorum.StrVal, "%")); percent > 100 {
+			allErrs = append(allErrs, field.Invalid(fldPath, quorum.StrVal, "must not be greater than 100%"))
+		}
+	}
+	return allErrs
+}
+
+func validateSchedule(schedule string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if schedule == "" {
+		allErrs = append(allErrs, field.Required(fldPath, ""))
+	} else if _, err := cron.ParseStandard(schedule); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, schedule, err.Error()))
+	}
+	return allErrs
+}
+
+func validateVolume(volume *datav1alpha1.DataVolumeSource, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if volume == nil {
+		return allErrs
+	}
+
+	sources := 0
+	if emptyDir := volume.EmptyDir; emptyDir != nil {
+		sources++
+		if emptyDir.Medium != corev1.StorageMediumDefault && emptyDir.Medium != corev1.StorageMediumMemory {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("emptyDir", "medium"), emptyDir.Medium,
+				[]string{string(corev1.StorageMediumDefault), string(corev1.StorageMediumMemory)}))
+		}
+		if emptyDir.SizeLimit != nil && emptyDir.SizeLimit.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("emptyDir", "sizeLimit"), emptyDir.SizeLimit.String(), "must be greater than or equal to 0"))
+		}
+	}
+	if volume.Ephemeral != nil {
+		sources++
+		if volume.Ephemeral.VolumeClaimTemplate == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("ephemeral", "volumeClaimTemplate"), ""))
+		}
+	}
+	if claim := volume.PersistentVolumeClaim; claim != nil {
+		sources++
+		claimPath := fldPath.Child("persistentVolumeClaim", "claimName")
+		if claim.ClaimName == "" {
+			allErrs = append(allErrs, field.Required(claimPath, ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(claim.ClaimName) {
+				allErrs = append(allErrs, field.Invalid(claimPath, claim.ClaimName, msg))
+			}
+		}
+		if claim.ReadOnly {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("persistentVolumeClaim", "readOnly"), claim.ReadOnly, "the data is written into the volume"))
+		}
+	}
+	if sources > 1 {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "may not specify more than 1 volume source"))
+	}
+
+	return allErrs
+}
+
+func validateLabels(labels map[string]string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := labels[k]
+		for _, msg := range validation.IsQualifiedName(k) {
+			allErrs = append(allErrs, field.Invalid(fldPath, k, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(v) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(k), v, msg))
+		}
+	}
+
+	return allErrs
+}
+
+func validateAffinityPolicy(policy *datav1alpha1.AffinityPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy == nil {
+		return allErrs
+	}
+
+	switch policy.Mode {
+	case "", datav1alpha1.AffinityModeAffinity, datav1alpha1.AffinityModeAntiAffinity, datav1alpha1.AffinityModeNone:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("mode"), policy.Mode,
+			[]string{string(datav1alpha1.AffinityModeAffinity), string(datav1alpha1.AffinityModeAntiAffinity), string(datav1alpha1.AffinityModeNone)}))
+	}
+	switch policy.Target {
+	case "", datav1alpha1.AffinityTargetPods:
+	case datav1alpha1.AffinityTargetNodes:
+		if policy.Mode == datav1alpha1.AffinityModeAntiAffinity {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("mode"), policy.Mode, "anti-affinity is only supported for the Pods target"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("target"), policy.Target,
+			[]string{string(datav1alpha1.AffinityTargetPods), string(datav1alpha1.AffinityTargetNodes)}))
+	}
+
+	if policy.TopologyKey != "" {
+		for _, msg := range validation.IsQualifiedName(policy.TopologyKey) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("topologyKey"), policy.TopologyKey, msg))
+		}
+	}
+	if policy.Weight < 0 || policy.Weight > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("weight"), policy.Weight, "must be in the range 1-100"))
+	}
+
+	return allErrs
+}
+
+// validateTemplate validates the template, the old template is nil on creation.
+func validateTemplate(template, old *datav1alpha1.DataTemplateSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	sourcesPath := fldPath.Child("dataSources")
+	if template.DataSources == nil {
+		allErrs = append(allErrs, field.Required(sourcesPath, ""))
+	} else {
+		allErrs = append(allErrs, validateDataSources(template.DataSources, sourcesPath)...)
+	}
+
+	itemsPath := fldPath.Child("dataItems")
+	if len(template.DataItems) == 0 {
+		allErrs = append(allErrs, field.Required(itemsPath, ""))
+	}
+	oldItems := make(map[string]*datav1alpha1.DataItem)
+	if old != nil {
+		for i := range old.DataItems {
+			oldItems[old.DataItems[i].Namespace+"/"+old.DataItems[i].Name] = &old.DataItems[i]
+		}
+	}
+	items := make(map[string]bool, len(template.DataItems))
+	localPaths := make(map[string]bool, len(template.DataItems))
+	for i, item := range template.DataItems {
+		itemPath := itemsPath.Index(i)
+		key := item.Namespace + "/" + item.Name
+		allErrs = append(allErrs, validateDataItem(&item, oldItems[key], template.DataSources, itemPath)...)
+
+		if items[key] {
+			allErrs = append(allErrs, field.Duplicate(itemPath, key))
+		}
+		items[key] = true
+
+		if item.LocalPath != "" {
+			localPath := filepath.Clean(item.LocalPath)
+			if localPaths[localPath] {
+				allErrs = append(allErrs, field.Duplicate(itemPath.Child("localPath"), item.LocalPath))
+			}
+			localPaths[localPath] = true
+		}
+	}
+
+	allErrs = append(allErrs, validateLifecycle(template.Lifecycle, fldPath.Child("lifecycle"))...)
+
+	return allErrs
+}
+
+// validateDataItem validates the data item, the old one is the item of the same namespace
+// and name in the old template, or nil if there is none.
+func validateDataItem(item, old *datav1alpha1.DataItem, sources *datav1alpha1.DataSources, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	required := map[string]string{
+		"name":           item.Name,
+		"namespace":      item.Namespace,
+		"remotePath":     item.RemotePath,
+		"localPath":      item.LocalPath,
+		"version":        item.Version,
+		"dataSourceType": item.DataSourceType,
+	}
+	for _, name := range []string{"name", "namespace", "remotePath", "localPath", "version", "dataSourceType"} {
+		if required[name] == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child(name), ""))
+		}
+	}
+
+	if item.LocalPath != "" && (old == nil || old.LocalPath != item.LocalPath) && !filepath.IsAbs(item.LocalPath) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("localPath"), item.LocalPath, "must be an absolute path"))
+	}
+
+	switch item.DataSourceType {
+	case "":
+	case dataSourceTypeHdfs:
+		if sources != nil && sources.Hdfs == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dataSourceType"), item.DataSourceType, "the data source is not configured in dataSources"))
+		}
+	case dataSourceTypeAlluxio:
+		if sources != nil && sources.Alluxio == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dataSourceType"), item.DataSourceType, "the data source is not configured in dataSources"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("dataSourceType"), item.DataSourceType,
+			[]string{dataSourceTypeHdfs, dataSourceTypeAlluxio}))
+	}
+
+	if item.Size != nil && item.Size.Sign() < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("size"), item.Size.String(), "must be greater than or equal to 0"))
+	}
+
+	switch item.Format {
+	case "", datav1alpha1.DataFormatTar, datav1alpha1.DataFormatTarGz, datav1alpha1.DataFormatTarZst, datav1alpha1.DataFormatZip:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("format"), item.Format,
+			[]string{string(datav1alpha1.DataFormatTar), string(datav1alpha1.DataFormatTarGz), string(datav1alpha1.DataFormatTarZst), string(datav1alpha1.DataFormatZip)}))
+	}
+
+	allErrs = append(allErrs, validateSyncPolicy(item.Sync, fldPath.Child("sync"))...)
+
+	allErrs = append(allErrs, validateDeltaPolicy(item.Delta, fldPath.Child("delta"))...)
+	if item.Versioning != nil && item.Versioning.KeepPrevious != nil && *item.Versioning.KeepPrevious < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("versioning", "keepPrevious"), *item.Versioning.KeepPrevious, "must be greater than or equal to 0"))
+	}
+	if item.Delta != nil && item.Format != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("delta"), item.Delta.Compare, "delta is not supported for the archives"))
+	}
+
+	if item.Schedule != "" {
+		allErrs = append(allErrs, validateSchedule(item.Schedule, fldPath.Child("schedule"))...)
+	}
+
+	allErrs = append(allErrs, validateVersionPolicy(item.VersionPolicy, fldPath.Child("versionPolicy"))...)
+	if item.VersionPolicy != nil && item.DataSourceType == dataSourceTypeHdfs && sources != nil && sources.Hdfs != nil && len(sources.Hdfs.HTTPAddresses) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("versionPolicy"), item.VersionPolicy.ParentPath, "httpAddresses of the hdfs data source must be set to discover versions"))
+	}
+
+	allErrs = append(allErrs, validateP2PPolicy(item.P2P, fldPath.Child("p2p"))...)
+	if item.Checksum != "" && !checksumRegexp.MatchString(item.Checksum) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("checksum"), item.Checksum, "must be in the format of <algorithm>:<hex>, e.g. sha256:<hex>"))
+	}
+
+	allErrs = append(allErrs, validateLifecycle(item.Lifecycle, fldPath.Child("lifecycle"))...)
+
+	return allErrs
+}
+
+func validateSyncPolicy(policy *datav1alpha1.SyncPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy == nil {
+		return allErrs
+	}
+
+	switch policy.Mode {
+	case "", datav1alpha1.SyncModeCopy, datav1alpha1.SyncModeMirror:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("mode"), policy.Mode,
+			[]string{string(datav1alpha1.SyncModeCopy), string(datav1alpha1.SyncModeMirror)}))
+	}
+	for i, pattern := range policy.Include {
+		if err := dirsync.ValidatePattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("include").Index(i), pattern, err.Error()))
+		}
+	}
+	for i, pattern := range policy.Exclude {
+		if err := dirsync.ValidatePattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("exclude").Index(i), pattern, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+func validateDeltaPolicy(policy *datav1alpha1.DeltaPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy == nil {
+		return allErrs
+	}
+
+	switch policy.Compare {
+	case "", datav1alpha1.DeltaCompareSizeModTime, datav1alpha1.DeltaCompareChecksum:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("compare"), policy.Compare,
+			[]string{string(datav1alpha1.DeltaCompareSizeModTime), string(datav1alpha1.DeltaCompareChecksum)}))
+	}
+	if policy.Manifest == "" {
+		if policy.Compare == datav1alpha1.DeltaCompareChecksum {
+			allErrs = append(allErrs, field.Required(fldPath.Child("manifest"), "the checksums are read from the manifest"))
+		}
+	} else if err := delta.ValidateManifestPath(policy.Manifest); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("manifest"), policy.Manifest, err.Error()))
+	}
+
+	return allErrs
+}
+
+func validateVersionPolicy(policy *datav1alpha1.VersionPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy == nil {
+		return allErrs
+	}
+
+	if policy.ParentPath == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("parentPath"), ""))
+	} else if !path.IsAbs(policy.ParentPath) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("parentPath"), policy.ParentPath, "must be an absolute path"))
+	}
+	switch policy.Order {
+	case "", datav1alpha1.VersionOrderLexical, datav1alpha1.VersionOrderNumeric, datav1alpha1.VersionOrderSemVer:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("order"), policy.Order,
+			[]string{string(datav1alpha1.VersionOrderLexical), string(datav1alpha1.VersionOrderNumeric), string(datav1alpha1.VersionOrderSemVer)}))
+	}
+	if policy.MarkerFile != "" {
+		markerFile := path.Clean(policy.MarkerFile)
+		if path.IsAbs(markerFile) || markerFile == ".." || strings.HasPrefix(markerFile, "../") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("markerFile"), policy.MarkerFile, "must be a relative path within the version"))
+		}
+	}
+	if policy.Interval != nil && policy.Interval.Duration < minDiscoveryInterval {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("interval"), policy.Interval.Duration.String(),
+			fmt.Sprintf("must be at least %s", minDiscoveryInterval)))
+	}
+
+	return allErrs
+}
+
+func validateP2PPolicy(policy *datav1alpha1.P2PPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy == nil {
+		return allErrs
+	}
+
+	if policy.Seeders != nil && *policy.Seeders < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("seeders"), *policy.Seeders, "must be greater than or equal to 1"))
+	}
+	if policy.PieceSize != nil {
+		if size := policy.PieceSize.Value(); size < p2p.MinPieceSize || size > p2p.MaxPieceSize {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("pieceSize"), policy.PieceSize.String(),
+				fmt.Sprintf("must be between %dKi and %dMi", p2p.MinPieceSize>>10, p2p.MaxPieceSize>>20)))
+		}
+	}
+
+	return allErrs
+}
+
+func validateDataSources(sources *datav1alpha1.DataSources, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if hdfs := sources.Hdfs; hdfs != nil {
+		hdfsPath := fldPath.Child("hdfs")
+		if len(hdfs.Addresses) == 0 {
+			allErrs = append(allErrs, field.Required(hdfsPath.Child("addresses"), ""))
+		}
+		if hdfs.UserName == "" {
+			allErrs = append(allErrs, field.Required(hdfsPath.Child("userName"), ""))
+		}
+	}
+
+	if alluxio := sources.Alluxio; alluxio != nil {
+		alluxioPath := fldPath.Child("alluxio")
+		if alluxio.Host == "" {
+			allErrs = append(allErrs, field.Required(alluxioPath.Child("host"), ""))
+		}
+		if alluxio.Port <= 0 || alluxio.Port > 65535 {
+			allErrs = append(allErrs, field.Invalid(alluxioPath.Child("port"), alluxio.Port, "must be between 1 and 65535"))
+		}
+		if alluxio.Timeout < 0 {
+			allErrs = append(allErrs, field.Invalid(alluxioPath.Child("timeout"), alluxio.Timeout, "must be greater than or equal to 0"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateLifecycle(lifecycle *datav1alpha1.Lifecycle, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if lifecycle == nil {
+		return allErrs
+	}
+
+	allErrs = append(allErrs, validateLifecycleHandler(lifecycle.PreDownload, fldPath.Child("preDownload"))...)
+	allErrs = append(allErrs, validateLifecycleHandler(lifecycle.PostDownload, fldPath.Child("postDownload"))...)
+
+	return allErrs
+}
+
+func validateLifecycleHandler(handler *datav1alpha1.LifecycleHandler, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if handler == nil {
+		return allErrs
+	}
+
+	switch {
+	case handler.Exec != nil && handler.HTTPGet != nil:
+		allErrs = append(allErrs, field.Forbidden(fldPath, "may not specify more than 1 handler type"))
+	case handler.Exec != nil:
+		if len(handler.Exec.Command) == 0 {
+			allErrs = append(allErrs, field.Required(fldPath.Child("exec", "command"), ""))
+		}
+	case handler.HTTPGet != nil:
+		if handler.HTTPGet.Port.IntValue() == 0 && handler.HTTPGet.Port.StrVal == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("httpGet", "port"), ""))
+		}
+	default:
+		allErrs = append(allErrs, field.Required(fldPath, "must specify a handler type"))
+	}
+
+	return allErrs
+}
@@ -0,0 +1,426 @@
+/*
+Copyright 2021 The Kuda Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	datav1alpha1 "github.com/kuda-io/kuda/pkg/api/data/v1alpha1"
+)
+
+func TestValidateDataSet(t *testing.T) {
+	newDataSet := func() *datav1alpha1.DataSet {
+		return &datav1alpha1.DataSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: datav1alpha1.DataSetSpec{
+				Template: datav1alpha1.DataTemplateSpec{
+					DataItems: []datav1alpha1.DataItem{
+						{
+							Name:           "conf",
+							Namespace:      "kuda-io",
+							RemotePath:     "/nginx-conf/test.conf",
+							LocalPath:      "/tmp/test.conf",
+							Version:        "1628811202",
+							DataSourceType: "hdfs",
+							Lifecycle: &datav1alpha1.Lifecycle{
+								PostDownload: &datav1alpha1.LifecycleHandler{
+									Exec: &corev1.ExecAction{Command: []string{"nginx", "-s", "reload"}},
+								},
+							},
+						},
+					},
+					DataSources: &datav1alpha1.DataSources{
+						Hdfs: &datav1alpha1.HdfsDataSource{Addresses: []string{"hdfs:8020"}, UserName: "root"},
+					},
+				},
+				WorkloadSelector: map[string]string{"app": "nginx"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(ds *datav1alpha1.DataSet)
+		// old returns the old dataset of the update, the dataset is created if it's nil.
+		old  func() *datav1alpha1.DataSet
+		errs []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(ds *datav1alpha1.DataSet) {},
+		},
+		{
+			name: "missing required fields",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].Version = ""
+				ds.Spec.WorkloadSelector = nil
+			},
+			errs: []string{"spec.template.dataItems[0].version", "spec.workloadSelector"},
+		},
+		{
+			name: "update the dataset created before the rules",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].LocalPath = "tmp/test.conf"
+				ds.Spec.Template.DataItems[0].Version = "1628811203"
+				ds.Spec.WorkloadSelector = nil
+			},
+			old: func() *datav1alpha1.DataSet {
+				ds := newDataSet()
+				ds.Spec.Template.DataItems[0].LocalPath = "tmp/test.conf"
+				ds.Spec.WorkloadSelector = nil
+				return ds
+			},
+		},
+		{
+			name: "update the fields checked by the new rules",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].LocalPath = "tmp/nginx.conf"
+				ds.Spec.WorkloadSelector = nil
+			},
+			old: func() *datav1alpha1.DataSet {
+				ds := newDataSet()
+				ds.Spec.Template.DataItems[0].LocalPath = "tmp/test.conf"
+				return ds
+			},
+			errs: []string{"spec.template.dataItems[0].localPath", "spec.workloadSelector"},
+		},
+		{
+			name: "unsupported enums",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.MissingInjectionPolicy = "Delete"
+				ds.Spec.Template.DataItems[0].DataSourceType = "s3"
+			},
+			errs: []string{"spec.template.dataItems[0].dataSourceType", "spec.missingInjectionPolicy"},
+		},
+		{
+			name: "data source not configured",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].DataSourceType = "alluxio"
+			},
+			errs: []string{"spec.template.dataItems[0].dataSourceType"},
+		},
+		{
+			name: "duplicate data items",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems = append(ds.Spec.Template.DataItems, ds.Spec.Template.DataItems[0])
+			},
+			errs: []string{"spec.template.dataItems[1]", "spec.template.dataItems[1].localPath"},
+		},
+		{
+			name: "invalid values",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Name = "Test_DS"
+				ds.Spec.Template.DataItems[0].LocalPath = "tmp/test.conf"
+				ds.Spec.Template.DataItems[0].Lifecycle.PostDownload.HTTPGet = &corev1.HTTPGetAction{}
+				ds.Spec.RevisionHistoryLimit = pointer.Int32Ptr(-1)
+			},
+			errs: []string{
+				"metadata.name",
+				"spec.template.dataItems[0].localPath",
+				"spec.template.dataItems[0].lifecycle.postDownload",
+				"spec.revisionHistoryLimit",
+			},
+		},
+		{
+			name: "invalid affinity",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Affinity = &datav1alpha1.AffinityPolicy{
+					Mode:        datav1alpha1.AffinityModeAntiAffinity,
+					Target:      datav1alpha1.AffinityTargetNodes,
+					TopologyKey: "-zone",
+					Weight:      101,
+				}
+			},
+			errs: []string{"spec.affinity.mode", "spec.affinity.topologyKey", "spec.affinity.weight"},
+		},
+		{
+			name: "invalid prefetch node selector",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Prefetch = &datav1alpha1.PrefetchSpec{NodeSelector: map[string]string{"pool": "gpu nodes"}}
+			},
+			errs: []string{"spec.prefetch.nodeSelector[pool]"},
+		},
+		{
+			name: "invalid download policy",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Download = &datav1alpha1.DownloadPolicy{MaxConcurrent: pointer.Int32Ptr(-1), PriorityClassName: "High_Priority"}
+			},
+			errs: []string{"spec.download.maxConcurrent", "spec.download.priorityClassName"},
+		},
+		{
+			name: "negative size",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				size := resource.MustParse("-1Gi")
+				ds.Spec.Template.DataItems[0].Size = &size
+			},
+			errs: []string{"spec.template.dataItems[0].size"},
+		},
+		{
+			name: "unsupported format",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].Format = "rar"
+			},
+			errs: []string{"spec.template.dataItems[0].format"},
+		},
+		{
+			name: "invalid sync policy",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].Sync = &datav1alpha1.SyncPolicy{
+					Mode:    "Move",
+					Include: []string{"*.parquet", "part-[0-9"},
+					Exclude: []string{""},
+				}
+			},
+			errs: []string{
+				"spec.template.dataItems[0].sync.mode",
+				"spec.template.dataItems[0].sync.include[1]",
+				"spec.template.dataItems[0].sync.exclude[0]",
+			},
+		},
+		{
+			name: "invalid delta policy",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].Format = datav1alpha1.DataFormatTarGz
+				ds.Spec.Template.DataItems[0].Delta = &datav1alpha1.DeltaPolicy{Compare: datav1alpha1.DeltaCompareChecksum}
+				ds.Spec.Template.DataItems = append(ds.Spec.Template.DataItems, ds.Spec.Template.DataItems[0])
+				ds.Spec.Template.DataItems[1].Name = "dict"
+				ds.Spec.Template.DataItems[1].LocalPath = "/dict"
+				ds.Spec.Template.DataItems[1].Format = ""
+				ds.Spec.Template.DataItems[1].Delta = &datav1alpha1.DeltaPolicy{Compare: "Hash", Manifest: "../SHA256SUMS"}
+			},
+			errs: []string{
+				"spec.template.dataItems[0].delta.manifest",
+				"spec.template.dataItems[0].delta",
+				"spec.template.dataItems[1].delta.compare",
+				"spec.template.dataItems[1].delta.manifest",
+			},
+		},
+		{
+			name: "negative versions kept",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].Versioning = &datav1alpha1.VersioningPolicy{KeepPrevious: pointer.Int32Ptr(-1)}
+			},
+			errs: []string{"spec.template.dataItems[0].versioning.keepPrevious"},
+		},
+		{
+			name: "invalid version policy",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].VersionPolicy = &datav1alpha1.VersionPolicy{
+					ParentPath: "models/ranker",
+					Order:      "Date",
+					MarkerFile: "../_SUCCESS",
+					Interval:   &metav1.Duration{Duration: time.Second},
+				}
+			},
+			errs: []string{
+				"spec.template.dataItems[0].versionPolicy.parentPath",
+				"spec.template.dataItems[0].versionPolicy.order",
+				"spec.template.dataItems[0].versionPolicy.markerFile",
+				"spec.template.dataItems[0].versionPolicy.interval",
+				"spec.template.dataItems[0].versionPolicy",
+			},
+		},
+		{
+			name: "valid p2p policy",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				pieceSize := resource.MustParse("16Mi")
+				ds.Spec.Template.DataItems[0].P2P = &datav1alpha1.P2PPolicy{Seeders: pointer.Int32Ptr(2), PieceSize: &pieceSize}
+			},
+		},
+		{
+			name: "invalid p2p policy",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				pieceSize := resource.MustParse("1Ki")
+				ds.Spec.Template.DataItems[0].P2P = &datav1alpha1.P2PPolicy{Seeders: pointer.Int32Ptr(0), PieceSize: &pieceSize}
+			},
+			errs: []string{
+				"spec.template.dataItems[0].p2p.seeders",
+				"spec.template.dataItems[0].p2p.pieceSize",
+			},
+		},
+		{
+			name: "invalid checksum",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].Checksum = "SHA256=ABCD"
+			},
+			errs: []string{"spec.template.dataItems[0].checksum"},
+		},
+		{
+			name: "invalid schedules",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				ds.Spec.Template.DataItems[0].Schedule = "0 25 * * *"
+				ds.Spec.UpdateWindows = []datav1alpha1.UpdateWindow{
+					{Schedule: "CRON_TZ=Asia/Shanghai 0 1 * * *", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+					{Duration: metav1.Duration{Duration: -time.Hour}},
+				}
+			},
+			errs: []string{
+				"spec.template.dataItems[0].schedule",
+				"spec.updateWindows[1].schedule",
+				"spec.updateWindows[1].duration",
+			},
+		},
+		{
+			name: "valid activation quorum",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				quorum := intstr.FromString("90%")
+				ds.Spec.Activation = &datav1alpha1.ActivationPolicy{Quorum: &quorum}
+			},
+		},
+		{
+			name: "invalid activation quorum",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				quorum := intstr.FromString("120%")
+				ds.Spec.Activation = &datav1alpha1.ActivationPolicy{Quorum: &quorum}
+			},
+			errs: []string{"spec.activation.quorum"},
+		},
+		{
+			name: "valid memory volume",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				sizeLimit := resource.MustParse("1Gi")
+				ds.Spec.Volume = &datav1alpha1.DataVolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &sizeLimit},
+				}
+			},
+		},
+		{
+			name: "invalid volume",
+			mutate: func(ds *datav1alpha1.DataSet) {
+				sizeLimit := resource.MustParse("-1Gi")
+				ds.Spec.Volume = &datav1alpha1.DataVolumeSource{
+					EmptyDir:              &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumHugePages, SizeLimit: &sizeLimit},
+					Ephemeral:             &corev1.EphemeralVolumeSource{},
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "Models", ReadOnly: true},
+				}
+			},
+			errs: []string{"spec.volume", "spec.volume.emptyDir.medium", "spec.volume.emptyDir.sizeLimit", "spec.volume.ephemeral.volumeClaimTemplate",
+				"spec.volume.persistentVolumeClaim.claimName", "spec.volume.persistentVolumeClaim.readOnly"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := newDataSet()
+			tt.mutate(ds)
+
+			var old *datav1alpha1.DataSet
+			if tt.old != nil {
+				old = tt.old()
+			}
+			errs := ValidateDataSet(ds, old)
+			fields := make([]string, 0, len(errs))
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			assert.ElementsMatch(t, tt.errs, fields)
+		})
+	}
+}
+
+func TestDataSetValidator_CheckDataSize(t *testing.T) {
+	max := resource.MustParse("10Gi")
+	v := NewDataSetValidator(&Config{DataSize: DataSizeConfig{Max: &max}}, nil)
+
+	ds := &datav1alpha1.DataSet{}
+	for _, size := range []string{"4Gi", "6Gi"} {
+		quantity := resource.MustParse(size)
+		ds.Spec.Template.DataItems = append(ds.Spec.Template.DataItems, datav1alpha1.DataItem{Size: &quantity})
+	}
+	assert.Empty(t, v.checkDataSize(ds))
+
+	size := resource.MustParse("1Mi")
+	ds.Spec.Template.DataItems = append(ds.Spec.Template.DataItems, datav1alpha1.DataItem{Size: &size})
+	assert.Equal(t, "the total size 10241Mi of the data items exceeds the max size 10Gi", v.checkDataSize(ds))
+
+	// Nothing is checked without the max size.
+	assert.Empty(t, NewDataSetValidator(&Config{}, nil).checkDataSize(ds))
+}
+
+func TestDataSetValidator_CheckQuota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = datav1alpha1.AddToScheme(scheme)
+
+	newDataSet := func(name string, sizes ...string) *datav1alpha1.DataSet {
+		ds := &datav1alpha1.DataSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		for _, size := range sizes {
+			quantity := resource.MustParse(size)
+			ds.Spec.Template.DataItems = append(ds.Spec.Template.DataItems, datav1alpha1.DataItem{Size: &quantity})
+		}
+		return ds
+	}
+	maxSize := resource.MustParse("10Gi")
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&datav1alpha1.DataQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "default"},
+			Spec:       datav1alpha1.DataQuotaSpec{MaxSize: &maxSize, MaxItems: pointer.Int32Ptr(3)},
+		},
+		newDataSet("model", "6Gi"),
+	).Build()
+	v := NewDataSetValidator(&Config{}, cli)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		ds   *datav1alpha1.DataSet
+		old  *datav1alpha1.DataSet
+		msg  string
+	}{
+		{
+			name: "within the quota",
+			ds:   newDataSet("dict", "2Gi", "2Gi"),
+		},
+		{
+			name: "exceeds the max size",
+			ds:   newDataSet("dict", "5Gi"),
+			msg:  "exceeded DataQuota quota: the total size 11Gi exceeds the max size 10Gi",
+		},
+		{
+			name: "exceeds the max items",
+			ds:   newDataSet("dict", "1Gi", "1Gi", "1Gi"),
+			msg:  "exceeded DataQuota quota: the total number 4 of data items exceeds the max items 3",
+		},
+		{
+			name: "the dataset itself is not counted twice on update",
+			ds:   newDataSet("model", "10Gi"),
+			old:  newDataSet("model", "6Gi"),
+		},
+		{
+			name: "shrinking is allowed even if exceeded",
+			ds:   newDataSet("model", "11Gi"),
+			old:  newDataSet("model", "12Gi"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := v.checkQuota(ctx, tt.ds, tt.old)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.msg, msg)
+		})
+	}
+}